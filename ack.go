@@ -0,0 +1,199 @@
+package socketigo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	uuid "github.com/google/uuid"
+)
+
+var ErrAckTimeout = errors.New("socketigo: ack timed out")
+
+// AckError is returned by EmitWithAck when the client's listener acknowledged
+// with an error.
+type AckError struct {
+	Message string
+}
+
+func (e *AckError) Error() string {
+	return "socketigo: ack failed: " + e.Message
+}
+
+// AckResult is the acknowledgement of a single client to a broadcast. Err is
+// ErrAckTimeout when the client did not answer in time.
+type AckResult struct {
+	Result interface{}
+	Err    error
+}
+
+func ackEventName(eventName string, ackId string) string {
+	return eventName + "@ack:" + ackId
+}
+
+// ackResponse acknowledges with the listener's result. Listeners returning an
+// error acknowledge with its message instead, plus the failed fields of a
+// ValidationError.
+func ackResponse(result interface{}) map[string]interface{} {
+	if err, ok := result.(error); ok {
+		response := map[string]interface{}{
+			"error": err.Error(),
+		}
+
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			response["fields"] = validationErr.Fields
+		}
+		return response
+	}
+
+	return map[string]interface{}{
+		"result": result,
+	}
+}
+
+// acknowledge answers the ack the frame asks for, or the call it carries, with
+// the result of its listeners.
+func (c *Client) acknowledge(frame *Frame, result interface{}) {
+	switch {
+	case frame == nil || frame.Event == "":
+	case frame.CallID != "":
+		c.reply(frame.CallID, result)
+	case frame.AckID != "":
+		c.Emit(ackEventName(frame.Event, frame.AckID), ackResponse(result))
+	}
+}
+
+// EmitWithAck emits the event to the client and blocks until the client
+// acknowledged it, the timeout elapsed or the client disconnected. The ack is
+// read by the client's read loop, so calling it from the connected handler, or
+// from a listener of the same client unless listeners run on workers, has to
+// happen in a separate goroutine.
+func (c *Client) EmitWithAck(eventName string, data interface{}, timeout time.Duration) (interface{}, error) {
+	return c.EmitWithAckContext(c.ctx, eventName, data, timeout)
+}
+
+// EmitWithAckContext is EmitWithAck, additionally giving up once ctx is done.
+// Passing the context of a listener continues its trace.
+func (c *Client) EmitWithAckContext(ctx context.Context, eventName string, data interface{}, timeout time.Duration) (interface{}, error) {
+	ackId := strconv.FormatUint(atomic.AddUint64(&c.ackSeq, 1), 36)
+	ackEvent := ackEventName(eventName, ackId)
+	ack := make(chan map[string]interface{}, 1)
+
+	c.acksMu.Lock()
+	c.acks[ackEvent] = ack
+	c.acksMu.Unlock()
+
+	defer func() {
+		c.acksMu.Lock()
+		delete(c.acks, ackEvent)
+		c.acksMu.Unlock()
+	}()
+
+	trace, end := c.Server.tracer.StartAck(ctx, c, eventName)
+
+	frame := map[string]interface{}{
+		"event": eventName,
+		"data":  data,
+		"ackId": ackId,
+	}
+	if len(trace) > 0 {
+		frame["trace"] = trace
+	}
+
+	result, err := c.awaitAck(ctx, eventName, frame, ack, timeout)
+	end(err)
+	return result, err
+}
+
+func (c *Client) awaitAck(ctx context.Context, eventName string, frame map[string]interface{}, ack chan map[string]interface{}, timeout time.Duration) (interface{}, error) {
+	if err := c.enqueueFrame(frame); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case response := <-ack:
+		if message, ok := response["error"].(string); ok {
+			return nil, &AckError{Message: message}
+		}
+		return response["result"], nil
+	case <-timer.C:
+		c.Server.metrics.AckTimeout(c.Namespace.Name, eventName)
+		c.Server.logger.Debug("ack timed out", c.logFields("event", eventName)...)
+		return nil, ErrAckTimeout
+	case <-c.done:
+		return nil, ErrClientClosed
+	case <-ctx.Done():
+		select {
+		case <-c.done:
+			// the client context is canceled once the client is closed
+			return nil, ErrClientClosed
+		default:
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) awaitsAck(eventName string) bool {
+	c.acksMu.Lock()
+	defer c.acksMu.Unlock()
+
+	_, ok := c.acks[eventName]
+	return ok
+}
+
+// resolveAck hands an incoming acknowledgement to the waiting EmitWithAck call
+// and reports whether the event was one.
+func (c *Client) resolveAck(eventName string, data map[string]interface{}) bool {
+	c.acksMu.Lock()
+	ack, ok := c.acks[eventName]
+	if ok {
+		delete(c.acks, eventName)
+	}
+	c.acksMu.Unlock()
+
+	if ok {
+		ack <- data
+	}
+	return ok
+}
+
+func emitWithAcks(clients []*Client, eventName string, data interface{}, timeout time.Duration) map[uuid.UUID]AckResult {
+	results := make(map[uuid.UUID]AckResult, len(clients))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, client := range clients {
+		wg.Add(1)
+
+		go func(client *Client) {
+			defer wg.Done()
+
+			result, err := client.EmitWithAck(eventName, data, timeout)
+
+			mu.Lock()
+			results[client.Id] = AckResult{Result: result, Err: err}
+			mu.Unlock()
+		}(client)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// EmitWithAcks emits the event to every member of the room and waits until all
+// of them acknowledged it or the timeout elapsed.
+func (r *Room) EmitWithAcks(eventName string, data interface{}, timeout time.Duration) map[uuid.UUID]AckResult {
+	return emitWithAcks(r.clients.snapshot(), eventName, data, timeout)
+}
+
+func (b *BroadcastOperator) EmitWithAcks(eventName string, data interface{}, timeout time.Duration) map[uuid.UUID]AckResult {
+	return emitWithAcks(b.Clients(), eventName, data, timeout)
+}
@@ -0,0 +1,127 @@
+package socketigo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/igoclient"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+func TestAckCarriesListenerResult(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	server.On("sum", func(client *socketigo.Client, data map[string]interface{}) interface{} {
+		a, _ := data["a"].(float64)
+		b, _ := data["b"].(float64)
+		return a + b
+	})
+	server.On("fail", func(client *socketigo.Client, data map[string]interface{}) interface{} {
+		return errors.New("failed on purpose")
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+
+	if result := client.ExpectAck(t, "sum", map[string]interface{}{"a": 1, "b": 2}); result != 3.0 {
+		t.Fatalf("ack result is %v, want 3", result)
+	}
+
+	_, err := client.EmitWithAck("fail", nil, time.Second)
+	var ackErr *igoclient.AckError
+	if !errors.As(err, &ackErr) || ackErr.Message != "failed on purpose" {
+		t.Fatalf("ack error is %v, want the error of the listener", err)
+	}
+}
+
+func TestServerEmitWithAck(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	connected := make(chan *socketigo.Client, 1)
+	server.OnConnected(func(client *socketigo.Client) {
+		connected <- client
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	client.On("greet", func(client *igoclient.Client, data map[string]interface{}) interface{} {
+		return "hello " + data["name"].(string)
+	})
+
+	serverClient := <-connected
+	result, err := serverClient.EmitWithAck("greet", map[string]interface{}{"name": "igo"}, time.Second)
+	if err != nil {
+		t.Fatalf("emitting with ack failed: %v", err)
+	}
+	if result != "hello igo" {
+		t.Fatalf("ack result is %v, want %q", result, "hello igo")
+	}
+}
+
+func TestCall(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	server.On("double", func(client *socketigo.Client, data map[string]interface{}) interface{} {
+		n, ok := data["n"].(float64)
+		if !ok {
+			return &socketigo.RPCError{Code: socketigo.CodeInvalidArgument, Message: "n is missing"}
+		}
+		return map[string]interface{}{"n": 2 * n}
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var resp struct {
+		N int `json:"n"`
+	}
+	if err := client.Call(ctx, "double", map[string]interface{}{"n": 21}, &resp); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if resp.N != 42 {
+		t.Fatalf("result is %d, want 42", resp.N)
+	}
+
+	var rpcErr *igoclient.RPCError
+	err := client.Call(ctx, "double", nil, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != igoclient.CodeInvalidArgument {
+		t.Fatalf("call error is %v, want code %q", err, igoclient.CodeInvalidArgument)
+	}
+
+	err = client.Call(ctx, "missing", nil, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != igoclient.CodeNotFound {
+		t.Fatalf("call error is %v, want code %q", err, igoclient.CodeNotFound)
+	}
+}
+
+func TestServerCall(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	connected := make(chan *socketigo.Client, 1)
+	server.OnConnected(func(client *socketigo.Client) {
+		connected <- client
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	client.On("version", func(client *igoclient.Client, data map[string]interface{}) interface{} {
+		return map[string]interface{}{"version": "1.2.3"}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	serverClient := <-connected
+	var resp struct {
+		Version string `json:"version"`
+	}
+	if err := serverClient.Call(ctx, "version", nil, &resp); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if resp.Version != "1.2.3" {
+		t.Fatalf("version is %q, want %q", resp.Version, "1.2.3")
+	}
+
+	var rpcErr *socketigo.RPCError
+	err := serverClient.Call(ctx, "missing", nil, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != socketigo.CodeNotFound {
+		t.Fatalf("call error is %v, want code %q", err, socketigo.CodeNotFound)
+	}
+}
@@ -0,0 +1,93 @@
+package socketigo
+
+// Adapter keeps the room memberships and delivers the broadcasts of a server.
+// Clustered adapters additionally publish every broadcast to the other
+// instances and hand the packets they receive to the subscribe handler.
+//
+// Every node keeps the memberships of its own clients. Emits to rooms, Except
+// and EmitExcept, as well as BroadcastOperator.Join and Leave, reach the
+// clients of every node, while Room.Size, Room.Clients and
+// BroadcastOperator.Clients only see the ones of this node.
+type Adapter interface {
+	Broker
+	// Init gets called once the adapter is attached to the server.
+	Init(server *IgoServer)
+	AddToRoom(room *Room, client *Client)
+	RemoveFromRoom(room *Room, client *Client) bool
+	// Broadcast delivers the packet to the matching clients of this instance.
+	Broadcast(packet *BroadcastPacket)
+}
+
+// MemoryAdapter is the default adapter, keeping everything within the process.
+// Other adapters may embed it and only replace the pub/sub methods.
+type MemoryAdapter struct {
+	server *IgoServer
+}
+
+func NewMemoryAdapter() *MemoryAdapter {
+	return &MemoryAdapter{}
+}
+
+func (a *MemoryAdapter) Init(server *IgoServer) {
+	a.server = server
+}
+
+func (a *MemoryAdapter) AddToRoom(room *Room, client *Client) {
+	room.clients.add(client)
+	client.addRoom(room)
+}
+
+func (a *MemoryAdapter) RemoveFromRoom(room *Room, client *Client) bool {
+	client.removeRoom(room)
+	return room.clients.remove(client)
+}
+
+func (a *MemoryAdapter) Broadcast(packet *BroadcastPacket) {
+	if ns := a.server.getNamespace(packet.Namespace); ns != nil {
+		ns.deliver(packet)
+	}
+}
+
+func (a *MemoryAdapter) Publish(packet *BroadcastPacket) error {
+	return nil
+}
+
+func (a *MemoryAdapter) Subscribe(handler func(packet *BroadcastPacket)) error {
+	return nil
+}
+
+func (a *MemoryAdapter) Close() error {
+	return nil
+}
+
+// SetAdapter replaces the adapter of the server. It has to be called before
+// the first client connects.
+func (s *IgoServer) SetAdapter(adapter Adapter) error {
+	adapter.Init(s)
+	s.adapter = adapter
+
+	return adapter.Subscribe(func(packet *BroadcastPacket) {
+		if packet.Node == s.nodeId {
+			return
+		}
+		adapter.Broadcast(packet)
+	})
+}
+
+// brokerAdapter clusters the memory adapter over a broker.
+type brokerAdapter struct {
+	*MemoryAdapter
+	broker Broker
+}
+
+func (a *brokerAdapter) Publish(packet *BroadcastPacket) error {
+	return a.broker.Publish(packet)
+}
+
+func (a *brokerAdapter) Subscribe(handler func(packet *BroadcastPacket)) error {
+	return a.broker.Subscribe(handler)
+}
+
+func (a *brokerAdapter) Close() error {
+	return a.broker.Close()
+}
@@ -0,0 +1,270 @@
+package socketigo
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	uuid "github.com/google/uuid"
+	ws "github.com/gorilla/websocket"
+)
+
+type AdminOptions struct {
+	// Token is the bearer token admin requests have to carry in their
+	// Authorization header.
+	Token string
+	// Authorize decides about requests instead of Token, e.g. to check a session
+	// cookie. Without Token and Authorize every request is rejected.
+	Authorize func(r *http.Request) bool
+}
+
+// ClientInfo describes a connected client in the admin API.
+type ClientInfo struct {
+	Id         string   `json:"id"`
+	Namespace  string   `json:"namespace"`
+	UserId     string   `json:"userId,omitempty"`
+	RemoteAddr string   `json:"remoteAddr"`
+	Rooms      []string `json:"rooms"`
+}
+
+// RoomInfo describes a room in the admin API.
+type RoomInfo struct {
+	Id        string                 `json:"id"`
+	Namespace string                 `json:"namespace"`
+	Topic     string                 `json:"topic,omitempty"`
+	Owner     string                 `json:"owner,omitempty"`
+	CreatedAt time.Time              `json:"createdAt"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Clients   []string               `json:"clients"`
+}
+
+type adminBroadcast struct {
+	Namespace string      `json:"namespace"`
+	Rooms     []string    `json:"rooms"`
+	Event     string      `json:"event"`
+	Data      interface{} `json:"data"`
+}
+
+/*
+AdminHandler serves the admin API for ops dashboards. Mount it behind
+http.StripPrefix to serve it below a path. Rooms are addressed within the
+namespace given by the namespace query parameter, the default one otherwise.
+
+  - GET /clients lists the connected clients, GET /clients/{id} returns one.
+  - DELETE /clients/{id} disconnects the client.
+  - PUT and DELETE /clients/{id}/rooms/{room} make the client join or leave the room.
+  - GET /rooms lists the rooms, GET /rooms/{room} returns one.
+  - POST /broadcast emits {"event", "data"} to the namespace, or its "rooms".
+*/
+func (s *IgoServer) AdminHandler(options *AdminOptions) http.Handler {
+	if options == nil {
+		options = &AdminOptions{}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /clients", s.adminListClients)
+	mux.HandleFunc("GET /clients/{id}", s.adminGetClient)
+	mux.HandleFunc("DELETE /clients/{id}", s.adminDisconnect)
+	mux.HandleFunc("PUT /clients/{id}/rooms/{room}", s.adminJoin)
+	mux.HandleFunc("DELETE /clients/{id}/rooms/{room}", s.adminLeave)
+	mux.HandleFunc("GET /rooms", s.adminListRooms)
+	mux.HandleFunc("GET /rooms/{room}", s.adminGetRoom)
+	mux.HandleFunc("POST /broadcast", s.adminBroadcast)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorized(options, r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func adminAuthorized(options *AdminOptions, r *http.Request) bool {
+	if options.Authorize != nil {
+		return options.Authorize(r)
+	}
+	if options.Token == "" {
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(options.Token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func (s *IgoServer) clientInfo(client *Client) ClientInfo {
+	info := ClientInfo{
+		Id:         client.Id.String(),
+		Namespace:  client.Namespace.Name,
+		UserId:     client.UserId(),
+		RemoteAddr: client.RemoteAddr(),
+		Rooms:      make([]string, 0),
+	}
+
+	for _, room := range client.Rooms() {
+		info.Rooms = append(info.Rooms, room.Id)
+	}
+	return info
+}
+
+func roomInfo(room *Room) RoomInfo {
+	info := RoomInfo{
+		Id:        room.Id,
+		Namespace: room.Namespace.Name,
+		Topic:     room.Topic(),
+		Owner:     room.Owner(),
+		CreatedAt: room.CreatedAt(),
+		Metadata:  room.Metadata(),
+		Clients:   make([]string, 0),
+	}
+	room.clients.each(func(client *Client) {
+		info.Clients = append(info.Clients, client.Id.String())
+	})
+	return info
+}
+
+// adminClient resolves the client of the request, answering with an error if
+// there is none.
+func (s *IgoServer) adminClient(w http.ResponseWriter, r *http.Request) *Client {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid client id", http.StatusBadRequest)
+		return nil
+	}
+
+	client := s.clients.get(id)
+	if client == nil {
+		http.Error(w, "unknown client", http.StatusNotFound)
+		return nil
+	}
+	return client
+}
+
+func (s *IgoServer) adminNamespace(w http.ResponseWriter, r *http.Request) *Namespace {
+	ns := s.getNamespace(r.URL.Query().Get("namespace"))
+	if ns == nil {
+		http.Error(w, "unknown namespace", http.StatusNotFound)
+	}
+	return ns
+}
+
+func (s *IgoServer) adminListClients(w http.ResponseWriter, r *http.Request) {
+	clients := s.clients.snapshot()
+	infos := make([]ClientInfo, 0, len(clients))
+	for _, client := range clients {
+		infos = append(infos, s.clientInfo(client))
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *IgoServer) adminGetClient(w http.ResponseWriter, r *http.Request) {
+	if client := s.adminClient(w, r); client != nil {
+		writeJSON(w, http.StatusOK, s.clientInfo(client))
+	}
+}
+
+func (s *IgoServer) adminDisconnect(w http.ResponseWriter, r *http.Request) {
+	if client := s.adminClient(w, r); client != nil {
+		client.Disconnect(ws.CloseNormalClosure, "disconnected by admin")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *IgoServer) adminJoin(w http.ResponseWriter, r *http.Request) {
+	client := s.adminClient(w, r)
+	if client == nil {
+		return
+	}
+
+	room := client.Namespace.CreateRoom(r.PathValue("room"))
+	if err := client.Join(room); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, ErrJoinRefused) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.clientInfo(client))
+}
+
+func (s *IgoServer) adminLeave(w http.ResponseWriter, r *http.Request) {
+	client := s.adminClient(w, r)
+	if client == nil {
+		return
+	}
+
+	room := client.Namespace.GetRoom(r.PathValue("room"))
+	if room == nil {
+		http.Error(w, "unknown room", http.StatusNotFound)
+		return
+	}
+	client.Leave(room)
+	writeJSON(w, http.StatusOK, s.clientInfo(client))
+}
+
+func (s *IgoServer) adminListRooms(w http.ResponseWriter, r *http.Request) {
+	ns := s.adminNamespace(w, r)
+	if ns == nil {
+		return
+	}
+
+	rooms := ns.ListRooms()
+
+	infos := make([]RoomInfo, 0, len(rooms))
+	for _, room := range rooms {
+		infos = append(infos, roomInfo(room))
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *IgoServer) adminGetRoom(w http.ResponseWriter, r *http.Request) {
+	ns := s.adminNamespace(w, r)
+	if ns == nil {
+		return
+	}
+
+	room := ns.GetRoom(r.PathValue("room"))
+	if room == nil {
+		http.Error(w, "unknown room", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, roomInfo(room))
+}
+
+func (s *IgoServer) adminBroadcast(w http.ResponseWriter, r *http.Request) {
+	var broadcast adminBroadcast
+	if err := json.NewDecoder(r.Body).Decode(&broadcast); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if broadcast.Event == "" {
+		http.Error(w, "missing event", http.StatusBadRequest)
+		return
+	}
+
+	ns := s.getNamespace(broadcast.Namespace)
+	if ns == nil {
+		http.Error(w, "unknown namespace", http.StatusNotFound)
+		return
+	}
+
+	ns.To(broadcast.Rooms...).Emit(broadcast.Event, broadcast.Data)
+	w.WriteHeader(http.StatusNoContent)
+}
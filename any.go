@@ -0,0 +1,73 @@
+package socketigo
+
+// AnyListener observes every incoming event, before the listeners of the event
+// run.
+type AnyListener func(client *Client, eventName string, data map[string]interface{})
+
+// OnAny observes the incoming events of the client.
+func (c *Client) OnAny(listener AnyListener) {
+	c.anyMu.Lock()
+	defer c.anyMu.Unlock()
+
+	c.anyListener = listener
+}
+
+func (c *Client) OffAny() {
+	c.OnAny(nil)
+}
+
+func (c *Client) anyHandler() AnyListener {
+	c.anyMu.RLock()
+	defer c.anyMu.RUnlock()
+
+	return c.anyListener
+}
+
+// OnAny observes the incoming events of every client of the namespace.
+func (n *Namespace) OnAny(listener AnyListener) {
+	n.eventsMu.Lock()
+	defer n.eventsMu.Unlock()
+
+	n.anyListener = listener
+}
+
+func (n *Namespace) OffAny() {
+	n.OnAny(nil)
+}
+
+// OnAny observes the incoming events of every client, across all namespaces.
+func (s *IgoServer) OnAny(listener AnyListener) {
+	s.anyMu.Lock()
+	defer s.anyMu.Unlock()
+
+	s.anyListener = listener
+}
+
+func (s *IgoServer) OffAny() {
+	s.OnAny(nil)
+}
+
+func (s *IgoServer) anyHandler() AnyListener {
+	s.anyMu.RLock()
+	defer s.anyMu.RUnlock()
+
+	return s.anyListener
+}
+
+func (c *Client) notifyAny(eventName string, data map[string]interface{}) {
+	if listener := c.Server.anyHandler(); listener != nil {
+		listener(c, eventName, data)
+	}
+
+	c.Namespace.eventsMu.RLock()
+	nsListener := c.Namespace.anyListener
+	c.Namespace.eventsMu.RUnlock()
+
+	if nsListener != nil {
+		nsListener(c, eventName, data)
+	}
+
+	if listener := c.anyHandler(); listener != nil {
+		listener(c, eventName, data)
+	}
+}
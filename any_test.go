@@ -0,0 +1,40 @@
+package socketigo_test
+
+import (
+	"testing"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+func TestOnAnyWhileEventsArrive(t *testing.T) {
+	server := echoServer(nil)
+	seen := make(chan string, 64)
+	server.OnConnected(func(client *socketigo.Client) {
+		client.OnAny(func(client *socketigo.Client, eventName string, data map[string]interface{}) {
+			seen <- eventName
+		})
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			client.Emit("echo", map[string]interface{}{"n": i})
+		}
+	}()
+	// the listeners get replaced while the reader calls them
+	for i := 0; i < 20; i++ {
+		server.OnAny(func(client *socketigo.Client, eventName string, data map[string]interface{}) {})
+		server.OffAny()
+	}
+	<-done
+
+	for i := 0; i < 20; i++ {
+		client.ExpectEvent(t, "echo")
+		if eventName := <-seen; eventName != "echo" {
+			t.Fatalf("any listener saw %q, want echo", eventName)
+		}
+	}
+}
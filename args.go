@@ -0,0 +1,111 @@
+package socketigo
+
+import (
+	"fmt"
+
+	"github.com/goccy/go-json"
+)
+
+// Args are the positional arguments of a multi-argument event, as emitted with
+// EmitArgs. Each one stays encoded until it gets decoded into its type.
+type Args []json.RawMessage
+
+// Decode decodes the argument at index i into out.
+func (a Args) Decode(i int, out interface{}) error {
+	if i < 0 || i >= len(a) {
+		return fmt.Errorf("%w: missing argument %d of %d", ErrDecodeFailed, i, len(a))
+	}
+	if err := json.Unmarshal(a[i], out); err != nil {
+		return fmt.Errorf("%w: argument %d: %w", ErrDecodeFailed, i, err)
+	}
+	return nil
+}
+
+// Scan decodes the arguments in order into outs. Surplus arguments are
+// ignored, missing ones fail.
+func (a Args) Scan(outs ...interface{}) error {
+	for i, out := range outs {
+		if err := a.Decode(i, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ArgsListener receives the positional arguments of an event.
+type ArgsListener func(client *Client, args Args) interface{}
+
+func (l ArgsListener) contextListener() ContextListener {
+	return func(ctx *EventContext, data map[string]interface{}) interface{} {
+		args, err := ctx.frame.args()
+		if err != nil {
+			ctx.Client.Server.ReportError(ctx.Client, fmt.Errorf("event %q: %w", ctx.Event, err))
+			return err
+		}
+		return l(ctx.Client, args)
+	}
+}
+
+// args splits the data of a multi-argument frame into its arguments. The data
+// of any other frame is its single argument.
+func (f *Frame) args() (Args, error) {
+	raw, err := f.raw()
+	if err != nil || len(raw) == 0 {
+		return nil, err
+	}
+	if !f.Args {
+		return Args{raw}, nil
+	}
+
+	var args Args
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("%w: arguments: %w", ErrDecodeFailed, err)
+	}
+	return args, nil
+}
+
+// OnArgs adds a listener getting the arguments of the event by position, like
+// the listeners of socket.io. Events emitted with a single payload carry it as
+// the only argument.
+func (c *Client) OnArgs(eventName string, listener ArgsListener) *Subscription {
+	return c.events.addTyped(eventName, listener.contextListener())
+}
+
+// OnArgs adds an arguments listener for every client of the namespace.
+func (n *Namespace) OnArgs(eventName string, listener ArgsListener) *Subscription {
+	return n.events.addTyped(eventName, listener.contextListener())
+}
+
+// EmitArgs emits a multi-argument event, its data being the array of the
+// arguments flagged with "args": true.
+func (c *Client) EmitArgs(eventName string, args ...interface{}) error {
+	return c.enqueueFrame(map[string]interface{}{
+		"event": eventName,
+		"data":  argsData(args),
+		"args":  true,
+	})
+}
+
+func (n *Namespace) EmitArgs(eventName string, args ...interface{}) {
+	n.emitPacket(&BroadcastPacket{
+		Event: eventName,
+		Data:  argsData(args),
+		Args:  true,
+	})
+}
+
+func (b *BroadcastOperator) EmitArgs(eventName string, args ...interface{}) {
+	packet := b.packet()
+	packet.Event = eventName
+	packet.Data = argsData(args)
+	packet.Args = true
+	b.namespace.emitPacket(packet)
+}
+
+// argsData keeps emitting no arguments from encoding a null.
+func argsData(args []interface{}) []interface{} {
+	if args == nil {
+		return []interface{}{}
+	}
+	return args
+}
@@ -0,0 +1,172 @@
+package socketigo
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// Attachments follow the socket.io placeholder protocol: the data of the frame
+// refers to them with {"_placeholder": true, "num": i} and "attachments" holds
+// their number. With text codecs they are sent as as many binary messages
+// right behind the frame, binary codecs carry them inline as a list of bytes.
+
+// maxAttachments bounds the attachments of a single frame.
+const maxAttachments = 256
+
+// Placeholder stands in for the attachment at index num within the data of
+// EmitAttachments.
+func Placeholder(num int) map[string]interface{} {
+	return map[string]interface{}{
+		"_placeholder": true,
+		"num":          num,
+	}
+}
+
+// EmitAttachments emits the event along with binary attachments, sent as they
+// are instead of base64 encoded within the data. The data refers to them with
+// Placeholder, listeners taking a map find the bytes in their place.
+func (c *Client) EmitAttachments(eventName string, data interface{}, attachments ...[]byte) error {
+	frame := map[string]interface{}{
+		"event": eventName,
+		"data":  data,
+	}
+	return c.sendFrame(frame, c.Server.attach(frame, attachments), false)
+}
+
+func (n *Namespace) EmitAttachments(eventName string, data interface{}, attachments ...[]byte) {
+	n.emitPacket(&BroadcastPacket{
+		Event:       eventName,
+		Data:        data,
+		Attachments: attachments,
+	})
+}
+
+func (b *BroadcastOperator) EmitAttachments(eventName string, data interface{}, attachments ...[]byte) {
+	packet := b.packet()
+	packet.Event = eventName
+	packet.Data = data
+	packet.Attachments = attachments
+	b.namespace.emitPacket(packet)
+}
+
+// attach adds the attachments to the frame, returning the ones to send as
+// binary messages behind it.
+func (s *IgoServer) attach(frame map[string]interface{}, attachments [][]byte) [][]byte {
+	if len(attachments) == 0 {
+		return nil
+	}
+	if s.codec.MessageType() == ws.BinaryMessage {
+		frame["attachments"] = attachments
+		return nil
+	}
+	frame["attachments"] = len(attachments)
+	return attachments
+}
+
+// Attachments returns the binary attachments the event was sent with.
+func (c *EventContext) Attachments() [][]byte {
+	return c.frame.attachments
+}
+
+// addAttachment collects the binary messages following a frame announcing
+// attachments, handing the frame on once all of them arrived. A frame whose
+// attachments add up to more than MaxChunkedSize is rejected, the attachments
+// still to come are discarded.
+func (c *Client) addAttachment(data []byte) {
+	frame := c.attaching
+	frame.size += len(data)
+	if limit := c.Server.maxChunkedSize; !frame.dropped && int64(frame.size) > limit {
+		frame.dropped = true
+		clear(frame.attachments)
+		c.acknowledge(frame, c.rejectOversized(int64(frame.size), limit))
+	}
+	if frame.dropped {
+		frame.attachments = append(frame.attachments, nil)
+	} else {
+		frame.attachments = append(frame.attachments, bytes.Clone(data))
+	}
+	if len(frame.attachments) < frame.Attachments {
+		return
+	}
+
+	c.attaching = nil
+	if frame.dropped {
+		releaseFrame(frame)
+		return
+	}
+	c.handleFrame(frame)
+}
+
+// inlineAttachments converts the attachments binary codecs decoded.
+func inlineAttachments(value interface{}) ([][]byte, bool) {
+	if value == nil {
+		return nil, true
+	}
+
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	attachments := make([][]byte, len(list))
+	for i, item := range list {
+		if attachments[i], ok = item.([]byte); !ok {
+			return nil, false
+		}
+	}
+	return attachments, true
+}
+
+func isPlaceholder(value map[string]interface{}) bool {
+	placeholder, _ := value["_placeholder"].(bool)
+	return placeholder
+}
+
+func attachmentAt(placeholder map[string]interface{}, attachments [][]byte) ([]byte, error) {
+	num := reflect.ValueOf(placeholder["num"])
+	index := -1
+	switch num.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		index = int(num.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		index = int(num.Uint())
+	case reflect.Float32, reflect.Float64:
+		if f := num.Float(); f == float64(int(f)) {
+			index = int(f)
+		}
+	}
+
+	if index < 0 || index >= len(attachments) {
+		return nil, fmt.Errorf("placeholder %v refers to none of %d attachments", placeholder["num"], len(attachments))
+	}
+	return attachments[index], nil
+}
+
+// resolvePlaceholders returns the attachment a placeholder refers to, any
+// other value with the placeholders within its maps and lists replaced.
+func resolvePlaceholders(value interface{}, attachments [][]byte) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if isPlaceholder(v) {
+			return attachmentAt(v, attachments)
+		}
+		for key, item := range v {
+			resolved, err := resolvePlaceholders(item, attachments)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+	case []interface{}:
+		for i, item := range v {
+			resolved, err := resolvePlaceholders(item, attachments)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+	}
+	return value, nil
+}
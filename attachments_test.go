@@ -0,0 +1,72 @@
+package socketigo_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/igoclient"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+func TestAttachments(t *testing.T) {
+	server := socketigo.CreateIgoServer(&socketigo.IgoServerOptions{MaxMessageSize: 4 << 10})
+	uploads := make(chan [][]byte, 2)
+	server.On("upload", func(client *socketigo.Client, data map[string]interface{}) interface{} {
+		files, _ := data["files"].([]interface{})
+		var attachments [][]byte
+		for _, file := range files {
+			attachment, _ := file.([]byte)
+			attachments = append(attachments, attachment)
+		}
+		uploads <- attachments
+		return nil
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	serverErrors := make(chan error, 1)
+	client.OnError(func(client *igoclient.Client, err error) {
+		serverErrors <- err
+	})
+
+	first, second := bytes.Repeat([]byte{1}, 1<<10), bytes.Repeat([]byte{2}, 2<<10)
+	data := map[string]interface{}{"files": []interface{}{igoclient.Placeholder(0), igoclient.Placeholder(1)}}
+	if err := client.EmitAttachments("upload", data, first, second); err != nil {
+		t.Fatalf("emitting attachments failed: %v", err)
+	}
+	select {
+	case attachments := <-uploads:
+		if len(attachments) != 2 || !bytes.Equal(attachments[0], first) || !bytes.Equal(attachments[1], second) {
+			t.Fatal("attachments did not arrive in place of their placeholders")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("frame with attachments did not get dispatched")
+	}
+
+	// every attachment is within MaxMessageSize, all of them are not
+	large := bytes.Repeat([]byte{3}, 3<<10)
+	data = map[string]interface{}{"files": []interface{}{igoclient.Placeholder(0), igoclient.Placeholder(1), igoclient.Placeholder(2)}}
+	client.EmitAttachments("upload", data, large, large, large)
+	select {
+	case err := <-serverErrors:
+		var serverErr *igoclient.ServerError
+		if !errors.As(err, &serverErr) || serverErr.Code != "message_too_large" {
+			t.Fatalf("error is %v, want message_too_large", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("oversized attachments did not get rejected")
+	}
+
+	// the rest of the rejected attachments must not be taken for binary events
+	client.EmitAttachments("upload", data, first, first, first)
+	select {
+	case attachments := <-uploads:
+		if len(attachments) != 3 {
+			t.Fatalf("got %d attachments, want the 3 of the frame after the rejected one", len(attachments))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("frame after the rejected one did not get dispatched")
+	}
+}
@@ -0,0 +1,89 @@
+package socketigo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+const (
+	authEvent          = "#auth"
+	errorEvent         = "#error"
+	handshakeEvent     = "#handshake"
+	defaultAuthTimeout = 10 * time.Second
+	// defaultMaxAuthSize bounds the auth frame without a MaxMessageSize
+	defaultMaxAuthSize = 64 << 10
+)
+
+// Authenticator validates the payload of the "#auth" frame a client has to send
+// as its very first message. Returning an error rejects the connection.
+type Authenticator func(ctx *HandshakeContext, payload map[string]interface{}) error
+
+// SetAuthenticator enables the authenticated handshake. Once set, the connected
+// event only fires after the authenticator accepted the client's auth payload.
+func (s *IgoServer) SetAuthenticator(authenticator Authenticator) {
+	s.authenticator = authenticator
+}
+
+// readAuthFrame reads the auth frame of at most limit bytes. A larger one fails
+// with ErrMessageTooLarge without being buffered.
+func readAuthFrame(conn Conn, codec Codec, timeout time.Duration, limit int64) (map[string]interface{}, error) {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	_, reader, err := conn.NextReader()
+	if err != nil {
+		return nil, fmt.Errorf("reading auth frame: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading auth frame: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: auth frame, limit is %d bytes", ErrMessageTooLarge, limit)
+	}
+
+	frame, err := decodeFrame(codec, data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding auth frame: %w", err)
+	}
+
+	defer releaseFrame(frame)
+
+	if frame.Event != authEvent {
+		return nil, errors.New("expected auth frame as first message")
+	}
+
+	payload, err := frame.payload()
+	if err != nil {
+		return nil, fmt.Errorf("decoding auth frame: %w", err)
+	}
+	return payload, nil
+}
+
+// rejectHandshake sends a structured error frame to the peer and closes the
+// connection with the close code afterwards.
+func rejectHandshake(conn Conn, codec Codec, closeCode int, code string, err error) {
+	deadline := time.Now().Add(time.Second)
+
+	conn.SetWriteDeadline(deadline)
+	writeFrame(conn, codec, map[string]interface{}{
+		"event": errorEvent,
+		"data": map[string]interface{}{
+			"code":    code,
+			"message": err.Error(),
+		},
+	})
+
+	reason := err.Error()
+	// close frames may carry at most 123 bytes of reason
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+
+	conn.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(closeCode, reason), deadline)
+	conn.Close()
+}
@@ -0,0 +1,139 @@
+package socketigo
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// Binary frames are laid out as a big endian uint16 holding the length of the
+// event name, followed by the event name and the raw payload.
+
+type BinaryListener func(client *Client, data []byte)
+
+var errMalformedBinaryFrame = errors.New("malformed binary frame")
+
+func encodeBinaryFrame(eventName string, data []byte) ([]byte, error) {
+	if len(eventName) > math.MaxUint16 {
+		return nil, errors.New("socketigo: event name too long")
+	}
+
+	frame := make([]byte, 2+len(eventName)+len(data))
+	binary.BigEndian.PutUint16(frame, uint16(len(eventName)))
+	copy(frame[2:], eventName)
+	copy(frame[2+len(eventName):], data)
+	return frame, nil
+}
+
+func decodeBinaryFrame(frame []byte) (string, []byte, error) {
+	if len(frame) < 2 {
+		return "", nil, errMalformedBinaryFrame
+	}
+
+	nameLen := int(binary.BigEndian.Uint16(frame))
+	if nameLen == 0 || len(frame) < 2+nameLen {
+		return "", nil, errMalformedBinaryFrame
+	}
+
+	return string(frame[2 : 2+nameLen]), frame[2+nameLen:], nil
+}
+
+func dispatchBinary(client *Client, eventName string, data []byte) {
+	_, end := client.Server.tracer.StartEvent(client.ctx, client, eventName, nil)
+	var spanErr error
+	defer func() { end(spanErr) }()
+
+	defer func() {
+		if value := recover(); value != nil {
+			spanErr = client.handlePanic(eventName, nil, value)
+		}
+	}()
+
+	listeners := client.binaryEvents.get(eventName)
+	if len(listeners) == 0 {
+		listeners = client.Namespace.binaryEvents.get(eventName)
+	}
+
+	if len(listeners) == 0 {
+		return
+	}
+
+	start := time.Now()
+	for _, listener := range listeners {
+		listener(client, data)
+	}
+	client.Server.metrics.HandlerDuration(client.Namespace.Name, eventName, time.Since(start))
+}
+
+func (c *Client) OnBinary(eventName string, listener BinaryListener) *Subscription {
+	return c.binaryEvents.add(eventName, listener)
+}
+
+func (c *Client) OffBinary(eventName string) {
+	c.binaryEvents.removeAll(eventName)
+}
+
+func (c *Client) EmitBinary(eventName string, data []byte) error {
+	// binary codecs carry binary events inside their regular frames
+	if c.Server.codec.MessageType() == ws.BinaryMessage {
+		return c.enqueueFrame(map[string]interface{}{
+			"event":  eventName,
+			"data":   data,
+			"binary": true,
+		})
+	}
+
+	frame, err := encodeBinaryFrame(eventName, data)
+	if err != nil {
+		return err
+	}
+
+	message := outboundMessage{messageType: ws.BinaryMessage, data: frame}
+	if c.chunks(eventName, len(frame)) {
+		if err := c.enqueueChunked(message, false); err != nil {
+			return err
+		}
+	} else if err := c.enqueueMessage(message); err != nil {
+		return err
+	}
+	c.Server.metrics.EventSent(c.Namespace.Name, eventName, len(frame))
+	return nil
+}
+
+// OnBinary adds a binary listener for every client of the namespace. Listeners
+// registered on the client itself take precedence.
+func (n *Namespace) OnBinary(eventName string, listener BinaryListener) *Subscription {
+	return n.binaryEvents.add(eventName, listener)
+}
+
+func (n *Namespace) OffBinary(eventName string) {
+	n.binaryEvents.removeAll(eventName)
+}
+
+func (n *Namespace) EmitBinary(eventName string, data []byte) {
+	n.emitPacket(&BroadcastPacket{
+		Event:    eventName,
+		Binary:   data,
+		IsBinary: true,
+	})
+}
+
+func (r *Room) EmitBinary(eventName string, data []byte) {
+	r.Namespace.emitPacket(&BroadcastPacket{
+		Rooms:    []string{r.Id},
+		Event:    eventName,
+		Binary:   data,
+		IsBinary: true,
+	})
+}
+
+func (b *BroadcastOperator) EmitBinary(eventName string, data []byte) {
+	packet := b.packet()
+	packet.Event = eventName
+	packet.Binary = data
+	packet.IsBinary = true
+	b.namespace.emitPacket(packet)
+}
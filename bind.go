@@ -0,0 +1,97 @@
+package socketigo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	validateOnce sync.Once
+	validate     *validator.Validate
+)
+
+// Validator returns the validator checking the `validate` tags of bound
+// payloads, e.g. to register custom validations. Field names are taken from
+// the json tags.
+func Validator() *validator.Validate {
+	validateOnce.Do(func() {
+		validate = validator.New(validator.WithRequiredStructEnabled())
+		validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	})
+	return validate
+}
+
+// FieldError describes a single field failing its validation.
+type FieldError struct {
+	Field string `json:"field"`
+	Tag   string `json:"tag"`
+	Param string `json:"param,omitempty"`
+}
+
+// ValidationError is returned by Bind when the payload does not satisfy the
+// `validate` tags. Listeners returning it acknowledge with the failed fields.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		fields[i] = field.Field + " (" + field.Tag + ")"
+	}
+	return "socketigo: validation failed: " + strings.Join(fields, ", ")
+}
+
+// Bind decodes the payload into out, a pointer to a struct, and validates it.
+func Bind(data map[string]interface{}, out interface{}) error {
+	if err := decodePayload(data, out); err != nil {
+		return err
+	}
+	return validateStruct(out)
+}
+
+// validateStruct validates structs and pointers to them, other values pass.
+func validateStruct(value interface{}) error {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	err := Validator().Struct(value)
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+
+	validationErr := &ValidationError{Fields: make([]FieldError, len(errs))}
+	for i, fieldErr := range errs {
+		// drop the name of the struct itself
+		_, field, _ := strings.Cut(fieldErr.Namespace(), ".")
+		validationErr.Fields[i] = FieldError{
+			Field: field,
+			Tag:   fieldErr.Tag(),
+			Param: fieldErr.Param(),
+		}
+	}
+	return validationErr
+}
@@ -0,0 +1,164 @@
+package socketigo
+
+// BroadcastOperator targets a set of clients of a namespace. Every method
+// returns a new operator, so partially built operators can be reused.
+type BroadcastOperator struct {
+	namespace    *Namespace
+	rooms        []string
+	roomPatterns []string
+	exceptRooms  []string
+	except       []*Client
+	volatile     bool
+}
+
+func (n *Namespace) broadcast() *BroadcastOperator {
+	return &BroadcastOperator{namespace: n}
+}
+
+// To targets the members of the given rooms. Without any rooms the whole
+// namespace is targeted.
+func (n *Namespace) To(rooms ...string) *BroadcastOperator {
+	return n.broadcast().To(rooms...)
+}
+
+// EmitToRooms emits to the members of every given room. Clients being in
+// several of them get the event once.
+func (n *Namespace) EmitToRooms(rooms []string, eventName string, data interface{}) {
+	if len(rooms) == 0 {
+		return
+	}
+	n.To(rooms...).Emit(eventName, data)
+}
+
+// ToPattern targets the members of every room whose Id matches one of the
+// patterns, using the syntax of event name patterns: a "*" matches any run of
+// characters and a "+" a single segment. So the hierarchical Id
+// "game/42/team/red" is matched by "game/42/*" and "game/+/team/red".
+func (n *Namespace) ToPattern(patterns ...string) *BroadcastOperator {
+	return n.broadcast().ToPattern(patterns...)
+}
+
+// EmitToPattern emits to the members of every room matching the pattern.
+// Clients being in several of them get the event once.
+func (n *Namespace) EmitToPattern(pattern string, eventName string, data interface{}) {
+	n.ToPattern(pattern).Emit(eventName, data)
+}
+
+// Volatile skips congested clients, see Client.EmitVolatile.
+func (n *Namespace) Volatile() *BroadcastOperator {
+	return n.broadcast().Volatile()
+}
+
+func (n *Namespace) Except(clients ...*Client) *BroadcastOperator {
+	return n.broadcast().Except(clients...)
+}
+
+func (b *BroadcastOperator) clone() *BroadcastOperator {
+	return &BroadcastOperator{
+		namespace:    b.namespace,
+		rooms:        append([]string(nil), b.rooms...),
+		roomPatterns: append([]string(nil), b.roomPatterns...),
+		exceptRooms:  append([]string(nil), b.exceptRooms...),
+		except:       append([]*Client(nil), b.except...),
+		volatile:     b.volatile,
+	}
+}
+
+func (b *BroadcastOperator) To(rooms ...string) *BroadcastOperator {
+	op := b.clone()
+	op.rooms = append(op.rooms, rooms...)
+	return op
+}
+
+func (b *BroadcastOperator) ToPattern(patterns ...string) *BroadcastOperator {
+	op := b.clone()
+	op.roomPatterns = append(op.roomPatterns, patterns...)
+	return op
+}
+
+// Volatile skips the clients whose outbound queue is at least half full or
+// whose connection is lost, instead of queueing, buffering or applying the
+// backpressure policy. Meant for frequent state updates of which only the
+// latest matters.
+func (b *BroadcastOperator) Volatile() *BroadcastOperator {
+	op := b.clone()
+	op.volatile = true
+	return op
+}
+
+func (b *BroadcastOperator) Except(clients ...*Client) *BroadcastOperator {
+	op := b.clone()
+	op.except = append(op.except, clients...)
+	return op
+}
+
+// ExceptRooms excludes every member of the given rooms.
+func (b *BroadcastOperator) ExceptRooms(rooms ...string) *BroadcastOperator {
+	op := b.clone()
+	op.exceptRooms = append(op.exceptRooms, rooms...)
+	return op
+}
+
+// Clients resolves the targeted clients. Clients being in several of the
+// targeted rooms are only returned once.
+func (b *BroadcastOperator) Clients() []*Client {
+	excluded := make(map[*Client]struct{}, len(b.except))
+	for _, client := range b.except {
+		excluded[client] = struct{}{}
+	}
+	for _, name := range b.exceptRooms {
+		if room := b.namespace.GetRoom(name); room != nil {
+			room.clients.each(func(client *Client) {
+				excluded[client] = struct{}{}
+			})
+		}
+	}
+
+	var candidates []*Client
+	if len(b.rooms) == 0 && len(b.roomPatterns) == 0 {
+		candidates = b.namespace.clients.snapshot()
+	} else {
+		for _, name := range b.rooms {
+			if room := b.namespace.GetRoom(name); room != nil {
+				candidates = append(candidates, room.clients.snapshot()...)
+			}
+		}
+		for _, room := range b.namespace.matchRooms(b.roomPatterns) {
+			candidates = append(candidates, room.clients.snapshot()...)
+		}
+	}
+
+	clients := make([]*Client, 0, len(candidates))
+	for _, client := range candidates {
+		if _, ok := excluded[client]; ok {
+			continue
+		}
+		excluded[client] = struct{}{}
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+func (b *BroadcastOperator) Emit(eventName string, data interface{}) {
+	packet := b.packet()
+	packet.Event = eventName
+	packet.Data = data
+	b.namespace.emitPacket(packet)
+}
+
+// Join makes the targeted clients join the rooms, on every node of the
+// cluster, creating rooms which do not exist yet. Rooms guarded against a
+// client are skipped for it.
+func (b *BroadcastOperator) Join(rooms ...string) {
+	packet := b.packet()
+	packet.Join = rooms
+	b.namespace.emitPacket(packet)
+}
+
+// Leave makes the targeted clients leave the rooms, on every node of the
+// cluster.
+func (b *BroadcastOperator) Leave(rooms ...string) {
+	packet := b.packet()
+	packet.Leave = rooms
+	b.namespace.emitPacket(packet)
+}
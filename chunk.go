@@ -0,0 +1,210 @@
+package socketigo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// Chunks are binary events named "#chunk" whose payload starts with a header of
+// the big endian uint32 id of the message, the uint32 index of the chunk and
+// the uint32 total of chunks, followed by the websocket message type of the
+// message as a byte. The pieces of the message follow in order, once all of
+// them arrived the message gets handled as if it had been sent whole.
+// Attachments are never chunked.
+
+const (
+	chunkEvent            = "#chunk"
+	chunkingParam         = "chunking"
+	chunkHeaderSize       = 13
+	defaultMaxChunkedSize = 32 << 20
+	// maxPendingChunked bounds the messages a client may send in chunks at
+	// the same time.
+	maxPendingChunked = 8
+)
+
+// ChunkProgress reports how far the transfer of a chunked message got.
+type ChunkProgress struct {
+	Id uint32
+	// Chunks is the number of chunks sent or received so far, out of Total.
+	Chunks int
+	Total  int
+	// Bytes is the size of the chunks transferred so far.
+	Bytes int
+	// Outbound is set for messages sent to the client.
+	Outbound bool
+}
+
+type chunkedMessage struct {
+	messageType int
+	total       int
+	received    int
+	data        []byte
+	// dropped messages are kept until their last chunk, which would start a new
+	// message otherwise
+	dropped bool
+}
+
+// OnChunkProgress gets called whenever a chunk of a message sent to or by the
+// client was written or read.
+func (c *Client) OnChunkProgress(listener func(progress ChunkProgress)) {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+
+	c.progressHandler = listener
+}
+
+func (c *Client) reportProgress(progress ChunkProgress) {
+	c.progressMu.RLock()
+	handler := c.progressHandler
+	c.progressMu.RUnlock()
+
+	if handler != nil {
+		handler(progress)
+	}
+}
+
+// chunking reports whether the client negotiated chunked messages, in both
+// directions.
+func (c *Client) chunking() bool {
+	return c.Server.chunkSize > 0 && c.Query().Get(chunkingParam) != ""
+}
+
+// chunks reports whether a message of the event of size bytes gets split for
+// the client. Stream frames are no larger than streamFrameSize and never get
+// split, since they must not come in chunked messages.
+func (c *Client) chunks(eventName string, size int) bool {
+	return c.chunking() && size > c.Server.chunkSize && eventName != streamEvent
+}
+
+// enqueueChunked queues the message in chunks, the attachments going with the
+// last one. Volatile messages are dropped as a whole if the client is
+// congested.
+func (c *Client) enqueueChunked(message outboundMessage, volatile bool) error {
+	if volatile && c.congested() {
+		return nil
+	}
+
+	id := atomic.AddUint32(&c.chunkSeq, 1)
+	size := c.Server.chunkSize
+	total := (len(message.data) + size - 1) / size
+
+	for index := 0; index < total; index++ {
+		end := min((index+1)*size, len(message.data))
+		data, err := c.Server.encodeChunk(id, index, total, message.messageType, message.data[index*size:end])
+		if err != nil {
+			return err
+		}
+
+		chunk := outboundMessage{messageType: ws.BinaryMessage, data: data}
+		if index == total-1 {
+			chunk.attachments = message.attachments
+		}
+		progress := ChunkProgress{Id: id, Chunks: index + 1, Total: total, Bytes: end, Outbound: true}
+		chunk.written = func() {
+			c.reportProgress(progress)
+		}
+
+		if err := c.enqueueMessage(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *IgoServer) encodeChunk(id uint32, index int, total int, messageType int, piece []byte) ([]byte, error) {
+	payload := make([]byte, chunkHeaderSize+len(piece))
+	binary.BigEndian.PutUint32(payload, id)
+	binary.BigEndian.PutUint32(payload[4:], uint32(index))
+	binary.BigEndian.PutUint32(payload[8:], uint32(total))
+	payload[12] = byte(messageType)
+	copy(payload[chunkHeaderSize:], piece)
+
+	// binary codecs carry binary events inside their regular frames
+	if s.codec.MessageType() == ws.BinaryMessage {
+		return s.codec.Marshal(map[string]interface{}{
+			"event":  chunkEvent,
+			"data":   payload,
+			"binary": true,
+		})
+	}
+	return encodeBinaryFrame(chunkEvent, payload)
+}
+
+// addChunk collects a chunk sent by the client, handling the message once its
+// last chunk arrived.
+func (c *Client) addChunk(payload []byte) {
+	if !c.chunking() {
+		c.rejectFrame(nil, errors.New("chunking not negotiated"))
+		return
+	}
+	if len(payload) < chunkHeaderSize {
+		c.rejectFrame(nil, errors.New("chunk header too short"))
+		return
+	}
+
+	id := binary.BigEndian.Uint32(payload)
+	index := int(binary.BigEndian.Uint32(payload[4:]))
+	total := int(binary.BigEndian.Uint32(payload[8:]))
+	messageType := int(payload[12])
+	piece := payload[chunkHeaderSize:]
+
+	message := c.chunked[id]
+	if message == nil {
+		if err := c.startChunked(index, total, messageType); err != nil {
+			c.rejectFrame(nil, err)
+			return
+		}
+		message = &chunkedMessage{messageType: messageType, total: total}
+		if c.chunked == nil {
+			c.chunked = make(map[uint32]*chunkedMessage)
+		}
+		c.chunked[id] = message
+	}
+
+	if index != message.received || total != message.total || messageType != message.messageType {
+		delete(c.chunked, id)
+		c.rejectFrame(nil, fmt.Errorf("chunk %d of %d of message %d out of order", index, total, id))
+		return
+	}
+	message.received++
+	if message.dropped {
+		if message.received == message.total {
+			delete(c.chunked, id)
+		}
+		return
+	}
+	if size := int64(len(message.data) + len(piece)); size > c.Server.maxChunkedSize {
+		message.data, message.dropped = nil, true
+		if message.received == message.total {
+			delete(c.chunked, id)
+		}
+		c.rejectOversized(size, c.Server.maxChunkedSize)
+		return
+	}
+
+	message.data = append(message.data, piece...)
+	c.reportProgress(ChunkProgress{Id: id, Chunks: message.received, Total: total, Bytes: len(message.data)})
+
+	if message.received == message.total {
+		delete(c.chunked, id)
+		c.handleMessage(message.messageType, message.data, true)
+	}
+}
+
+func (c *Client) startChunked(index int, total int, messageType int) error {
+	switch {
+	case index != 0:
+		return fmt.Errorf("chunked message starting with chunk %d", index)
+	case total < 1:
+		return errors.New("chunked message without chunks")
+	case messageType != ws.TextMessage && messageType != ws.BinaryMessage:
+		return fmt.Errorf("chunked message of message type %d", messageType)
+	case len(c.chunked) >= maxPendingChunked:
+		return fmt.Errorf("more than %d chunked messages at a time", maxPendingChunked)
+	}
+	return nil
+}
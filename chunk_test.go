@@ -0,0 +1,139 @@
+package socketigo_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/igoclient"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+func TestChunkedMessages(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{ChunkSize: 1 << 10})
+	chunks := make(chan socketigo.ChunkProgress, 64)
+	server.OnConnected(func(client *socketigo.Client) {
+		client.OnChunkProgress(func(progress socketigo.ChunkProgress) {
+			chunks <- progress
+		})
+	})
+
+	client := testclient.MustConnect(t, server, &testclient.Options{ChunkSize: 1 << 10})
+	text := strings.Repeat("abcdefgh", 1<<10)
+	client.Emit("echo", map[string]interface{}{"text": text})
+
+	if data := client.ExpectEvent(t, "echo"); data["text"] != text {
+		t.Fatal("chunked message did not arrive intact")
+	}
+
+	// the progress of the last chunk to the client may come after the echo
+	var inbound, outbound int
+	for inbound == 0 || outbound == 0 {
+		select {
+		case progress := <-chunks:
+			if progress.Chunks != progress.Total {
+				continue
+			}
+			if progress.Outbound {
+				outbound = progress.Total
+			} else {
+				inbound = progress.Total
+			}
+		case <-time.After(time.Second):
+			t.Fatal("chunk progress did not get reported")
+		}
+	}
+	if inbound < 8 || outbound < 8 {
+		t.Fatalf("message came in %d chunks and went out in %d, want at least 8 each", inbound, outbound)
+	}
+}
+
+func TestChunkedMessageLimit(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{ChunkSize: 1 << 10, MaxMessageSize: 4 << 10})
+
+	client := testclient.MustConnect(t, server, &testclient.Options{ChunkSize: 1 << 10})
+	serverErrors := make(chan error, 1)
+	client.OnError(func(client *igoclient.Client, err error) {
+		serverErrors <- err
+	})
+
+	// MaxMessageSize bounds the reassembled message, not only its chunks
+	client.Emit("echo", map[string]interface{}{"text": strings.Repeat("a", 8<<10)})
+	select {
+	case err := <-serverErrors:
+		var serverErr *igoclient.ServerError
+		if !errors.As(err, &serverErr) || serverErr.Code != "message_too_large" {
+			t.Fatalf("error is %v, want message_too_large", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("oversized chunked message did not get answered with an error")
+	}
+	client.ExpectNoEvent(t, "echo", 50*time.Millisecond)
+
+	text := strings.Repeat("a", 3<<10)
+	client.Emit("echo", map[string]interface{}{"text": text})
+	if data := client.ExpectEvent(t, "echo"); data["text"] != text {
+		t.Fatal("chunked message within the limit did not arrive intact")
+	}
+}
+
+// binaryFrame encodes a binary event the way the JSON codec sends it.
+func binaryFrame(eventName string, data []byte) []byte {
+	frame := binary.BigEndian.AppendUint16(nil, uint16(len(eventName)))
+	frame = append(frame, eventName...)
+	return append(frame, data...)
+}
+
+// chunk encodes the only chunk of a binary message.
+func chunk(id uint32, message []byte) []byte {
+	payload := binary.BigEndian.AppendUint32(nil, id)
+	payload = binary.BigEndian.AppendUint32(payload, 0)
+	payload = binary.BigEndian.AppendUint32(payload, 1)
+	payload = append(payload, byte(ws.BinaryMessage))
+	return append(payload, message...)
+}
+
+func TestNestedChunkRejected(t *testing.T) {
+	server := socketigo.CreateIgoServer(&socketigo.IgoServerOptions{ChunkSize: 1 << 10})
+	pings := make(chan []byte, 2)
+	server.OnBinary("ping", func(client *socketigo.Client, data []byte) {
+		pings <- data
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	serverErrors := make(chan error, 1)
+	client.OnError(func(client *igoclient.Client, err error) {
+		serverErrors <- err
+	})
+
+	client.EmitBinary("#chunk", chunk(1, binaryFrame("ping", []byte("flat"))))
+	select {
+	case data := <-pings:
+		if string(data) != "flat" {
+			t.Fatalf("ping is %q, want %q", data, "flat")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("chunked binary event did not get dispatched")
+	}
+
+	nested := chunk(2, binaryFrame("#chunk", chunk(3, binaryFrame("ping", []byte("nested")))))
+	client.EmitBinary("#chunk", nested)
+	select {
+	case err := <-serverErrors:
+		var serverErr *igoclient.ServerError
+		if !errors.As(err, &serverErr) || serverErr.Code != "malformed_frame" {
+			t.Fatalf("error is %v, want malformed_frame", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("chunk inside a chunk did not get rejected")
+	}
+	select {
+	case data := <-pings:
+		t.Fatalf("nested ping %q got dispatched", data)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
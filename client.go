@@ -1,93 +1,411 @@
 package socketigo
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
 	uuid "github.com/google/uuid"
 	ws "github.com/gorilla/websocket"
 )
 
+const closeGracePeriod = time.Second
+
 type EventListener func(client *Client, data map[string]interface{}) interface{}
 
 type Client struct {
-	Id     uuid.UUID
-	Events map[string]EventListener
-	Server *IgoServer
-	socket *ws.Conn
+	Id                uuid.UUID
+	Server            *IgoServer
+	Namespace         *Namespace
+	socket            Conn
+	request           *http.Request
+	events            *listenerSet[ContextListener]
+	binaryEvents      *listenerSet[BinaryListener]
+	anyMu             sync.RWMutex
+	anyListener       AnyListener
+	claims            JWTClaims
+	acksMu            sync.Mutex
+	acks              map[string]chan map[string]interface{}
+	ackSeq            uint64
+	calls             map[string]chan callReply
+	done              chan struct{}
+	closeOnce         sync.Once
+	reasonMu          sync.Mutex
+	reasonSet         bool
+	disconnectReason  DisconnectReason
+	disconnectHandler func(reason DisconnectReason)
+	send              chan outboundMessage
+	session           *session
+	inbox             *inbox
+	ctx               context.Context
+	cancel            context.CancelFunc
+	userMu            sync.RWMutex
+	userId            string
+	lastActive        int64
+	state             int32
+	limiterMu         sync.RWMutex
+	limiter           *rateLimiter
+	metadataMu        sync.RWMutex
+	metadata          map[string]interface{}
+	tags              map[string]struct{}
+	roomsMu           sync.RWMutex
+	rooms             map[*Room]struct{}
+	topics            map[string]struct{}
+	seqMu             sync.Mutex
+	seq               uint64
+	// attaching is the frame whose attachments the reader is collecting
+	attaching *Frame
+	// chunked holds the messages the reader is reassembling, by id
+	chunked         map[uint32]*chunkedMessage
+	chunkSeq        uint32
+	progressMu      sync.RWMutex
+	progressHandler func(progress ChunkProgress)
+	fileHandler     func(progress FileProgress)
+	streamsMu       sync.Mutex
+	streams         map[uint32]*Stream
+	streamSeq       uint32
 }
 
-func createClient(server *IgoServer, socket *ws.Conn) *Client {
+func createClient(ctx context.Context, server *IgoServer, namespace *Namespace, socket Conn, request *http.Request) *Client {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var clientInbox *inbox
+	if server.workers != nil && server.dispatchOrder == OrderPerClient {
+		clientInbox = newInbox(server.workerQueueSize)
+	}
+
 	return &Client{
-		Server: server,
-		socket: socket,
-		Id:     uuid.New(),
-		Events: make(map[string]EventListener),
+		inbox:        clientInbox,
+		limiter:      newRateLimiter(server.rateLimit),
+		ctx:          ctx,
+		cancel:       cancel,
+		Server:       server,
+		Namespace:    namespace,
+		socket:       socket,
+		request:      request,
+		Id:           uuid.New(),
+		events:       newListenerSet[ContextListener](),
+		binaryEvents: newListenerSet[BinaryListener](),
+		acks:         make(map[string]chan map[string]interface{}),
+		done:         make(chan struct{}),
+		send:         make(chan outboundMessage, server.sendQueueSize),
 	}
 }
 
-func handleClientData(client *Client, data map[string]interface{}) {
-	eventName := data["event"].(string)
-	eventData := data["data"].(map[string]interface{})
-	ackId := ""
+func handleClientData(client *Client, frame *Frame) {
+	eventName := frame.Event
+
+	spanCtx, end := client.Server.tracer.StartEvent(client.ctx, client, eventName, frame.Trace)
+	var spanErr error
+	defer func() { end(spanErr) }()
 
-	if data["ackId"] != nil {
-		ackId = data["ackId"].(string)
+	defer func() {
+		if value := recover(); value != nil {
+			spanErr = client.handlePanic(eventName, frame, value)
+		}
+	}()
+
+	if isTopicEvent(eventName) {
+		data, err := frame.payload()
+		if err != nil {
+			client.rejectFrame(frame, err)
+			spanErr = err
+			return
+		}
+		client.handleTopicEvent(frame, data)
+		return
 	}
 
-	if listener, ok := client.Events[eventName]; ok {
-		result := listener(client, eventData)
+	listeners, typed := client.events.match(eventName)
+	if len(listeners) == 0 {
+		listeners, typed = client.Namespace.events.match(eventName)
+	}
 
-		if ackId != "" {
-			response := map[string]interface{}{
-				"result": result,
-			}
+	// typed and raw listeners decode the frame themselves, the map is only
+	// built for the ones that need it
+	var eventData map[string]interface{}
+	if !typed || client.observes(eventName) {
+		var err error
+		// any listeners get no map of payloads other than objects
+		if eventData, err = frame.payload(); err != nil && !typed {
+			client.rejectFrame(frame, err)
+			spanErr = err
+			return
+		}
+	}
+
+	client.notifyAny(eventName, eventData)
+
+	if !client.validatePayload(frame, eventData) {
+		spanErr = ErrInvalidPayload
+		return
+	}
+
+	if len(listeners) == 0 {
+		client.Server.logger.Debug("no listener for event", client.logFields("event", eventName)...)
+		if frame.CallID != "" {
+			client.reply(frame.CallID, &RPCError{Code: CodeNotFound, Message: fmt.Sprintf("no method %q", eventName)})
+		}
+		return
+	}
+
+	timeout := client.Namespace.handlerTimeout(eventName)
+	ctx, cancel := newEventContext(spanCtx, client, frame, timeout)
+	defer cancel()
 
-			client.Emit(eventName+"@ack:"+ackId, response)
+	start := time.Now()
+
+	var result interface{}
+	if timeout > 0 {
+		var ok bool
+		result, ok = runListenersWithTimeout(ctx, listeners, eventData)
+		client.Server.metrics.HandlerDuration(client.Namespace.Name, eventName, time.Since(start))
+		spanErr, _ = result.(error)
+		if !ok {
+			return
 		}
+	} else {
+		result = runListeners(ctx, listeners, eventData)
+		client.Server.metrics.HandlerDuration(client.Namespace.Name, eventName, time.Since(start))
+		spanErr, _ = result.(error)
+	}
+
+	client.acknowledge(frame, result)
+}
+
+// observes tells whether an any listener or a schema needs the decoded payload
+// of the event.
+func (c *Client) observes(eventName string) bool {
+	if c.anyHandler() != nil || c.Server.anyHandler() != nil {
+		return true
 	}
+
+	c.Namespace.eventsMu.RLock()
+	defer c.Namespace.eventsMu.RUnlock()
+
+	_, hasSchema := c.Namespace.schemas[eventName]
+	return c.Namespace.anyListener != nil || hasSchema
+}
+
+// Request returns the upgrade request the client connected with. Its body is
+// already consumed and its context ends with the connection.
+func (c *Client) Request() *http.Request {
+	return c.request
+}
+
+// RemoteAddr returns the network address of the client as "host:port".
+func (c *Client) RemoteAddr() string {
+	return c.request.RemoteAddr
+}
+
+// Header returns the headers of the upgrade request.
+func (c *Client) Header() http.Header {
+	return c.request.Header
 }
 
+// Query returns the query parameters of the upgrade request.
+func (c *Client) Query() url.Values {
+	return c.request.URL.Query()
+}
+
+// Subprotocol returns the subprotocol negotiated during the upgrade, if any.
+func (c *Client) Subprotocol() string {
+	return c.socket.Subprotocol()
+}
+
+// Claims returns the claims of the JWT the client authenticated with, if any.
+func (c *Client) Claims() JWTClaims {
+	return c.claims
+}
+
+func (c *Client) markClosed() {
+	c.setState(StateClosed)
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.cancel()
+	})
+}
+
+// Close closes the connection. A client closed by the server cannot resume its
+// session.
 func (c *Client) Close() error {
-	return c.socket.Close()
+	if c.session != nil {
+		c.session.end()
+	}
+	return c.closeWithReason(ReasonKicked)
 }
 
+// Disconnect closes the connection with a close frame carrying the code and
+// reason, written after the messages queued before it. The socket is released
+// once the client answered the close frame, or after a second at the latest.
+// Like Close, it rules out resuming the session.
+func (c *Client) Disconnect(code int, reason string) error {
+	if c.session != nil {
+		c.session.end()
+	}
+	c.setDisconnectReason(ReasonKicked)
+	c.setState(StateClosing)
+
+	// close frames may carry at most 123 bytes of reason
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	message := outboundMessage{
+		messageType: ws.CloseMessage,
+		data:        ws.FormatCloseMessage(code, reason),
+	}
+
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+
+	// bypassing enqueue, so the close frame is neither buffered by the session
+	// nor subject to backpressure
+	select {
+	case c.send <- message:
+	default:
+		err := c.socket.WriteControl(ws.CloseMessage, message.data, time.Now().Add(c.Server.writeWait))
+		if err != nil {
+			c.socket.Close()
+			return err
+		}
+	}
+
+	time.AfterFunc(closeGracePeriod, func() {
+		c.socket.Close()
+	})
+	return nil
+}
+
+// Seq returns the sequence number of the last frame sent to the client, see
+// IgoServerOptions.SequenceNumbers.
+func (c *Client) Seq() uint64 {
+	c.seqMu.Lock()
+	defer c.seqMu.Unlock()
+
+	return c.seq
+}
+
+// Emit queues the event for the client. It returns ErrClientClosed once the
+// client is closing or closed.
 func (c *Client) Emit(eventName string, data interface{}) error {
-	return c.socket.WriteJSON(map[string]interface{}{
+	return c.enqueueFrame(map[string]interface{}{
 		"event": eventName,
 		"data":  data,
 	})
 }
 
-func (c *Client) On(eventName string, listener EventListener) {
-	c.Events[eventName] = listener
+// EmitVolatile emits the event unless the client is congested, i.e. its
+// outbound queue is at least half full or the connection is lost, in which
+// case the event is dropped silently. Meant for frequent updates of which only
+// the latest matters.
+func (c *Client) EmitVolatile(eventName string, data interface{}) error {
+	return c.sendFrame(map[string]interface{}{
+		"event": eventName,
+		"data":  data,
+	}, nil, true)
 }
 
-func (c *Client) Once(eventName string, listener EventListener) {
-	c.Events[eventName] = func(client *Client, data map[string]interface{}) interface{} {
-		delete(client.Events, eventName)
-		return listener(client, data)
-	}
+// On adds a listener for the event or an event name pattern like "chat:*".
+// Listeners run in the order they were added, the first one acknowledges the
+// event. Listeners registered on the client take precedence over the ones of
+// its namespace.
+func (c *Client) On(eventName string, listener EventListener) *Subscription {
+	return c.events.add(eventName, listener.contextListener())
 }
 
+// Once adds a listener which gets removed after its first invocation.
+func (c *Client) Once(eventName string, listener EventListener) *Subscription {
+	var sub *Subscription
+	var once sync.Once
+
+	sub = c.events.add(eventName, func(ctx *EventContext, data map[string]interface{}) interface{} {
+		var result interface{}
+		once.Do(func() {
+			sub.Off()
+			result = listener(ctx.Client, data)
+		})
+		return result
+	})
+	return sub
+}
+
+// Off removes every listener of the event. Use Subscription.Off to remove a
+// single one.
 func (c *Client) Off(eventName string) {
-	delete(c.Events, eventName)
+	c.events.removeAll(eventName)
 }
 
-func (c *Client) Join(room *Room) {
-	room.clients = append(room.clients, c)
+// Join makes the client a member of the room. It fails with ErrJoinRefused if
+// the join guard of the room refused the client, and with ErrRoomFull once the
+// room is at capacity.
+func (c *Client) Join(room *Room) error {
+	if err := room.guard(c); err != nil {
+		return err
+	}
+
+	added, err := room.add(c)
+	if err != nil {
+		return err
+	}
+	if added {
+		room.replay(c)
+		room.presenceJoined(c)
+	}
 
-	if room.joinedHandler != nil {
+	if added && room.joinedHandler != nil {
 		room.joinedHandler(c)
 	}
+	return nil
 }
 
 func (c *Client) Leave(room *Room) {
-	for i, client := range room.clients {
-		if client == c {
-			room.clients = append(room.clients[:i], room.clients[i+1:]...)
-			break
-		}
+	if c.Server.adapter.RemoveFromRoom(room, c) {
+		room.removed(c)
+	}
+}
+
+// LeaveAll makes the client leave every room it is a member of.
+func (c *Client) LeaveAll() {
+	for _, room := range c.Rooms() {
+		c.Leave(room)
 	}
+}
+
+// Rooms returns the rooms the client is a member of, sorted by Id.
+func (c *Client) Rooms() []*Room {
+	c.roomsMu.RLock()
+	rooms := make([]*Room, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	c.roomsMu.RUnlock()
+
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].Id < rooms[j].Id
+	})
+	return rooms
+}
+
+func (c *Client) addRoom(room *Room) {
+	c.roomsMu.Lock()
+	defer c.roomsMu.Unlock()
 
-	if room.leftHandler != nil {
-		room.leftHandler(c)
+	if c.rooms == nil {
+		c.rooms = make(map[*Room]struct{})
 	}
+	c.rooms[room] = struct{}{}
+}
+
+func (c *Client) removeRoom(room *Room) {
+	c.roomsMu.Lock()
+	defer c.roomsMu.Unlock()
+
+	delete(c.rooms, room)
 }
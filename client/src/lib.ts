@@ -1,6 +1,20 @@
 export type EventArg = string | number | boolean | null | undefined | {[key: string]: EventArg} | EventArg[];
 export type EventData = {[key: string]: EventArg};
-export type EventHandler = (data: EventData) => void;
+export type EventHandler = (data: EventData) => EventArg | void;
+export type BinaryHandler = (data: ArrayBuffer) => void;
+
+/**
+ * Rejects emitWithAck when the server's listener acknowledged with an error. The fields are set
+ * when the payload failed its validation.
+ */
+export class AckError extends Error {
+    public readonly fields: EventArg[];
+
+    constructor(message: string, fields: EventArg[] = []) {
+        super(message);
+        this.fields = fields;
+    }
+}
 
 /**
  * The igo client is a wrapper for the default websocket client bringing compatibility with the igo server.
@@ -8,23 +22,32 @@ export type EventHandler = (data: EventData) => void;
 export class IgoClient {
 
     private readonly _handlers: {[key: string]: EventHandler[]} = {};
+    private readonly _binaryHandlers: {[key: string]: BinaryHandler[]} = {};
     private readonly _reconnectTimeout: number;
     private readonly _url: string;
+    private readonly _namespace: string;
     private _socket: WebSocket | null = null;
     private _id: string = "";
+    private _session: string = "";
+    private _resumed: boolean = false;
+    private _auth: EventData | (() => EventData) | null = null;
 
     private _preConnectedHandler: (() => void) | null = null;
     private _connectedHandler: (() => void) | null = null;
     private _disconnectedHandler: (() => void) | null = null;
+    private _errorHandler: ((error: EventData) => void) | null = null;
 
     /**
      * Constructs a new igo client and connects to the given url.
      * 
      * @param url The url to connect to.
+     * @param reconnectTimeout The time in milliseconds to wait before reconnecting.
+     * @param namespace The server namespace to connect to.
      */
-    constructor(url: string, reconnectTimeout: number = 5000) {
+    constructor(url: string, reconnectTimeout: number = 5000, namespace: string = "/") {
         this._url = url;
         this._reconnectTimeout = reconnectTimeout;
+        this._namespace = namespace;
         this.connect();
     }
 
@@ -58,12 +81,67 @@ export class IgoClient {
             const id = Math.random().toString(36).substring(2, 15) + Math.random().toString(36).substring(2, 15);
 
             this.once(event + "@ack:" + id, data => {
+                if (typeof data.error === "string") {
+                    reject(new AckError(data.error, Array.isArray(data.fields) ? data.fields : []));
+                    return;
+                }
                 resolve(data.result);
             });
             this._socket.send(JSON.stringify({event, data, ackId: id}));
         });
     }
 
+    /**
+     * Emits a new binary event to the server.
+     * 
+     * @param event The event to emit.
+     * @param data The raw bytes to send with the event.
+     */
+    public emitBinary(event: string, data: ArrayBuffer | Uint8Array) {
+        if (this._socket === null) {
+            throw new Error("Socket is not connected");
+        }
+
+        // binary frames: uint16 event name length, event name, payload
+        const name = new TextEncoder().encode(event);
+        const payload = data instanceof Uint8Array ? data : new Uint8Array(data);
+        const frame = new Uint8Array(2 + name.length + payload.length);
+        new DataView(frame.buffer).setUint16(0, name.length);
+        frame.set(name, 2);
+        frame.set(payload, 2 + name.length);
+        this._socket.send(frame);
+    }
+
+    /**
+     * Adds a binary event listener to the client.
+     * 
+     * @param event The event to listen to.
+     * @param handler The handler to call with the raw bytes when the event is received.
+     */
+    public onBinary(event: string, handler: BinaryHandler) {
+        if (this._binaryHandlers[event] === undefined) {
+            this._binaryHandlers[event] = [];
+        }
+        this._binaryHandlers[event].push(handler);
+    }
+
+    /**
+     * Removes a binary event handler from the client.
+     * 
+     * @param event The event to remove the handler from.
+     * @param handler The handler to remove.
+     */
+    public offBinary(event: string, handler: BinaryHandler) {
+        if (this._binaryHandlers[event] === undefined) {
+            return;
+        }
+
+        const index = this._binaryHandlers[event].indexOf(handler);
+        if (index !== -1) {
+            this._binaryHandlers[event].splice(index, 1);
+        }
+    }
+
     /**
      * Adds an event listener to the client.
      * 
@@ -102,8 +180,8 @@ export class IgoClient {
      */
     public once(event: string, handler: EventHandler) {
         const onceHandler = (data: EventData) => {
-            handler(data);
             this.off(event, onceHandler);
+            return handler(data);
         };
         this.on(event, onceHandler);
     }
@@ -135,6 +213,40 @@ export class IgoClient {
         this._disconnectedHandler = handler;
     }
 
+    /**
+     * Sets the auth payload sent as the first frame of every connection attempt.
+     * Required when the server has an authenticator configured.
+     * 
+     * @param auth The auth payload or a function returning it.
+     */
+    public setAuth(auth: EventData | (() => EventData) | null) {
+        this._auth = auth;
+    }
+
+    /**
+     * Gets called when the server sends an error frame, e.g. when the handshake got rejected.
+     * 
+     * @param handler The handler to call when the event is received.
+     */
+    public onError(handler: (error: EventData) => void) {
+        this._errorHandler = handler;
+    }
+
+    /**
+     * Returns the namespace the client connects to.
+     */
+    public get namespace(): string {
+        return this._namespace;
+    }
+
+    /**
+     * Returns whether the current connection resumed the previous session, so the events emitted
+     * in between got replayed.
+     */
+    public get resumed(): boolean {
+        return this._resumed;
+    }
+
     /**
      * Returns the server given client id or an empty string if the handshake was not yet completed.
      */
@@ -144,13 +256,24 @@ export class IgoClient {
 
     private connect() {
         this._id = "";
-        this._socket = new WebSocket(this._url);
+        const url = new URL(this._url);
+        url.searchParams.set("namespace", this._namespace);
+        if (this._session !== "") {
+            url.searchParams.set("session", this._session);
+        }
+        this._socket = new WebSocket(url.toString());
+        this._socket.binaryType = "arraybuffer";
         this._socket.onopen = () => this.onOpen();
         this._socket.onclose = () => this.onClose();
         this._socket.onmessage = (message) => this.onMessage(message);
     }
 
     private onOpen() {
+        if (this._auth !== null && this._socket !== null) {
+            const data = typeof this._auth === "function" ? this._auth() : this._auth;
+            this._socket.send(JSON.stringify({event: "#auth", data}));
+        }
+
         if (this._preConnectedHandler !== null) {
             this._preConnectedHandler();
         }
@@ -163,7 +286,30 @@ export class IgoClient {
         setTimeout(() => this.connect(), this._reconnectTimeout);
     }
 
+    private onBinaryMessage(frame: ArrayBuffer) {
+        if (frame.byteLength < 2) {
+            return;
+        }
+
+        const nameLength = new DataView(frame).getUint16(0);
+        const eventName = new TextDecoder().decode(new Uint8Array(frame, 2, nameLength));
+        const handlers = this._binaryHandlers[eventName];
+        if (handlers === undefined) {
+            return;
+        }
+
+        const data = frame.slice(2 + nameLength);
+        for (const handler of [...handlers]) {
+            handler(data);
+        }
+    }
+
     private onMessage(message: MessageEvent) {
+        if (message.data instanceof ArrayBuffer) {
+            this.onBinaryMessage(message.data);
+            return;
+        }
+
         const event = JSON.parse(message.data);
         const eventName = event.event;
         const eventData: EventData = event.data;
@@ -175,18 +321,39 @@ export class IgoClient {
             }
 
             this._id = eventData.clientId as string;
+            this._session = (eventData.session as string | undefined) ?? "";
+            this._resumed = eventData.resumed === true;
             if (this._connectedHandler !== null) {
                 this._connectedHandler();
             }
             return;
         }
 
+        if (eventName === "#error") {
+            if (this._errorHandler !== null) {
+                this._errorHandler(eventData);
+            }
+            return;
+        }
+
         if (this._handlers[eventName] === undefined) {
             return;
         }
 
-        for (const handler of this._handlers[eventName]) {
-            handler(eventData);
+        let result: EventArg | void = undefined;
+        for (const handler of [...this._handlers[eventName]]) {
+            const value = handler(eventData);
+            if (result === undefined) {
+                result = value;
+            }
+        }
+
+        // the server awaits an acknowledgement carrying the first handler's result
+        if (event.ackId !== undefined && this._socket !== null) {
+            this._socket.send(JSON.stringify({
+                event: eventName + "@ack:" + event.ackId,
+                data: {result: result === undefined ? null : result},
+            }));
         }
     }
 }
\ No newline at end of file
@@ -0,0 +1,153 @@
+package socketigo
+
+import (
+	uuid "github.com/google/uuid"
+)
+
+// BroadcastPacket describes a broadcast, so it can be replayed by the other
+// server instances of a cluster against their own clients.
+type BroadcastPacket struct {
+	Node         string      `json:"node"`
+	Namespace    string      `json:"nsp"`
+	Rooms        []string    `json:"rooms,omitempty"`
+	RoomPatterns []string    `json:"roomPatterns,omitempty"`
+	ExceptRooms  []string    `json:"exceptRooms,omitempty"`
+	Topic        string      `json:"topic,omitempty"`
+	RoomSeq      uint64      `json:"roomSeq,omitempty"`
+	Volatile     bool        `json:"volatile,omitempty"`
+	Args         bool        `json:"args,omitempty"`
+	Except       []uuid.UUID `json:"except,omitempty"`
+	Event        string      `json:"event"`
+	Data         interface{} `json:"data,omitempty"`
+	Binary       []byte      `json:"binary,omitempty"`
+	IsBinary     bool        `json:"isBinary,omitempty"`
+	Attachments  [][]byte    `json:"attachments,omitempty"`
+	// Join and Leave change the room memberships of the targeted clients
+	// instead of emitting to them.
+	Join  []string `json:"join,omitempty"`
+	Leave []string `json:"leave,omitempty"`
+}
+
+// Broker relays broadcast packets between server instances, e.g. over Redis
+// pub/sub. Packets published by a server are handed back to it by most
+// brokers; the server drops them by their node id.
+type Broker interface {
+	Publish(packet *BroadcastPacket) error
+	Subscribe(handler func(packet *BroadcastPacket)) error
+	Close() error
+}
+
+// UseBroker makes every broadcast of the server reach the clients connected to
+// the other instances sharing the broker.
+func (s *IgoServer) UseBroker(broker Broker) error {
+	return s.SetAdapter(&brokerAdapter{
+		MemoryAdapter: NewMemoryAdapter(),
+		broker:        broker,
+	})
+}
+
+// NodeId identifies the server instance within a cluster.
+func (s *IgoServer) NodeId() string {
+	return s.nodeId
+}
+
+// emitPacket hands a broadcast to the adapter, which delivers it locally and
+// publishes it to the other instances.
+func (n *Namespace) emitPacket(packet *BroadcastPacket) {
+	packet.Node = n.server.nodeId
+	packet.Namespace = n.Name
+
+	adapter := n.server.adapter
+	adapter.Broadcast(packet)
+
+	if err := adapter.Publish(packet); err != nil {
+		n.server.emitError(nil, nil, nil, err)
+	}
+}
+
+// deliver emits a packet to the matching local clients.
+func (n *Namespace) deliver(packet *BroadcastPacket) {
+	if packet.Topic != "" {
+		n.deliverTopic(packet)
+		return
+	}
+
+	op := n.broadcast().To(packet.Rooms...).ToPattern(packet.RoomPatterns...).ExceptRooms(packet.ExceptRooms...)
+
+	excluded := make(map[uuid.UUID]struct{}, len(packet.Except))
+	for _, id := range packet.Except {
+		excluded[id] = struct{}{}
+	}
+
+	matching := op.Clients()
+	clients := matching[:0]
+	for _, client := range matching {
+		if _, ok := excluded[client.Id]; !ok {
+			clients = append(clients, client)
+		}
+	}
+
+	if len(packet.Join) > 0 || len(packet.Leave) > 0 {
+		n.changeMemberships(clients, packet.Join, packet.Leave)
+		return
+	}
+
+	if packet.IsBinary {
+		for _, client := range clients {
+			if packet.Volatile && client.congested() {
+				continue
+			}
+			client.EmitBinary(packet.Event, packet.Binary)
+		}
+		return
+	}
+
+	frame := map[string]interface{}{
+		"event": packet.Event,
+		"data":  packet.Data,
+	}
+	if packet.Args {
+		frame["args"] = true
+	}
+	if packet.RoomSeq != 0 {
+		frame["room"] = packet.Rooms[0]
+		frame["roomSeq"] = packet.RoomSeq
+	}
+	var attachments [][]byte
+	if len(packet.Attachments) > 0 {
+		attachments = n.server.attach(frame, packet.Attachments)
+	}
+	n.server.emitToClients(clients, frame, attachments, packet.Volatile)
+}
+
+func (n *Namespace) changeMemberships(clients []*Client, join []string, leave []string) {
+	for _, name := range join {
+		room := n.CreateRoom(name)
+		for _, client := range clients {
+			if err := client.Join(room); err != nil {
+				n.server.logger.Debug("remote join refused", client.logFields("room", name, "error", err)...)
+			}
+		}
+	}
+	for _, name := range leave {
+		if room := n.GetRoom(name); room != nil {
+			for _, client := range clients {
+				client.Leave(room)
+			}
+		}
+	}
+}
+
+func (b *BroadcastOperator) packet() *BroadcastPacket {
+	packet := &BroadcastPacket{
+		Rooms:        b.rooms,
+		RoomPatterns: b.roomPatterns,
+		ExceptRooms:  b.exceptRooms,
+		Volatile:     b.volatile,
+	}
+
+	for _, client := range b.except {
+		packet.Except = append(packet.Except, client.Id)
+	}
+	return packet
+}
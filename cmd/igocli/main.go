@@ -0,0 +1,197 @@
+// Command igocli connects to a socket.igo server, prints every event it
+// receives and emits the events typed on stdin.
+//
+//	igocli [flags] ws://localhost:8080/ws
+//
+// Commands read from stdin:
+//
+//	emit <event> [json]            emit an event
+//	ack <event> [json]             emit an event and wait for its ack
+//	binary <event> <text>          emit a binary event carrying the text
+//	quit                           disconnect and exit
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/nauri-io/socket.igo/igoclient"
+	"github.com/nauri-io/socket.igo/msgpack"
+)
+
+type headerFlag http.Header
+
+func (h headerFlag) String() string {
+	return fmt.Sprint(http.Header(h))
+}
+
+func (h headerFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("header %q is not of the form \"Key: value\"", value)
+	}
+	http.Header(h).Add(strings.TrimSpace(key), strings.TrimSpace(val))
+	return nil
+}
+
+type printer struct {
+	mu      sync.Mutex
+	compact bool
+}
+
+func (p *printer) print(prefix string, eventName string, data interface{}) {
+	var out []byte
+	var err error
+	if p.compact {
+		out, err = json.Marshal(data)
+	} else {
+		out, err = json.MarshalIndent(data, "", "  ")
+	}
+	if err != nil {
+		out = []byte(fmt.Sprint(data))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Printf("%s %s %s %s\n", time.Now().Format("15:04:05.000"), prefix, eventName, out)
+}
+
+func (p *printer) info(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Printf("%s -- %s\n", time.Now().Format("15:04:05.000"), fmt.Sprintf(format, args...))
+}
+
+func main() {
+	header := headerFlag{}
+	namespace := flag.String("namespace", "/", "namespace to connect to")
+	auth := flag.String("auth", "", "JSON object sent as auth frame")
+	codec := flag.String("codec", "json", "codec of the server, json or msgpack")
+	ackTimeout := flag.Duration("ack-timeout", 5*time.Second, "time to wait for acks")
+	compact := flag.Bool("compact", false, "print payloads on a single line")
+	noReconnect := flag.Bool("no-reconnect", false, "exit instead of reconnecting")
+	flag.Var(header, "header", "header of the upgrade request as \"Key: value\", repeatable")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: igocli [flags] <url>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	options := &igoclient.Options{
+		Namespace:        *namespace,
+		Header:           http.Header(header),
+		DisableReconnect: *noReconnect,
+	}
+	if *auth != "" {
+		if err := json.Unmarshal([]byte(*auth), &options.Auth); err != nil {
+			fatal("invalid auth: %v", err)
+		}
+	}
+	switch *codec {
+	case "json":
+	case "msgpack":
+		options.Codec = msgpack.Codec{}
+	default:
+		fatal("unknown codec %q", *codec)
+	}
+
+	out := &printer{compact: *compact}
+	client := igoclient.NewClient(flag.Arg(0), options)
+	client.OnAny(func(client *igoclient.Client, eventName string, data map[string]interface{}) {
+		out.print("<-", eventName, data)
+	})
+	client.OnConnected(func(client *igoclient.Client) {
+		out.info("connected as %s (resumed: %t)", client.Id(), client.Resumed())
+	})
+	client.OnDisconnected(func(client *igoclient.Client, err error) {
+		out.info("disconnected: %v", err)
+		if *noReconnect {
+			os.Exit(1)
+		}
+	})
+	client.OnError(func(client *igoclient.Client, err error) {
+		out.info("error: %v", err)
+	})
+
+	if err := client.Connect(); err != nil {
+		fatal("connecting failed: %v", err)
+	}
+	defer client.Close()
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line == "quit" || line == "exit" {
+			return
+		}
+		if err := run(client, out, line, *ackTimeout); err != nil {
+			out.info("%v", err)
+		}
+	}
+}
+
+func run(client *igoclient.Client, out *printer, line string, ackTimeout time.Duration) error {
+	command, rest, _ := strings.Cut(line, " ")
+	if command != "emit" && command != "ack" && command != "binary" {
+		return fmt.Errorf("unknown command %q, use emit, ack, binary or quit", command)
+	}
+
+	eventName, payload, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	if eventName == "" {
+		return fmt.Errorf("usage: %s <event> [payload]", command)
+	}
+
+	switch command {
+	case "emit", "ack":
+		var data interface{} = map[string]interface{}{}
+		if payload = strings.TrimSpace(payload); payload != "" {
+			if err := json.Unmarshal([]byte(payload), &data); err != nil {
+				return fmt.Errorf("invalid payload: %v", err)
+			}
+		}
+
+		if command == "emit" {
+			if err := client.Emit(eventName, data); err != nil {
+				return err
+			}
+			out.print("->", eventName, data)
+			return nil
+		}
+
+		out.print("->", eventName, data)
+		result, err := client.EmitWithAck(eventName, data, ackTimeout)
+		if err != nil {
+			return fmt.Errorf("ack of %s: %v", eventName, err)
+		}
+		out.print("<- ack", eventName, result)
+		return nil
+
+	default:
+		if err := client.EmitBinary(eventName, []byte(payload)); err != nil {
+			return err
+		}
+		out.info("-> binary %s (%d bytes)", eventName, len(payload))
+		return nil
+	}
+}
+
+func fatal(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "igocli: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,254 @@
+// Command igoload load tests a socket.igo server. It opens the given number of
+// connections, emits events at a fixed rate on each of them and reports the
+// ack latency percentiles and errors once done.
+//
+//	igoload -clients 1000 -rate 2 -size 512 -duration 1m ws://localhost:8080/ws
+//
+// The server has to acknowledge the event, e.g. by returning a result from its
+// listener, unless -ack=false is given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nauri-io/socket.igo/igoclient"
+	"github.com/nauri-io/socket.igo/msgpack"
+)
+
+type stats struct {
+	connected     int64
+	connectErrors int64
+	disconnects   int64
+	sent          int64
+	acked         int64
+	emitErrors    int64
+	received      int64
+	mu            sync.Mutex
+	latencies     []time.Duration
+	errors        map[string]int
+}
+
+func (s *stats) addLatency(latency time.Duration) {
+	s.mu.Lock()
+	s.latencies = append(s.latencies, latency)
+	s.mu.Unlock()
+}
+
+func (s *stats) addError(err error) {
+	s.mu.Lock()
+	s.errors[err.Error()]++
+	s.mu.Unlock()
+}
+
+type config struct {
+	url        string
+	options    igoclient.Options
+	event      string
+	rate       float64
+	payload    string
+	ack        bool
+	ackTimeout time.Duration
+}
+
+func main() {
+	clients := flag.Int("clients", 100, "number of concurrent connections")
+	rate := flag.Float64("rate", 1, "events per second emitted on every connection")
+	size := flag.Int("size", 128, "payload size in bytes")
+	duration := flag.Duration("duration", 30*time.Second, "duration of the test once connected")
+	ramp := flag.Duration("ramp", 5*time.Second, "time the connections are opened over")
+	event := flag.String("event", "load", "event to emit")
+	namespace := flag.String("namespace", "/", "namespace to connect to")
+	codec := flag.String("codec", "json", "codec of the server, json or msgpack")
+	ack := flag.Bool("ack", true, "wait for acks and measure their latency")
+	ackTimeout := flag.Duration("ack-timeout", 5*time.Second, "time to wait for acks")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: igoload [flags] <url>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || *clients <= 0 || *rate <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := &config{
+		url: flag.Arg(0),
+		options: igoclient.Options{
+			Namespace:        *namespace,
+			DisableReconnect: true,
+		},
+		event:      *event,
+		rate:       *rate,
+		payload:    strings.Repeat("x", *size),
+		ack:        *ack,
+		ackTimeout: *ackTimeout,
+	}
+	switch *codec {
+	case "json":
+	case "msgpack":
+		cfg.options.Codec = msgpack.Codec{}
+	default:
+		fmt.Fprintf(os.Stderr, "igoload: unknown codec %q\n", *codec)
+		os.Exit(2)
+	}
+
+	s := &stats{errors: make(map[string]int)}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	fmt.Printf("opening %d connections over %s\n", *clients, *ramp)
+	for i := 0; i < *clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runClient(cfg, s, stop)
+		}()
+
+		if *clients > 1 {
+			time.Sleep(*ramp / time.Duration(*clients))
+		}
+	}
+
+	fmt.Printf("%d connected, running for %s\n", atomic.LoadInt64(&s.connected), *duration)
+	start := time.Now()
+	progress := time.NewTicker(5 * time.Second)
+	deadline := time.After(*duration)
+
+loop:
+	for {
+		select {
+		case <-progress.C:
+			fmt.Printf("%6s  connected %d  sent %d  acked %d  errors %d\n",
+				time.Since(start).Round(time.Second),
+				atomic.LoadInt64(&s.connected),
+				atomic.LoadInt64(&s.sent),
+				atomic.LoadInt64(&s.acked),
+				atomic.LoadInt64(&s.emitErrors))
+		case <-deadline:
+			break loop
+		}
+	}
+	progress.Stop()
+	close(stop)
+	wg.Wait()
+
+	report(s, time.Since(start))
+}
+
+func runClient(cfg *config, s *stats, stop chan struct{}) {
+	options := cfg.options
+	client := igoclient.NewClient(cfg.url, &options)
+	client.OnAny(func(client *igoclient.Client, eventName string, data map[string]interface{}) {
+		atomic.AddInt64(&s.received, 1)
+	})
+	client.OnDisconnected(func(client *igoclient.Client, err error) {
+		select {
+		case <-stop:
+			// closed by the harness itself
+			return
+		default:
+		}
+		atomic.AddInt64(&s.disconnects, 1)
+		atomic.AddInt64(&s.connected, -1)
+	})
+
+	if err := client.Connect(); err != nil {
+		atomic.AddInt64(&s.connectErrors, 1)
+		s.addError(err)
+		return
+	}
+	atomic.AddInt64(&s.connected, 1)
+	defer client.Close()
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / cfg.rate))
+	defer ticker.Stop()
+
+	var pending sync.WaitGroup
+	defer pending.Wait()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		data := map[string]interface{}{"payload": cfg.payload}
+		atomic.AddInt64(&s.sent, 1)
+
+		if !cfg.ack {
+			if err := client.Emit(cfg.event, data); err != nil {
+				atomic.AddInt64(&s.emitErrors, 1)
+				s.addError(err)
+			}
+			continue
+		}
+
+		pending.Add(1)
+		go func() {
+			defer pending.Done()
+
+			sent := time.Now()
+			if _, err := client.EmitWithAck(cfg.event, data, cfg.ackTimeout); err != nil {
+				atomic.AddInt64(&s.emitErrors, 1)
+				s.addError(err)
+				return
+			}
+			atomic.AddInt64(&s.acked, 1)
+			s.addLatency(time.Since(sent))
+		}()
+	}
+}
+
+func report(s *stats, elapsed time.Duration) {
+	fmt.Println()
+	fmt.Printf("duration        %s\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("connect errors  %d\n", atomic.LoadInt64(&s.connectErrors))
+	fmt.Printf("disconnects     %d\n", atomic.LoadInt64(&s.disconnects))
+	sent := atomic.LoadInt64(&s.sent)
+	fmt.Printf("sent            %d (%.1f/s)\n", sent, float64(sent)/elapsed.Seconds())
+	fmt.Printf("acked           %d\n", atomic.LoadInt64(&s.acked))
+	fmt.Printf("received        %d\n", atomic.LoadInt64(&s.received))
+	fmt.Printf("emit errors     %d\n", atomic.LoadInt64(&s.emitErrors))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.latencies) > 0 {
+		sort.Slice(s.latencies, func(i, j int) bool {
+			return s.latencies[i] < s.latencies[j]
+		})
+		fmt.Println("ack latency")
+		for _, p := range []float64{50, 90, 95, 99} {
+			fmt.Printf("  p%-4g         %s\n", p, percentile(s.latencies, p))
+		}
+		fmt.Printf("  max           %s\n", s.latencies[len(s.latencies)-1])
+	}
+
+	if len(s.errors) > 0 {
+		fmt.Println("errors")
+		for message, count := range s.errors {
+			fmt.Printf("  %6d  %s\n", count, message)
+		}
+	}
+}
+
+// percentile expects the latencies to be sorted.
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	i := int(float64(len(latencies))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(latencies) {
+		i = len(latencies) - 1
+	}
+	return latencies[i]
+}
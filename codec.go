@@ -0,0 +1,256 @@
+package socketigo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/goccy/go-json"
+	ws "github.com/gorilla/websocket"
+)
+
+const malformedFrameCode = "malformed_frame"
+
+// ErrMalformedFrame is reported for frames not following the protocol, e.g.
+// lacking an event name. The client gets an error frame for each of them.
+var ErrMalformedFrame = errors.New("socketigo: malformed frame")
+
+// Codec encodes and decodes the frames exchanged with clients. Frames are maps
+// holding the "event", "data" and optional "ackId" keys.
+//
+// Codecs sending binary websocket messages carry binary events inside regular
+// frames, flagged with "binary": true and the raw bytes as data.
+type Codec interface {
+	// MessageType returns the websocket message type frames are sent as,
+	// either websocket.TextMessage or websocket.BinaryMessage.
+	MessageType() int
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal must not retain data, its buffer is reused for the next
+	// message.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) MessageType() int {
+	return ws.TextMessage
+}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func writeFrame(conn Conn, codec Codec, frame map[string]interface{}) error {
+	data, err := codec.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(codec.MessageType(), data)
+}
+
+// Frame is a frame received from a client. Its data stays encoded until the
+// listeners of the event are known, so typed listeners decode it straight into
+// their payload type.
+type Frame struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	AckID string          `json:"ackId,omitempty"`
+	// CallID is set for calls, which get answered with a "#reply" instead of
+	// an ack.
+	CallID string `json:"callId,omitempty"`
+	// Trace carries the trace headers the event was sent with.
+	Trace map[string]string `json:"trace,omitempty"`
+	// Binary flags frames of binary codecs whose data are raw bytes.
+	Binary bool `json:"binary,omitempty"`
+	// Args flags multi-argument events, whose data is the array of arguments.
+	Args bool `json:"args,omitempty"`
+	// Attachments is the number of binary messages following the frame, see
+	// EmitAttachments.
+	Attachments int `json:"attachments,omitempty"`
+	attachments [][]byte
+	// size is the number of bytes received for the frame, attachments
+	// included
+	size int
+	// dropped is set once the attachments got too large, the rest of them is
+	// discarded
+	dropped bool
+	// value is the data as decoded by codecs other than JSONCodec, which
+	// cannot leave it encoded.
+	value interface{}
+}
+
+func decodeFrame(codec Codec, data []byte) (*Frame, error) {
+	frame := acquireFrame()
+	if _, isJSON := codec.(JSONCodec); isJSON {
+		if err := json.Unmarshal(data, frame); err != nil {
+			releaseFrame(frame)
+			return nil, err
+		}
+		return frame, nil
+	}
+
+	fields := make(map[string]interface{})
+	if err := codec.Unmarshal(data, &fields); err != nil {
+		releaseFrame(frame)
+		return nil, err
+	}
+
+	var ok bool
+	if frame.Event, ok = fields["event"].(string); !ok && fields["event"] != nil {
+		releaseFrame(frame)
+		return nil, errors.New("event is not a string")
+	}
+	if frame.AckID, ok = fields["ackId"].(string); !ok && fields["ackId"] != nil {
+		releaseFrame(frame)
+		return nil, errors.New("ackId is not a string")
+	}
+	if frame.CallID, ok = fields["callId"].(string); !ok && fields["callId"] != nil {
+		releaseFrame(frame)
+		return nil, errors.New("callId is not a string")
+	}
+	frame.Trace = traceHeaders(fields["trace"])
+	frame.Binary, _ = fields["binary"].(bool)
+	frame.Args, _ = fields["args"].(bool)
+	if frame.attachments, ok = inlineAttachments(fields["attachments"]); !ok {
+		releaseFrame(frame)
+		return nil, errors.New("attachments are not binary")
+	}
+	frame.Attachments = len(frame.attachments)
+	frame.value = fields["data"]
+	return frame, nil
+}
+
+// validate checks the frame against the protocol: every frame names its event.
+// Its data may be any value, only listeners taking a map require an object.
+func (f *Frame) validate() error {
+	if f.Event == "" {
+		return errors.New("missing event")
+	}
+	if f.Attachments < 0 || f.Attachments > maxAttachments {
+		return fmt.Errorf("%d attachments, at most %d are allowed", f.Attachments, maxAttachments)
+	}
+	return nil
+}
+
+// rejectFrame reports a malformed frame and answers it with an error frame, as
+// well as an error ack if the client waits for one. The frame is nil if it did
+// not decode at all.
+func (c *Client) rejectFrame(frame *Frame, err error) {
+	if !errors.Is(err, ErrMalformedFrame) {
+		err = fmt.Errorf("%w: %w", ErrMalformedFrame, err)
+	}
+	c.Server.emitError(c, nil, nil, err)
+
+	errorData := map[string]interface{}{
+		"code":    malformedFrameCode,
+		"message": err.Error(),
+	}
+	if frame != nil && frame.Event != "" {
+		errorData["event"] = frame.Event
+	}
+	c.Emit(errorEvent, errorData)
+
+	c.acknowledge(frame, err)
+}
+
+// payload decodes the data of the frame into the map listeners get. Frames
+// without data get an empty one, data other than an object fails to decode.
+func (f *Frame) payload() (map[string]interface{}, error) {
+	var payload map[string]interface{}
+	if f.value != nil {
+		var ok bool
+		if payload, ok = f.value.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("%w: data of event %q is not an object", ErrMalformedFrame, f.Event)
+		}
+	} else if len(f.Data) > 0 {
+		if f.Data[0] != '{' && string(f.Data) != "null" {
+			return nil, fmt.Errorf("%w: data of event %q is not an object", ErrMalformedFrame, f.Event)
+		}
+		if err := json.Unmarshal(f.Data, &payload); err != nil {
+			return nil, fmt.Errorf("%w: data of event %q: %w", ErrDecodeFailed, f.Event, err)
+		}
+	}
+
+	if payload == nil {
+		payload = make(map[string]interface{})
+	}
+	if len(f.attachments) > 0 {
+		if _, err := resolvePlaceholders(payload, f.attachments); err != nil {
+			return nil, fmt.Errorf("%w: event %q: %w", ErrMalformedFrame, f.Event, err)
+		}
+	}
+	return payload, nil
+}
+
+// decode decodes the data of the frame into out, without the detour through
+// the map listeners get when the frame was sent as JSON.
+func (f *Frame) decode(out interface{}) error {
+	if len(f.attachments) == 0 {
+		return f.decodeData(out)
+	}
+
+	// placeholders are resolved on the generic value, which then gets
+	// converted
+	var value interface{}
+	if err := f.decodeData(&value); err != nil {
+		return err
+	}
+	value, err := resolvePlaceholders(value, f.attachments)
+	if err != nil {
+		return fmt.Errorf("%w: event %q: %w", ErrMalformedFrame, f.Event, err)
+	}
+	return decodePayload(value, out)
+}
+
+func (f *Frame) decodeData(out interface{}) error {
+	if f.value != nil {
+		return decodePayload(f.value, out)
+	}
+	if len(f.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(f.Data, out); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodeFailed, err)
+	}
+	return nil
+}
+
+// any decodes the data of the frame into the types encoding/json produces,
+// frames without data carrying an empty object.
+func (f *Frame) any() (interface{}, error) {
+	if f.value == nil && len(f.Data) == 0 {
+		return make(map[string]interface{}), nil
+	}
+
+	var value interface{}
+	err := f.decodeData(&value)
+	return value, err
+}
+
+// raw returns the data of the frame as JSON, encoding it for codecs other than
+// JSONCodec.
+func (f *Frame) raw() (json.RawMessage, error) {
+	if f.value == nil {
+		return f.Data, nil
+	}
+
+	data, err := json.Marshal(f.value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecodeFailed, err)
+	}
+	return data, nil
+}
+
+// binaryPayload returns the raw bytes of a frame flagged as binary.
+func (f *Frame) binaryPayload() []byte {
+	if payload, ok := f.value.([]byte); ok {
+		return payload
+	}
+
+	var payload []byte
+	f.decode(&payload)
+	return payload
+}
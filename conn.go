@@ -0,0 +1,96 @@
+package socketigo
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+const transportParam = "transport"
+
+// The message types of a Conn, the opcodes of websocket.
+const (
+	TextMessage   = ws.TextMessage
+	BinaryMessage = ws.BinaryMessage
+	CloseMessage  = ws.CloseMessage
+	PingMessage   = ws.PingMessage
+	PongMessage   = ws.PongMessage
+)
+
+// ErrReadLimit is returned by a Conn reading a message larger than its read
+// limit.
+var ErrReadLimit = ws.ErrReadLimit
+
+// Conn is what a client talks to its peer over. *websocket.Conn satisfies it,
+// other transports emulate its messages on top of their own protocol.
+type Conn interface {
+	NextReader() (messageType int, r io.Reader, err error)
+	WriteMessage(messageType int, data []byte) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	SetPongHandler(h func(appData string) error)
+	EnableWriteCompression(enable bool)
+	Subprotocol() string
+	Close() error
+}
+
+// Transport upgrades a request to a connection for clients which do not talk
+// websocket. Clients pick a registered transport by its name with the
+// "transport" query parameter, everything past the upgrade works the same on
+// every transport.
+type Transport interface {
+	Upgrade(w http.ResponseWriter, r *http.Request) (Conn, error)
+}
+
+// RegisterTransport makes the transport available under the name. "sse" is
+// registered by default.
+func (s *IgoServer) RegisterTransport(name string, transport Transport) {
+	s.transportsMu.Lock()
+	defer s.transportsMu.Unlock()
+
+	s.transports[name] = transport
+}
+
+// getTransport returns the transport the request asks for, the websocket
+// backend if it names none.
+func (s *IgoServer) getTransport(r *http.Request) (Transport, bool) {
+	name := r.URL.Query().Get(transportParam)
+	if name == "" || name == "websocket" {
+		return s.websocket, true
+	}
+
+	s.transportsMu.RLock()
+	defer s.transportsMu.RUnlock()
+
+	transport, ok := s.transports[name]
+	return transport, ok
+}
+
+// readLimiter is implemented by the connections able to fail a read of a
+// message larger than the limit with ErrReadLimit.
+type readLimiter interface {
+	SetReadLimit(limit int64)
+}
+
+// limitReads makes the connection fail messages larger than MaxMessageSize,
+// if oversized messages disconnect.
+func (s *IgoServer) limitReads(conn Conn) {
+	if limiter, ok := conn.(readLimiter); ok && s.maxMessageSize > 0 && s.disconnectOversized {
+		limiter.SetReadLimit(s.maxMessageSize)
+	}
+}
+
+// preparedWriter is implemented by the connections able to write a broadcast
+// compressed once for every client.
+type preparedWriter interface {
+	WritePreparedMessage(pm *ws.PreparedMessage) error
+}
+
+// NewCloseError returns the error a Conn fails reads with once the peer
+// closed the connection with the code and text.
+func NewCloseError(code int, text string) error {
+	return &ws.CloseError{Code: code, Text: text}
+}
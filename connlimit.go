@@ -0,0 +1,129 @@
+package socketigo
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTooManyConnections = errors.New("socketigo: too many connections")
+	ErrServerFull         = errors.New("socketigo: server full")
+)
+
+// RemoteIP is the default key connections are limited by, the host part of the
+// remote address of the request.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// connectionCounter counts the open connections per key.
+type connectionCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newConnectionCounter() *connectionCounter {
+	return &connectionCounter{
+		counts: make(map[string]int),
+	}
+}
+
+func (c *connectionCounter) acquire(key string, limit int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts[key] >= limit {
+		return false
+	}
+	c.counts[key]++
+	return true
+}
+
+func (c *connectionCounter) release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts[key] <= 1 {
+		delete(c.counts, key)
+		return
+	}
+	c.counts[key]--
+}
+
+// OnServerFull gets called whenever an upgrade gets rejected because
+// MaxClients connections are open.
+func (s *IgoServer) OnServerFull(listener func(r *http.Request)) {
+	s.serverFullHandler = listener
+}
+
+// acquireConnection takes a connection slot for the request, answering with
+// 429 once MaxConnectionsPerIP and with 503 once MaxClients is reached. The
+// returned release frees the slot again.
+func (s *IgoServer) acquireConnection(w http.ResponseWriter, r *http.Request) (func(), bool) {
+	release := func() {}
+
+	if s.maxConnectionsPerIP > 0 {
+		key := s.connectionKey(r)
+		if !s.connections.acquire(key, s.maxConnectionsPerIP) {
+			s.emitError(nil, r, ErrTooManyConnections, nil)
+			http.Error(w, "too many connections", http.StatusTooManyRequests)
+			return nil, false
+		}
+		release = func() {
+			s.connections.release(key)
+		}
+	}
+
+	if s.clientSlots != nil {
+		if !s.acquireSlot(r) {
+			release()
+
+			if s.serverFullHandler != nil {
+				s.serverFullHandler(r)
+			}
+			s.emitError(nil, r, ErrServerFull, nil)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "server full", http.StatusServiceUnavailable)
+			return nil, false
+		}
+
+		releaseIP := release
+		release = func() {
+			<-s.clientSlots
+			releaseIP()
+		}
+	}
+	return release, true
+}
+
+// acquireSlot waits for at most MaxClientsWait for one of the MaxClients slots.
+func (s *IgoServer) acquireSlot(r *http.Request) bool {
+	select {
+	case s.clientSlots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if s.maxClientsWait <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(s.maxClientsWait)
+	defer timer.Stop()
+
+	select {
+	case s.clientSlots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,100 @@
+package socketigo_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+// connectOnceFreed connects a client as soon as the server freed a slot for
+// it, failing the test if it does not within a second.
+func connectOnceFreed(t *testing.T, server *socketigo.IgoServer, options *testclient.Options) *testclient.Client {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		client, err := testclient.Connect(server, options)
+		if err == nil {
+			t.Cleanup(func() {
+				client.Close()
+			})
+			return client
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("client did not get a freed slot: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMaxConnectionsPerIP(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{MaxConnectionsPerIP: 2})
+
+	first := testclient.MustConnect(t, server, nil)
+	testclient.MustConnect(t, server, nil)
+	if _, err := testclient.Connect(server, nil); err == nil {
+		t.Fatal("third connection of the address got accepted")
+	}
+
+	// closing a connection frees its slot
+	first.Close()
+	connectOnceFreed(t, server, nil)
+}
+
+func TestConnectionKey(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{
+		MaxConnectionsPerIP: 1,
+		ConnectionKey: func(r *http.Request) string {
+			return r.Header.Get("X-Tenant")
+		},
+	})
+	tenant := func(name string) *testclient.Options {
+		return &testclient.Options{Header: http.Header{"X-Tenant": {name}}}
+	}
+
+	testclient.MustConnect(t, server, tenant("a"))
+	testclient.MustConnect(t, server, tenant("b"))
+	if _, err := testclient.Connect(server, tenant("a")); err == nil {
+		t.Fatal("second connection of the key got accepted")
+	}
+}
+
+func TestMaxClients(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{MaxClients: 1})
+	full := make(chan struct{}, 1)
+	server.OnServerFull(func(r *http.Request) {
+		full <- struct{}{}
+	})
+
+	first := testclient.MustConnect(t, server, nil)
+	if _, err := testclient.Connect(server, nil); err == nil {
+		t.Fatal("connection above MaxClients got accepted")
+	}
+	select {
+	case <-full:
+	default:
+		t.Fatal("server full handler did not get called")
+	}
+
+	first.Close()
+	connectOnceFreed(t, server, nil)
+}
+
+func TestMaxClientsWait(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{MaxClients: 1, MaxClientsWait: time.Second})
+
+	first := testclient.MustConnect(t, server, nil)
+	time.AfterFunc(50*time.Millisecond, func() {
+		first.Close()
+	})
+
+	// the upgrade waits for the slot the first client frees
+	start := time.Now()
+	testclient.MustConnect(t, server, nil)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("connection got accepted after %v, before a slot got freed", elapsed)
+	}
+}
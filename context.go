@@ -0,0 +1,79 @@
+package socketigo
+
+import (
+	"context"
+	"time"
+)
+
+// EventContext is handed to context listeners for every incoming event. It is
+// derived from the client's context, so it is canceled once the client
+// disconnects, the handler timeout elapsed or the listeners of the event
+// returned.
+type EventContext struct {
+	context.Context
+	Client *Client
+	Event  string
+	// AckId is set when the client waits for an acknowledgement.
+	AckId string
+	// Trace carries the trace headers the client sent along with the event,
+	// e.g. a W3C traceparent.
+	Trace map[string]string
+	// frame is a copy, the frame itself gets reused once the listeners returned
+	// while they may go on running past their timeout.
+	frame Frame
+}
+
+type ContextListener func(ctx *EventContext, data map[string]interface{}) interface{}
+
+func (l EventListener) contextListener() ContextListener {
+	return func(ctx *EventContext, data map[string]interface{}) interface{} {
+		return l(ctx.Client, data)
+	}
+}
+
+// Context returns the context of the client, which is canceled once it
+// disconnects. It is derived from the context of the upgrade request.
+func (c *Client) Context() context.Context {
+	return c.ctx
+}
+
+// OnContext adds a listener receiving the context of the event. It is invoked
+// in order with the ones added by On.
+func (c *Client) OnContext(eventName string, listener ContextListener) *Subscription {
+	return c.events.add(eventName, listener)
+}
+
+func (n *Namespace) OnContext(eventName string, listener ContextListener) *Subscription {
+	return n.events.add(eventName, listener)
+}
+
+func newEventContext(parent context.Context, client *Client, frame *Frame, timeout time.Duration) (*EventContext, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, timeout)
+	}
+
+	return &EventContext{
+		Context: ctx,
+		Client:  client,
+		Event:   frame.Event,
+		AckId:   frame.AckID,
+		Trace:   frame.Trace,
+		frame:   *frame,
+	}, cancel
+}
+
+func traceHeaders(value interface{}) map[string]string {
+	trace, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	headers := make(map[string]string, len(trace))
+	for key, value := range trace {
+		if value, ok := value.(string); ok {
+			headers[key] = value
+		}
+	}
+	return headers
+}
@@ -0,0 +1,170 @@
+package socketigo
+
+import (
+	"context"
+	"sync"
+)
+
+const defaultWorkerQueueSize = 1024
+
+// DispatchOrder decides whether the events of a client may run concurrently
+// once listeners run on workers.
+type DispatchOrder int
+
+const (
+	// OrderPerClient runs the events of a client one after another, in the order
+	// they arrived. Events of different clients still run in parallel.
+	OrderPerClient DispatchOrder = iota
+	// OrderParallel runs every event as soon as a worker is free.
+	OrderParallel
+)
+
+func (o DispatchOrder) String() string {
+	switch o {
+	case OrderPerClient:
+		return "per client"
+	case OrderParallel:
+		return "parallel"
+	default:
+		return "unknown"
+	}
+}
+
+// workerPool runs the listeners of incoming events on a fixed number of
+// goroutines instead of each client's reader.
+type workerPool struct {
+	jobs    chan func()
+	mu      sync.RWMutex
+	stopped bool
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newWorkerPool(workers int, queueSize int) *workerPool {
+	pool := &workerPool{
+		jobs: make(chan func(), queueSize),
+		quit: make(chan struct{}),
+	}
+
+	pool.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go pool.work()
+	}
+	return pool
+}
+
+func (p *workerPool) work() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case job := <-p.jobs:
+			job()
+		case <-p.quit:
+			// nothing gets queued anymore, so what is left can be run
+			for {
+				select {
+				case job := <-p.jobs:
+					job()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// submit queues the job, running it right away once the pool got stopped.
+func (p *workerPool) submit(job func()) {
+	p.mu.RLock()
+	if !p.stopped {
+		p.jobs <- job
+		p.mu.RUnlock()
+		return
+	}
+	p.mu.RUnlock()
+	job()
+}
+
+// stop lets the workers exit once they ran the queued jobs and waits for them
+// until ctx ends.
+func (p *workerPool) stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.quit)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// inbox serializes the events of a client on the worker pool. At most one
+// worker drains it at a time.
+type inbox struct {
+	mu      sync.Mutex
+	pending []func()
+	running bool
+	slots   chan struct{}
+}
+
+func newInbox(size int) *inbox {
+	return &inbox{
+		slots: make(chan struct{}, size),
+	}
+}
+
+func (b *inbox) push(pool *workerPool, job func()) {
+	b.slots <- struct{}{}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, job)
+	if b.running {
+		b.mu.Unlock()
+		return
+	}
+	b.running = true
+	b.mu.Unlock()
+
+	pool.submit(b.drain)
+}
+
+func (b *inbox) drain() {
+	for {
+		b.mu.Lock()
+		if len(b.pending) == 0 {
+			b.running = false
+			b.mu.Unlock()
+			return
+		}
+		job := b.pending[0]
+		b.pending = b.pending[1:]
+		b.mu.Unlock()
+
+		job()
+		<-b.slots
+	}
+}
+
+// dispatch runs the job on the worker pool, if enabled. A full queue blocks the
+// reader of the client, which stops reading from its socket meanwhile.
+func (s *IgoServer) dispatch(client *Client, job func()) {
+	switch {
+	case s.workers == nil:
+		job()
+	case client.inbox != nil:
+		client.inbox.push(s.workers, job)
+	default:
+		s.workers.submit(job)
+	}
+}
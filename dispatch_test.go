@@ -0,0 +1,45 @@
+package socketigo_test
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+func TestShutdownStopsWorkers(t *testing.T) {
+	before := runtime.NumGoroutine()
+	server := socketigo.CreateIgoServer(&socketigo.IgoServerOptions{Workers: 8})
+	started := make(chan struct{})
+	var finished atomic.Bool
+	server.On("slow", func(client *socketigo.Client, data map[string]interface{}) interface{} {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+		return nil
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	client.Emit("slow", nil)
+	<-started
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if !finished.Load() {
+		t.Fatal("shutdown returned before the listener on a worker finished")
+	}
+
+	client.Close()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left after the shutdown, want at most %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,20 @@
+// Package echoadapter mounts a socket.igo server on an echo router.
+//
+//	e.GET("/socket", echoadapter.Handler(server))
+package echoadapter
+
+import (
+	"github.com/labstack/echo/v4"
+	socketigo "github.com/nauri-io/socket.igo"
+)
+
+// Handler returns an echo handler serving every transport of the server. The
+// request is passed on as the middlewares left it, so the clients see its
+// context and whatever the middlewares put into it. Rejected requests are
+// answered by the server itself, the handler never returns an error.
+func Handler(server *socketigo.IgoServer) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		server.ServeHTTP(c.Response(), c.Request())
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+package socketigo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrDecodeFailed      = errors.New("socketigo: decoding frame failed")
+	ErrClientClosed      = errors.New("socketigo: client closed")
+	ErrUpgradeFailed     = errors.New("socketigo: upgrading connection failed")
+	ErrHandshakeRejected = errors.New("socketigo: handshake rejected")
+	ErrAuthFailed        = errors.New("socketigo: authentication failed")
+	ErrInvalidPayload    = errors.New("socketigo: invalid payload")
+)
+
+// ErrorEvent is the error passed to the server's error handler. It wraps the
+// underlying error and carries the client and request it originated from, if
+// known. Use errors.As to get hold of it and errors.Is to match the sentinels.
+type ErrorEvent struct {
+	Err     error
+	Client  *Client
+	Request *http.Request
+}
+
+func (e *ErrorEvent) Error() string {
+	if e.Client != nil {
+		return fmt.Sprintf("client %s: %v", e.Client.Id, e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *ErrorEvent) Unwrap() error {
+	return e.Err
+}
+
+func (s *IgoServer) OnError(listener func(err error)) {
+	s.errHandler = listener
+}
+
+// ReportError passes an error that occurred while handling the client to the
+// server's error handler, e.g. from listeners or extension packages.
+func (s *IgoServer) ReportError(client *Client, err error) {
+	s.emitError(client, nil, nil, err)
+}
+
+func (s *IgoServer) emitError(client *Client, r *http.Request, kind error, err error) {
+	if err == nil {
+		err = kind
+	} else if kind != nil && !errors.Is(err, kind) {
+		err = fmt.Errorf("%w: %w", kind, err)
+	}
+
+	s.metrics.Error(err)
+	s.logError(client, r, err)
+
+	if s.errHandler == nil {
+		return
+	}
+
+	s.errHandler(&ErrorEvent{
+		Err:     err,
+		Client:  client,
+		Request: r,
+	})
+}
@@ -0,0 +1,163 @@
+// Package fasthttpadapter mounts a socket.igo server in fasthttp based
+// stacks, upgrading its requests with fasthttp/websocket.
+//
+//	fasthttp.ListenAndServe(":8080", fasthttpadapter.Handler(server, nil))
+package fasthttpadapter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/valyala/fasthttp"
+)
+
+// hijackTimeout is the time fasthttp has to hand the upgraded connection over
+// once the handler returned. It does not if writing the upgrade response
+// failed or it closes the connection instead, e.g. while shutting down.
+const hijackTimeout = 10 * time.Second
+
+var errNotHijacked = errors.New("fasthttpadapter: upgraded connection did not get hijacked")
+
+type Options struct {
+	// Upgrader upgrades the requests. Defaults to one with the websocket
+	// options of the server, see IgoServer.UpgradeOptions.
+	Upgrader *websocket.FastHTTPUpgrader
+}
+
+// Handler returns a fasthttp handler serving the websockets of the server.
+// The other transports need net/http, see IgoServer.Handle.
+func Handler(server *socketigo.IgoServer, options *Options) fasthttp.RequestHandler {
+	var custom *websocket.FastHTTPUpgrader
+	if options != nil {
+		custom = options.Upgrader
+	}
+	upgradeOptions := server.UpgradeOptions()
+
+	return func(ctx *fasthttp.RequestCtx) {
+		// the connection outlives the RequestCtx, which gets reused
+		requestCtx, cancel := context.WithCancel(context.Background())
+
+		r, err := newRequest(requestCtx, ctx)
+		if err != nil {
+			cancel()
+			ctx.Error(err.Error(), fasthttp.StatusBadRequest)
+			return
+		}
+
+		admission, ok := server.Admit(&responseWriter{ctx: ctx}, r)
+		if !ok {
+			cancel()
+			return
+		}
+
+		upgrader := custom
+		if upgrader == nil {
+			upgrader = defaultUpgrader(upgradeOptions, r)
+		}
+
+		// pending, serving or abandoned
+		var state atomic.Int32
+		timer := time.AfterFunc(hijackTimeout, func() {
+			if state.CompareAndSwap(0, 2) {
+				cancel()
+				admission.Fail(errNotHijacked)
+			}
+		})
+
+		err = upgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+			defer conn.Close()
+			if !state.CompareAndSwap(0, 1) {
+				return
+			}
+			timer.Stop()
+			defer cancel()
+
+			if custom == nil && upgradeOptions.CompressionLevel != 0 {
+				// an invalid level keeps the default one, as with gorilla
+				conn.SetCompressionLevel(upgradeOptions.CompressionLevel)
+			}
+			admission.Serve(conn)
+		})
+		if err != nil && state.CompareAndSwap(0, 2) {
+			timer.Stop()
+			cancel()
+			admission.Fail(err)
+		}
+	}
+}
+
+// defaultUpgrader upgrades the request with the websocket options of the
+// server, checking the origin of r, the request the server admitted.
+func defaultUpgrader(options socketigo.UpgradeOptions, r *http.Request) *websocket.FastHTTPUpgrader {
+	return &websocket.FastHTTPUpgrader{
+		ReadBufferSize:    options.ReadBufferSize,
+		WriteBufferSize:   options.WriteBufferSize,
+		WriteBufferPool:   options.WriteBufferPool,
+		Subprotocols:      options.Subprotocols,
+		HandshakeTimeout:  options.HandshakeTimeout,
+		EnableCompression: options.EnableCompression,
+		CheckOrigin: func(ctx *fasthttp.RequestCtx) bool {
+			return options.CheckOrigin(r)
+		},
+	}
+}
+
+// newRequest copies the request out of the RequestCtx, whose buffers get
+// reused once the handler returned.
+func newRequest(requestCtx context.Context, ctx *fasthttp.RequestCtx) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(requestCtx, string(ctx.Method()), string(ctx.RequestURI()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range ctx.Request.Header.All() {
+		r.Header.Add(string(key), string(value))
+	}
+	r.Host = string(ctx.Host())
+	r.RequestURI = string(ctx.RequestURI())
+	r.RemoteAddr = ctx.RemoteAddr().String()
+	if ctx.IsTLS() {
+		r.TLS = ctx.TLSConnectionState()
+	}
+	return r, nil
+}
+
+// responseWriter answers the requests the server rejects before the upgrade.
+type responseWriter struct {
+	ctx         *fasthttp.RequestCtx
+	header      http.Header
+	wroteHeader bool
+}
+
+func (w *responseWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	for key, values := range w.header {
+		for _, value := range values {
+			w.ctx.Response.Header.Add(key, value)
+		}
+	}
+	w.ctx.SetStatusCode(status)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ctx.Write(data)
+}
@@ -0,0 +1,67 @@
+package fasthttpadapter_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	ws "github.com/gorilla/websocket"
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/fasthttpadapter"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// dial upgrades a request with the origin to the handler, served over an
+// in-memory listener.
+func dial(t *testing.T, handler fasthttp.RequestHandler, origin string) (*ws.Conn, *http.Response, error) {
+	t.Helper()
+
+	listener := fasthttputil.NewInmemoryListener()
+	go fasthttp.Serve(listener, handler)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	dialer := &ws.Dialer{
+		NetDialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			return listener.Dial()
+		},
+		Subprotocols: []string{"igo.v2", "igo.v1"},
+	}
+	header := http.Header{"Origin": {origin}}
+	conn, response, err := dialer.Dial("ws://socketigo.test/?namespace=/", header)
+	if conn != nil {
+		t.Cleanup(func() {
+			conn.Close()
+		})
+	}
+	return conn, response, err
+}
+
+func TestHandlerUsesServerOptions(t *testing.T) {
+	server := socketigo.CreateIgoServer(&socketigo.IgoServerOptions{
+		CheckOrigin: func(r *http.Request) bool {
+			return r.Header.Get("Origin") == "https://app.example"
+		},
+		Subprotocols: []string{"igo.v1"},
+	})
+	handler := fasthttpadapter.Handler(server, nil)
+
+	_, response, err := dial(t, handler, "https://evil.example")
+	if err == nil {
+		t.Fatal("upgrade from a foreign origin succeeded")
+	}
+	if response == nil || response.StatusCode != http.StatusForbidden {
+		t.Fatalf("upgrade from a foreign origin got %v, want %d", response, http.StatusForbidden)
+	}
+
+	conn, _, err := dial(t, handler, "https://app.example")
+	if err != nil {
+		t.Fatalf("upgrade from the allowed origin failed: %v", err)
+	}
+	if conn.Subprotocol() != "igo.v1" {
+		t.Fatalf("negotiated subprotocol %q, want %q", conn.Subprotocol(), "igo.v1")
+	}
+}
@@ -0,0 +1,23 @@
+// Package fiberadapter mounts a socket.igo server on a fiber app.
+//
+//	app.Get("/socket", fiberadapter.Handler(server, nil))
+package fiberadapter
+
+import (
+	"github.com/gofiber/fiber/v2"
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/fasthttpadapter"
+)
+
+// Handler returns a fiber handler serving the websockets of the server, see
+// fasthttpadapter.Handler. The connection outlives the fiber.Ctx, which gets
+// reused once the handler returned, so clients get a request of their own
+// built from the fasthttp request of the context.
+func Handler(server *socketigo.IgoServer, options *fasthttpadapter.Options) fiber.Handler {
+	handle := fasthttpadapter.Handler(server, options)
+
+	return func(c *fiber.Ctx) error {
+		handle(c.Context())
+		return nil
+	}
+}
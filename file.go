@@ -0,0 +1,365 @@
+package socketigo
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/goccy/go-json"
+)
+
+// Files travel over a stream named "#file". The sender writes a header of its
+// uint32 length followed by the name, size and sha256 of the file as JSON, the
+// receiver answers with the uint64 offset it already has the file up to, so
+// an interrupted transfer resumes where it stopped. The sender writes the rest
+// of the file and the receiver answers with a status byte once it verified the
+// checksum.
+
+const (
+	fileStream         = "#file"
+	maxFileHeader      = 4 << 10
+	fileBufferSize     = 32 << 10
+	partialExtension   = ".part"
+	defaultMaxFileSize = 1 << 30
+)
+
+const (
+	fileVerified byte = iota
+	fileCorrupted
+)
+
+var (
+	ErrChecksumMismatch = errors.New("socketigo: file checksum mismatch")
+	ErrFileRejected     = errors.New("socketigo: file rejected")
+)
+
+// FileProgress reports how far a file transfer got. Bytes includes what a
+// resumed transfer skipped.
+type FileProgress struct {
+	Name     string
+	Size     int64
+	Bytes    int64
+	Outbound bool
+}
+
+// FileHeader describes a file a client is about to send.
+type FileHeader struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"sha256"`
+}
+
+type FileOptions struct {
+	// MaxSize is the largest file accepted in bytes. Defaults to 1 GiB.
+	MaxSize int64
+	// Accept decides whether a file gets received before any of it is
+	// written, returning an error rejects it.
+	Accept func(client *Client, header FileHeader) error
+	// Overwrite replaces files which exist in the directory already, which
+	// are rejected otherwise.
+	Overwrite bool
+}
+
+// SendFile sends the file at path to the client, which accepts it with
+// ReceiveFile. It blocks until the client verified the checksum.
+func (s *IgoServer) SendFile(client *Client, path string) error {
+	stream, err := s.OpenStream(client, fileStream)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	return sendFile(stream, path, client.reportFileProgress)
+}
+
+// ReceiveFile stores the files the clients of the namespace send into dir.
+// Partial files are kept with a ".part" extension until their checksum got
+// verified, so the same user, or the same client without one, sending the
+// same file again resumes the transfer. A file can only be received by one
+// transfer at a time, a second one of the same name is rejected meanwhile. The
+// listener gets called once a transfer ended, with the path of the file or the
+// error it failed with.
+func (n *Namespace) ReceiveFile(dir string, options *FileOptions, listener func(client *Client, path string, err error)) {
+	receiver := &fileReceiver{
+		server:  n.server,
+		dir:     dir,
+		maxSize: defaultMaxFileSize,
+	}
+	if options != nil {
+		if options.MaxSize > 0 {
+			receiver.maxSize = options.MaxSize
+		}
+		receiver.accept = options.Accept
+		receiver.overwrite = options.Overwrite
+	}
+
+	n.OnStream(fileStream, func(client *Client, stream *Stream) {
+		defer stream.Close()
+
+		path, err := receiver.receive(client, stream)
+		if listener != nil {
+			listener(client, path, err)
+		}
+	})
+}
+
+type fileReceiver struct {
+	server    *IgoServer
+	dir       string
+	maxSize   int64
+	accept    func(client *Client, header FileHeader) error
+	overwrite bool
+}
+
+// partialPath returns the path the file is received at until it got verified,
+// which is distinct for every sender and content, so a transfer only resumes
+// its own partial file.
+func partialPath(path string, client *Client, header FileHeader) string {
+	sender := client.UserId()
+	if sender == "" {
+		sender = client.Id.String()
+	}
+	key := sha256.Sum256([]byte(sender + "\x00" + header.Checksum))
+	return path + "." + hex.EncodeToString(key[:8]) + partialExtension
+}
+
+// lockFile claims the path for a transfer, so two of them do not write the
+// same partial file.
+func (s *IgoServer) lockFile(path string) bool {
+	s.filesMu.Lock()
+	defer s.filesMu.Unlock()
+
+	if _, ok := s.receivingFiles[path]; ok {
+		return false
+	}
+	s.receivingFiles[path] = struct{}{}
+	return true
+}
+
+func (s *IgoServer) unlockFile(path string) {
+	s.filesMu.Lock()
+	defer s.filesMu.Unlock()
+
+	delete(s.receivingFiles, path)
+}
+
+// OnFileProgress gets called whenever a file sent to or by the client got
+// further.
+func (c *Client) OnFileProgress(listener func(progress FileProgress)) {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+
+	c.fileHandler = listener
+}
+
+func (c *Client) reportFileProgress(progress FileProgress) {
+	c.progressMu.RLock()
+	handler := c.fileHandler
+	c.progressMu.RUnlock()
+
+	if handler != nil {
+		handler(progress)
+	}
+}
+
+func sendFile(stream io.ReadWriter, path string, progress func(progress FileProgress)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+
+	header, err := json.Marshal(FileHeader{
+		Name:     filepath.Base(path),
+		Size:     info.Size(),
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+	})
+	if err != nil {
+		return err
+	}
+	message := make([]byte, 4+len(header))
+	binary.BigEndian.PutUint32(message, uint32(len(header)))
+	copy(message[4:], header)
+	if _, err := stream.Write(message); err != nil {
+		return err
+	}
+
+	var answer [8]byte
+	if _, err := io.ReadFull(stream, answer[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return ErrFileRejected
+		}
+		return err
+	}
+	offset := int64(binary.BigEndian.Uint64(answer[:]))
+	if offset > info.Size() {
+		return fmt.Errorf("%w: offset %d beyond size %d", ErrFileRejected, offset, info.Size())
+	}
+
+	buffer := make([]byte, fileBufferSize)
+	for sent := offset; sent < info.Size(); {
+		n, err := file.ReadAt(buffer[:min(int64(len(buffer)), info.Size()-sent)], sent)
+		if n == 0 && err != nil {
+			return err
+		}
+		if _, err := stream.Write(buffer[:n]); err != nil {
+			return err
+		}
+		sent += int64(n)
+		progress(FileProgress{Name: filepath.Base(path), Size: info.Size(), Bytes: sent, Outbound: true})
+	}
+
+	var status [1]byte
+	if _, err := io.ReadFull(stream, status[:]); err != nil {
+		// the receiver verified the file, but could not keep it
+		if errors.Is(err, io.EOF) {
+			return ErrFileRejected
+		}
+		return err
+	}
+	if status[0] != fileVerified {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+func (r *fileReceiver) receive(client *Client, stream io.ReadWriter) (string, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(stream, length[:]); err != nil {
+		return "", err
+	}
+	size := binary.BigEndian.Uint32(length[:])
+	if size > maxFileHeader {
+		return "", fmt.Errorf("%w: header of %d bytes", ErrFileRejected, size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(stream, data); err != nil {
+		return "", err
+	}
+	var header FileHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrFileRejected, err)
+	}
+
+	// the name is the sender's, it must not point outside of dir
+	name := filepath.Base(header.Name)
+	if name != header.Name || name == "." || name == ".." || header.Size < 0 {
+		return "", fmt.Errorf("%w: %q of %d bytes", ErrFileRejected, header.Name, header.Size)
+	}
+	if header.Size > r.maxSize {
+		return "", fmt.Errorf("%w: %q of %d bytes, limit is %d", ErrFileRejected, name, header.Size, r.maxSize)
+	}
+	if r.accept != nil {
+		if err := r.accept(client, header); err != nil {
+			return "", fmt.Errorf("%w: %w", ErrFileRejected, err)
+		}
+	}
+
+	path, err := filepath.Abs(filepath.Join(r.dir, name))
+	if err != nil {
+		return "", err
+	}
+	if !r.server.lockFile(path) {
+		return "", fmt.Errorf("%w: %q is being received already", ErrFileRejected, name)
+	}
+	defer r.server.unlockFile(path)
+
+	if _, err := os.Lstat(path); err == nil && !r.overwrite {
+		return "", fmt.Errorf("%w: %q exists already", ErrFileRejected, name)
+	}
+	partial := partialPath(path, client, header)
+
+	file, err := os.OpenFile(partial, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	offset := info.Size()
+	if offset > header.Size {
+		if err := file.Truncate(0); err != nil {
+			return "", err
+		}
+		offset = 0
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, offset)); err != nil {
+		return "", err
+	}
+
+	var answer [8]byte
+	binary.BigEndian.PutUint64(answer[:], uint64(offset))
+	if _, err := stream.Write(answer[:]); err != nil {
+		return "", err
+	}
+
+	buffer := make([]byte, fileBufferSize)
+	for received := offset; received < header.Size; {
+		n, err := stream.Read(buffer[:min(int64(len(buffer)), header.Size-received)])
+		if n > 0 {
+			if _, err := file.WriteAt(buffer[:n], received); err != nil {
+				return "", err
+			}
+			hash.Write(buffer[:n])
+			received += int64(n)
+			client.reportFileProgress(FileProgress{Name: name, Size: header.Size, Bytes: received})
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != header.Checksum {
+		file.Close()
+		os.Remove(partial)
+		stream.Write([]byte{fileCorrupted})
+		return "", ErrChecksumMismatch
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	if err := r.complete(partial, path); err != nil {
+		return "", err
+	}
+	if _, err := stream.Write([]byte{fileVerified}); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// complete moves the verified partial file to path. Without Overwrite it is
+// linked rather than renamed, which fails if a file got there meanwhile.
+func (r *fileReceiver) complete(partial string, path string) error {
+	if r.overwrite {
+		return os.Rename(partial, path)
+	}
+	if err := os.Link(partial, path); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%w: %q exists already", ErrFileRejected, filepath.Base(path))
+		}
+		return err
+	}
+	return os.Remove(partial)
+}
@@ -0,0 +1,259 @@
+package socketigo_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/igoclient"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+type fileResult struct {
+	path string
+	err  error
+}
+
+// writeFile writes size random bytes to a file named name in a new directory.
+func writeFile(t *testing.T, name string, size int) (string, []byte) {
+	t.Helper()
+
+	data := make([]byte, size)
+	rand.Read(data)
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+func receiveFiles(server *socketigo.IgoServer, dir string, options *socketigo.FileOptions) chan fileResult {
+	results := make(chan fileResult, 1)
+	server.ReceiveFile(dir, options, func(client *socketigo.Client, path string, err error) {
+		results <- fileResult{path: path, err: err}
+	})
+	return results
+}
+
+func TestReceiveFile(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	dir := t.TempDir()
+	results := receiveFiles(server, dir, nil)
+
+	client := testclient.MustConnect(t, server, nil)
+	path, data := writeFile(t, "report.bin", 300<<10)
+	if err := client.SendFile(path); err != nil {
+		t.Fatalf("sending the file failed: %v", err)
+	}
+
+	result := <-results
+	if result.err != nil {
+		t.Fatalf("receiving the file failed: %v", result.err)
+	}
+	if result.path != filepath.Join(dir, "report.bin") {
+		t.Fatalf("file got received to %q, want it in %q", result.path, dir)
+	}
+	received, err := os.ReadFile(result.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(received, data) {
+		t.Fatal("received file differs from the sent one")
+	}
+}
+
+func TestReceiveFileRejects(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	dir := t.TempDir()
+	results := receiveFiles(server, dir, &socketigo.FileOptions{
+		MaxSize: 64 << 10,
+		Accept: func(client *socketigo.Client, header socketigo.FileHeader) error {
+			if filepath.Ext(header.Name) != ".txt" {
+				return errors.New("text files only")
+			}
+			return nil
+		},
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	for _, file := range []struct {
+		name string
+		size int
+	}{
+		{name: "large.txt", size: 128 << 10},
+		{name: "image.png", size: 1 << 10},
+	} {
+		path, _ := writeFile(t, file.name, file.size)
+		if err := client.SendFile(path); !errors.Is(err, igoclient.ErrFileRejected) {
+			t.Fatalf("sending %s failed with %v, want it rejected", file.name, err)
+		}
+		if result := <-results; !errors.Is(result.err, socketigo.ErrFileRejected) {
+			t.Fatalf("receiving %s failed with %v, want it rejected", file.name, result.err)
+		}
+		if partials, _ := filepath.Glob(filepath.Join(dir, "*.part")); len(partials) > 0 {
+			t.Fatalf("rejected %s left partial files %v", file.name, partials)
+		}
+	}
+}
+
+func TestReceiveFileExisting(t *testing.T) {
+	for _, overwrite := range []bool{false, true} {
+		server := socketigo.CreateIgoServer(nil)
+		dir := t.TempDir()
+		results := receiveFiles(server, dir, &socketigo.FileOptions{Overwrite: overwrite})
+
+		existing := filepath.Join(dir, "notes.txt")
+		if err := os.WriteFile(existing, []byte("existing"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		client := testclient.MustConnect(t, server, nil)
+		path, data := writeFile(t, "notes.txt", 1<<10)
+		err := client.SendFile(path)
+		result := <-results
+
+		received, _ := os.ReadFile(existing)
+		if overwrite {
+			if err != nil || result.err != nil {
+				t.Fatalf("overwriting failed with %v and %v", err, result.err)
+			}
+			if !bytes.Equal(received, data) {
+				t.Fatal("existing file did not get overwritten")
+			}
+			continue
+		}
+		if !errors.Is(err, igoclient.ErrFileRejected) || !errors.Is(result.err, socketigo.ErrFileRejected) {
+			t.Fatalf("sending an existing file failed with %v and %v, want it rejected", err, result.err)
+		}
+		if string(received) != "existing" {
+			t.Fatal("existing file got overwritten")
+		}
+	}
+}
+
+func TestSendFile(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	connected := make(chan *socketigo.Client, 1)
+	server.OnConnected(func(client *socketigo.Client) {
+		connected <- client
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	dir := t.TempDir()
+	results := make(chan fileResult, 1)
+	client.ReceiveFile(dir, func(client *igoclient.Client, path string, err error) {
+		results <- fileResult{path: path, err: err}
+	})
+
+	path, data := writeFile(t, "update.bin", 100<<10)
+	if err := server.SendFile(<-connected, path); err != nil {
+		t.Fatalf("sending the file failed: %v", err)
+	}
+
+	select {
+	case result := <-results:
+		if result.err != nil {
+			t.Fatalf("receiving the file failed: %v", result.err)
+		}
+		received, err := os.ReadFile(result.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(received, data) {
+			t.Fatal("received file differs from the sent one")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("client did not receive the file")
+	}
+}
+
+func TestReceiveFileResumesOwnPartial(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	server.SetAuthenticator(func(ctx *socketigo.HandshakeContext, payload map[string]interface{}) error {
+		user, _ := payload["user"].(string)
+		ctx.Client.SetUserId(user)
+		return nil
+	})
+	progress := make(chan socketigo.FileProgress, 64)
+	server.OnConnected(func(client *socketigo.Client) {
+		client.OnFileProgress(func(p socketigo.FileProgress) {
+			progress <- p
+		})
+	})
+	dir := t.TempDir()
+	results := receiveFiles(server, dir, &socketigo.FileOptions{Overwrite: true})
+
+	path, data := writeFile(t, "video.bin", 256<<10)
+	connect := func(user string) *testclient.Client {
+		return testclient.MustConnect(t, server, &testclient.Options{Auth: map[string]interface{}{"user": user}})
+	}
+	// firstProgress returns the bytes the server had of the next transfer
+	// once the first piece arrived
+	firstProgress := func() int64 {
+		first := <-progress
+		for len(progress) > 0 {
+			<-progress
+		}
+		return first.Bytes
+	}
+
+	// alice gets half of the file across before her connection drops
+	alice := connect("alice")
+	stream, err := alice.OpenStream("#file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	header, _ := json.Marshal(map[string]interface{}{"name": "video.bin", "size": len(data), "sha256": hex.EncodeToString(sum[:])})
+	stream.Write(binary.BigEndian.AppendUint32(nil, uint32(len(header))))
+	stream.Write(header)
+	var offset [8]byte
+	if _, err := io.ReadFull(stream, offset[:]); err != nil {
+		t.Fatal(err)
+	}
+	stream.Write(data[:128<<10])
+	for p := range progress {
+		if p.Bytes == 128<<10 {
+			break
+		}
+	}
+	alice.Drop()
+	if result := <-results; result.err == nil {
+		t.Fatal("interrupted transfer succeeded")
+	}
+
+	// bob sends the same file, which must not resume the partial of alice
+	if err := connect("bob").SendFile(path); err != nil {
+		t.Fatalf("sending the file failed: %v", err)
+	}
+	if result := <-results; result.err != nil {
+		t.Fatalf("receiving the file failed: %v", result.err)
+	}
+	if start := firstProgress(); start > 64<<10 {
+		t.Fatalf("transfer of bob started at %d bytes, want it to start over", start)
+	}
+
+	// alice sending it again resumes her own partial
+	if err := connect("alice").SendFile(path); err != nil {
+		t.Fatalf("sending the file again failed: %v", err)
+	}
+	if result := <-results; result.err != nil {
+		t.Fatalf("receiving the file again failed: %v", result.err)
+	}
+	if start := firstProgress(); start <= 128<<10 {
+		t.Fatalf("transfer of alice started at %d bytes, want it to resume after %d", start, 128<<10)
+	}
+	if partials, _ := filepath.Glob(filepath.Join(dir, "*.part")); len(partials) > 0 {
+		t.Fatalf("completed transfers left partial files %v", partials)
+	}
+}
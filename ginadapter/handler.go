@@ -0,0 +1,18 @@
+// Package ginadapter mounts a socket.igo server on a gin router.
+//
+//	router.GET("/socket", ginadapter.Handler(server))
+package ginadapter
+
+import (
+	"github.com/gin-gonic/gin"
+	socketigo "github.com/nauri-io/socket.igo"
+)
+
+// Handler returns a gin handler serving every transport of the server. The
+// request is passed on as the middlewares left it, so the clients see its
+// context and whatever the middlewares put into it.
+func Handler(server *socketigo.IgoServer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		server.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,112 @@
+package socketigo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const healthCheckTimeout = 2 * time.Second
+
+// HealthChecker is implemented by adapters and brokers able to tell whether
+// their backend is reachable. The health endpoint reports unhealthy otherwise.
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+type RoomHealth struct {
+	Id      string `json:"id"`
+	Clients int    `json:"clients"`
+}
+
+type NamespaceHealth struct {
+	Name    string       `json:"name"`
+	Clients int          `json:"clients"`
+	Rooms   []RoomHealth `json:"rooms"`
+}
+
+type AdapterHealth struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Health is the report served by HealthHandler.
+type Health struct {
+	Status        string            `json:"status"`
+	Node          string            `json:"node"`
+	Uptime        string            `json:"uptime"`
+	UptimeSeconds float64           `json:"uptimeSeconds"`
+	Clients       int               `json:"clients"`
+	Namespaces    []NamespaceHealth `json:"namespaces"`
+	Adapter       AdapterHealth     `json:"adapter"`
+}
+
+// Health reports the state of the server. It is unhealthy if the adapter
+// implements HealthChecker and its check fails.
+func (s *IgoServer) Health(ctx context.Context) *Health {
+	uptime := time.Since(s.startedAt)
+	health := &Health{
+		Status:        "ok",
+		Node:          s.nodeId,
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: uptime.Seconds(),
+		Clients:       s.clients.len(),
+		Namespaces:    make([]NamespaceHealth, 0),
+		Adapter:       s.adapterHealth(ctx),
+	}
+	if health.Adapter.Status != "ok" {
+		health.Status = "unhealthy"
+	}
+
+	for _, ns := range s.Namespaces() {
+		nsHealth := NamespaceHealth{
+			Name:    ns.Name,
+			Clients: ns.clients.len(),
+			Rooms:   make([]RoomHealth, 0),
+		}
+
+		for _, room := range ns.ListRooms() {
+			nsHealth.Rooms = append(nsHealth.Rooms, RoomHealth{Id: room.Id, Clients: room.Size()})
+		}
+
+		health.Namespaces = append(health.Namespaces, nsHealth)
+	}
+	return health
+}
+
+func (s *IgoServer) adapterHealth(ctx context.Context) AdapterHealth {
+	var target interface{} = s.adapter
+	if adapter, ok := s.adapter.(*brokerAdapter); ok {
+		target = adapter.broker
+	}
+
+	health := AdapterHealth{
+		Type:   fmt.Sprintf("%T", target),
+		Status: "ok",
+	}
+	if checker, ok := target.(HealthChecker); ok {
+		if err := checker.Health(ctx); err != nil {
+			health.Status = "error"
+			health.Error = err.Error()
+		}
+	}
+	return health
+}
+
+// HealthHandler serves the health report as JSON, answering with 503 while the
+// server is unhealthy so load balancers take the instance out of rotation.
+func (s *IgoServer) HealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+
+		health := s.Health(ctx)
+		status := http.StatusOK
+		if health.Status != "ok" {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, health)
+	}
+}
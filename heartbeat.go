@@ -0,0 +1,117 @@
+package socketigo
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+const (
+	defaultPingInterval = 25 * time.Second
+	defaultPongWait     = 60 * time.Second
+	defaultWriteWait    = 10 * time.Second
+)
+
+type DisconnectReason int
+
+const (
+	// ReasonClientClose means the client closed the connection with a close frame.
+	ReasonClientClose DisconnectReason = iota
+	// ReasonReadError means the connection broke while reading from it.
+	ReasonReadError
+	// ReasonPingTimeout means the client did not answer pings within PongWait.
+	ReasonPingTimeout
+	// ReasonSlowConsumer means the client got disconnected by the Disconnect
+	// backpressure policy.
+	ReasonSlowConsumer
+	// ReasonMessageTooLarge means the client sent a message above MaxMessageSize
+	// with DisconnectOversized.
+	ReasonMessageTooLarge
+	// ReasonIdle means the client did not send any message for IdleTimeout.
+	ReasonIdle
+	// ReasonRateLimited means the client exceeded its rate limit with
+	// RateLimitDisconnect.
+	ReasonRateLimited
+	// ReasonKicked means the server closed the client with Close or Disconnect.
+	ReasonKicked
+	// ReasonShutdown means the server shut down.
+	ReasonShutdown
+)
+
+func (r DisconnectReason) String() string {
+	switch r {
+	case ReasonClientClose:
+		return "client close"
+	case ReasonReadError:
+		return "read error"
+	case ReasonPingTimeout:
+		return "ping timeout"
+	case ReasonSlowConsumer:
+		return "slow consumer"
+	case ReasonMessageTooLarge:
+		return "message too large"
+	case ReasonIdle:
+		return "idle"
+	case ReasonRateLimited:
+		return "rate limited"
+	case ReasonKicked:
+		return "kicked"
+	case ReasonShutdown:
+		return "shutdown"
+	default:
+		return "unknown"
+	}
+}
+
+func classifyReadError(err error) DisconnectReason {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ReasonPingTimeout
+	}
+
+	var closeErr *ws.CloseError
+	if errors.As(err, &closeErr) {
+		return ReasonClientClose
+	}
+	return ReasonReadError
+}
+
+// DisconnectReason returns why the client got disconnected. It is only
+// meaningful once the disconnected handler fired.
+func (c *Client) DisconnectReason() DisconnectReason {
+	c.reasonMu.Lock()
+	defer c.reasonMu.Unlock()
+
+	return c.disconnectReason
+}
+
+// setDisconnectReason records the reason unless one was recorded before, so the
+// cause of a server side close wins over the resulting read error.
+func (c *Client) setDisconnectReason(reason DisconnectReason) {
+	c.reasonMu.Lock()
+	defer c.reasonMu.Unlock()
+
+	if !c.reasonSet {
+		c.disconnectReason = reason
+		c.reasonSet = true
+	}
+}
+
+func (c *Client) closeWithReason(reason DisconnectReason) error {
+	c.setDisconnectReason(reason)
+	c.setState(StateClosing)
+	return c.socket.Close()
+}
+
+// startHeartbeat arms the read deadline, which every pong extends. The pings
+// themselves are sent by the write pump.
+func (s *IgoServer) startHeartbeat(client *Client) {
+	conn := client.socket
+
+	conn.SetReadDeadline(time.Now().Add(s.readWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(s.readWait))
+	})
+}
@@ -0,0 +1,91 @@
+package socketigo
+
+import (
+	"sync"
+	"time"
+)
+
+// HistoryEntry is an event a room retained for late joiners.
+type HistoryEntry struct {
+	Event string
+	Data  interface{}
+	At    time.Time
+}
+
+type roomHistory struct {
+	mu      sync.Mutex
+	size    int
+	window  time.Duration
+	entries []HistoryEntry
+}
+
+// SetHistory makes the room retain the last size events emitted with Emit and
+// EmitExcept on this node, dropping the ones older than window unless it is
+// zero. Every joining client gets them replayed, oldest first, right after
+// joining. A size of zero disables the history.
+func (r *Room) SetHistory(size int, window time.Duration) {
+	r.joinMu.Lock()
+	defer r.joinMu.Unlock()
+
+	if size <= 0 {
+		r.history = nil
+		return
+	}
+	r.history = &roomHistory{size: size, window: window}
+}
+
+func (r *Room) getHistory() *roomHistory {
+	r.joinMu.Lock()
+	defer r.joinMu.Unlock()
+
+	return r.history
+}
+
+// History returns the retained events, oldest first.
+func (r *Room) History() []HistoryEntry {
+	h := r.getHistory()
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.expire(time.Now())
+	return append([]HistoryEntry(nil), h.entries...)
+}
+
+func (r *Room) record(eventName string, data interface{}) {
+	h := r.getHistory()
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.expire(now)
+	if len(h.entries) >= h.size {
+		h.entries = h.entries[len(h.entries)-h.size+1:]
+	}
+	h.entries = append(h.entries, HistoryEntry{Event: eventName, Data: data, At: now})
+}
+
+func (h *roomHistory) expire(now time.Time) {
+	if h.window <= 0 {
+		return
+	}
+
+	i := 0
+	for i < len(h.entries) && now.Sub(h.entries[i].At) > h.window {
+		i++
+	}
+	h.entries = h.entries[i:]
+}
+
+func (r *Room) replay(client *Client) {
+	for _, entry := range r.History() {
+		client.Emit(entry.Event, entry.Data)
+	}
+}
@@ -0,0 +1,38 @@
+package socketigo
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// startIdleTimer disconnects the client once it did not send a message for
+// IdleTimeout. Pongs do not count, the heartbeat covers dead connections.
+func (s *IgoServer) startIdleTimer(client *Client) {
+	if s.idleTimeout <= 0 {
+		return
+	}
+
+	client.touch()
+
+	var timer *time.Timer
+	timer = time.AfterFunc(s.idleTimeout, func() {
+		select {
+		case <-client.done:
+			return
+		default:
+		}
+
+		idle := time.Since(time.Unix(0, atomic.LoadInt64(&client.lastActive)))
+		if idle < s.idleTimeout {
+			timer.Reset(s.idleTimeout - idle)
+			return
+		}
+		s.logger.Debug("client idle", client.logFields("idle", idle.String())...)
+		client.closeWithReason(ReasonIdle)
+	})
+}
+
+// touch records that the client just sent a message.
+func (c *Client) touch() {
+	atomic.StoreInt64(&c.lastActive, time.Now().UnixNano())
+}
@@ -0,0 +1,107 @@
+package igoclient
+
+import (
+	"reflect"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// Placeholder stands in for the attachment at index num within the data of
+// EmitAttachments.
+func Placeholder(num int) map[string]interface{} {
+	return map[string]interface{}{
+		"_placeholder": true,
+		"num":          num,
+	}
+}
+
+// EmitAttachments emits the event along with binary attachments, sent as they
+// are instead of base64 encoded within the data. The data refers to them with
+// Placeholder.
+func (c *Client) EmitAttachments(eventName string, data interface{}, attachments ...[]byte) error {
+	frame := map[string]interface{}{
+		"event": eventName,
+		"data":  data,
+	}
+	// binary codecs carry the attachments inline
+	if c.options.Codec.MessageType() == ws.BinaryMessage {
+		frame["attachments"] = attachments
+		return c.write(frame)
+	}
+	frame["attachments"] = len(attachments)
+
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.writeFrameTo(conn, frame); err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if err := conn.WriteMessage(ws.BinaryMessage, attachment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachmentCount returns the number of binary messages following a frame of
+// a text codec.
+func attachmentCount(frame map[string]interface{}) int {
+	count, _ := frame["attachments"].(float64)
+	return int(count)
+}
+
+// inlineAttachments returns the attachments binary codecs carry in the frame.
+func inlineAttachments(frame map[string]interface{}) [][]byte {
+	list, _ := frame["attachments"].([]interface{})
+	attachments := make([][]byte, 0, len(list))
+	for _, item := range list {
+		if attachment, ok := item.([]byte); ok {
+			attachments = append(attachments, attachment)
+		}
+	}
+	return attachments
+}
+
+// resolvePlaceholders replaces the placeholders within maps and lists by the
+// attachments they refer to.
+func resolvePlaceholders(value interface{}, attachments [][]byte) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if placeholder, _ := v["_placeholder"].(bool); placeholder {
+			if index := placeholderIndex(v["num"]); index >= 0 && index < len(attachments) {
+				return attachments[index]
+			}
+			return nil
+		}
+		for key, item := range v {
+			v[key] = resolvePlaceholders(item, attachments)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = resolvePlaceholders(item, attachments)
+		}
+	}
+	return value
+}
+
+func placeholderIndex(num interface{}) int {
+	value := reflect.ValueOf(num)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return int(value.Float())
+	}
+	return -1
+}
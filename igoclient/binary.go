@@ -0,0 +1,114 @@
+package igoclient
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	ws "github.com/gorilla/websocket"
+)
+
+var errMalformedBinaryFrame = errors.New("igoclient: malformed binary frame")
+
+func encodeBinaryFrame(eventName string, data []byte) ([]byte, error) {
+	if len(eventName) > math.MaxUint16 {
+		return nil, errors.New("igoclient: event name too long")
+	}
+
+	frame := make([]byte, 2+len(eventName)+len(data))
+	binary.BigEndian.PutUint16(frame, uint16(len(eventName)))
+	copy(frame[2:], eventName)
+	copy(frame[2+len(eventName):], data)
+	return frame, nil
+}
+
+func (c *Client) handleBinary(r *reader, frame []byte) error {
+	if len(frame) < 2 {
+		return errMalformedBinaryFrame
+	}
+
+	nameLen := int(binary.BigEndian.Uint16(frame))
+	if len(frame) < 2+nameLen {
+		return errMalformedBinaryFrame
+	}
+
+	eventName := string(frame[2 : 2+nameLen])
+	switch eventName {
+	case chunkEvent:
+		return c.addChunk(r, frame[2+nameLen:])
+	case streamEvent:
+		return c.handleStream(frame[2+nameLen:])
+	}
+	c.dispatchBinaryEvent(eventName, frame[2+nameLen:])
+	return nil
+}
+
+func (c *Client) dispatchBinaryEvent(eventName string, data []byte) {
+	c.mu.RLock()
+	listener, ok := c.binaryEvents[eventName]
+	c.mu.RUnlock()
+
+	if ok {
+		listener(c, data)
+	}
+}
+
+func (c *Client) EmitBinary(eventName string, data []byte) error {
+	return c.emitBinary(eventName, data, true)
+}
+
+// emitBinary emits the binary event, split into chunks if chunked is set and
+// it is larger than ChunkSize.
+func (c *Client) emitBinary(eventName string, data []byte, chunked bool) error {
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	// binary codecs carry binary events inside their regular frames
+	if c.options.Codec.MessageType() == ws.BinaryMessage {
+		frame := map[string]interface{}{
+			"event":  eventName,
+			"data":   data,
+			"binary": true,
+		}
+		if chunked {
+			return c.write(frame)
+		}
+
+		c.writeMu.Lock()
+		defer c.writeMu.Unlock()
+
+		return c.writeFrameTo(conn, frame)
+	}
+
+	frame, err := encodeBinaryFrame(eventName, data)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if !chunked {
+		return conn.WriteMessage(ws.BinaryMessage, frame)
+	}
+	return c.writeMessage(conn, ws.BinaryMessage, frame)
+}
+
+func (c *Client) OnBinary(eventName string, listener BinaryListener) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.binaryEvents[eventName] = listener
+}
+
+func (c *Client) OffBinary(eventName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.binaryEvents, eventName)
+}
@@ -0,0 +1,141 @@
+package igoclient
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	ws "github.com/gorilla/websocket"
+)
+
+const (
+	chunkEvent      = "#chunk"
+	chunkingParam   = "chunking"
+	chunkHeaderSize = 13
+)
+
+var errMalformedChunk = errors.New("igoclient: malformed chunk")
+
+// ChunkProgress reports how far the transfer of a chunked message got.
+type ChunkProgress struct {
+	Id uint32
+	// Chunks is the number of chunks sent or received so far, out of Total.
+	Chunks int
+	Total  int
+	// Bytes is the size of the chunks transferred so far.
+	Bytes int
+	// Outbound is set for messages sent to the server.
+	Outbound bool
+}
+
+type chunkedMessage struct {
+	messageType int
+	total       int
+	received    int
+	data        []byte
+}
+
+// OnChunkProgress gets called whenever a chunk of a message sent to or by the
+// server was written or read.
+func (c *Client) OnChunkProgress(listener func(client *Client, progress ChunkProgress)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.progressHandler = listener
+}
+
+func (c *Client) reportProgress(progress ChunkProgress) {
+	c.mu.RLock()
+	handler := c.progressHandler
+	c.mu.RUnlock()
+
+	if handler != nil {
+		handler(c, progress)
+	}
+}
+
+// writeMessage writes the message, split into chunks when it is larger than
+// ChunkSize. The caller holds writeMu.
+func (c *Client) writeMessage(conn connection, messageType int, data []byte) error {
+	size := c.options.ChunkSize
+	if size <= 0 || len(data) <= size {
+		return conn.WriteMessage(messageType, data)
+	}
+
+	id := atomic.AddUint32(&c.chunkSeq, 1)
+	total := (len(data) + size - 1) / size
+
+	for index := 0; index < total; index++ {
+		end := min((index+1)*size, len(data))
+		chunk, err := c.encodeChunk(id, index, total, messageType, data[index*size:end])
+		if err != nil {
+			return err
+		}
+		if err := conn.WriteMessage(ws.BinaryMessage, chunk); err != nil {
+			return err
+		}
+		c.reportProgress(ChunkProgress{Id: id, Chunks: index + 1, Total: total, Bytes: end, Outbound: true})
+	}
+	return nil
+}
+
+func (c *Client) encodeChunk(id uint32, index int, total int, messageType int, piece []byte) ([]byte, error) {
+	payload := make([]byte, chunkHeaderSize+len(piece))
+	binary.BigEndian.PutUint32(payload, id)
+	binary.BigEndian.PutUint32(payload[4:], uint32(index))
+	binary.BigEndian.PutUint32(payload[8:], uint32(total))
+	payload[12] = byte(messageType)
+	copy(payload[chunkHeaderSize:], piece)
+
+	// binary codecs carry binary events inside their regular frames
+	if c.options.Codec.MessageType() == ws.BinaryMessage {
+		return c.options.Codec.Marshal(map[string]interface{}{
+			"event":  chunkEvent,
+			"data":   payload,
+			"binary": true,
+		})
+	}
+	return encodeBinaryFrame(chunkEvent, payload)
+}
+
+// addChunk collects a chunk sent by the server, handling the message once its
+// last chunk arrived.
+func (c *Client) addChunk(r *reader, payload []byte) error {
+	if len(payload) < chunkHeaderSize {
+		return errMalformedChunk
+	}
+
+	id := binary.BigEndian.Uint32(payload)
+	index := int(binary.BigEndian.Uint32(payload[4:]))
+	total := int(binary.BigEndian.Uint32(payload[8:]))
+	messageType := int(payload[12])
+	piece := payload[chunkHeaderSize:]
+
+	message := r.chunked[id]
+	if message == nil {
+		if index != 0 || total < 1 {
+			return fmt.Errorf("%w: message %d starting with chunk %d of %d", errMalformedChunk, id, index, total)
+		}
+		message = &chunkedMessage{messageType: messageType, total: total}
+		if r.chunked == nil {
+			r.chunked = make(map[uint32]*chunkedMessage)
+		}
+		r.chunked[id] = message
+	}
+
+	if index != message.received || total != message.total {
+		delete(r.chunked, id)
+		return fmt.Errorf("%w: chunk %d of %d of message %d out of order", errMalformedChunk, index, total, id)
+	}
+
+	message.data = append(message.data, piece...)
+	message.received++
+	c.reportProgress(ChunkProgress{Id: id, Chunks: message.received, Total: total, Bytes: len(message.data)})
+
+	if message.received == message.total {
+		delete(r.chunked, id)
+		c.handleMessage(r, message.messageType, message.data)
+	}
+	return nil
+}
@@ -0,0 +1,729 @@
+// Package igoclient is a Go client speaking the socket.igo wire protocol.
+package igoclient
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+var (
+	ErrNotConnected = errors.New("igoclient: not connected")
+	ErrClosed       = errors.New("igoclient: client closed")
+	ErrAckTimeout   = errors.New("igoclient: ack timed out")
+)
+
+// HandshakeError is returned when the server rejects the connection with an
+// error frame.
+type HandshakeError struct {
+	Code    string
+	Message string
+}
+
+func (e *HandshakeError) Error() string {
+	return "igoclient: handshake rejected (" + e.Code + "): " + e.Message
+}
+
+// ServerError is reported to the error handler when the server sends an error
+// frame after the handshake, e.g. for a payload failing its schema.
+type ServerError struct {
+	Code    string
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return "igoclient: server error (" + e.Code + "): " + e.Message
+}
+
+// AckError is returned by EmitWithAck when the server's listener acknowledged
+// with an error. Fields lists the failed fields of a payload failing its
+// validation.
+type AckError struct {
+	Message string
+	Fields  []FieldError
+}
+
+type FieldError struct {
+	Field string
+	Tag   string
+	Param string
+}
+
+func newAckError(message string, response map[string]interface{}) *AckError {
+	ackErr := &AckError{Message: message}
+
+	fields, _ := response["fields"].([]interface{})
+	for _, field := range fields {
+		values, _ := field.(map[string]interface{})
+		name, _ := values["field"].(string)
+		tag, _ := values["tag"].(string)
+		param, _ := values["param"].(string)
+		ackErr.Fields = append(ackErr.Fields, FieldError{Field: name, Tag: tag, Param: param})
+	}
+	return ackErr
+}
+
+func (e *AckError) Error() string {
+	return "igoclient: ack failed: " + e.Message
+}
+
+type EventListener func(client *Client, data map[string]interface{}) interface{}
+
+type BinaryListener func(client *Client, data []byte)
+
+// AnyListener observes every incoming event, before the listener of the event
+// runs.
+type AnyListener func(client *Client, eventName string, data map[string]interface{})
+
+type Options struct {
+	// Namespace is the server namespace to connect to. Defaults to "/".
+	Namespace string
+	// Auth is sent as the first frame of every connection when set.
+	Auth   map[string]interface{}
+	Header http.Header
+	Dialer *ws.Dialer
+	// Codec has to match the codec of the server. Defaults to JSONCodec.
+	Codec Codec
+	// ReconnectDelay is the initial backoff between reconnection attempts, which
+	// doubles up to MaxReconnectDelay. Defaults to 1 and 30 seconds.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	DisableReconnect  bool
+	// PingInterval is the interval pings are sent to the server in, PongWait the
+	// time the server has to answer before the connection is considered dead.
+	// Default to 25 and 60 seconds.
+	PingInterval time.Duration
+	PongWait     time.Duration
+	// ChunkSize splits messages larger than it into chunks of that size, which
+	// the server reassembles. Zero sends every message whole.
+	ChunkSize int
+}
+
+type Client struct {
+	url                 string
+	options             Options
+	mu                  sync.RWMutex
+	writeMu             sync.Mutex
+	conn                connection
+	id                  string
+	session             string
+	resumed             bool
+	events              map[string]EventListener
+	binaryEvents        map[string]BinaryListener
+	anyListener         AnyListener
+	acks                map[string]chan map[string]interface{}
+	closed              bool
+	done                chan struct{}
+	connectedHandler    func(client *Client)
+	disconnectedHandler func(client *Client, err error)
+	errorHandler        func(client *Client, err error)
+	calls               map[string]chan map[string]interface{}
+	progressHandler     func(client *Client, progress ChunkProgress)
+	fileHandler         func(client *Client, progress FileProgress)
+	chunkSeq            uint32
+	streamsMu           sync.Mutex
+	streams             map[uint32]*Stream
+	streamListeners     map[string]func(client *Client, stream *Stream)
+	streamSeq           uint32
+}
+
+// Dial connects to the server at the given url and blocks until the handshake
+// completed. Reconnection only kicks in after the first successful handshake.
+func Dial(rawUrl string, options *Options) (*Client, error) {
+	c := NewClient(rawUrl, options)
+
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewClient creates a client without connecting it, so listeners can be
+// registered before the first events arrive.
+func NewClient(rawUrl string, options *Options) *Client {
+	if options == nil {
+		options = &Options{}
+	}
+
+	c := &Client{
+		url:          rawUrl,
+		options:      *options,
+		events:       make(map[string]EventListener),
+		binaryEvents: make(map[string]BinaryListener),
+		acks:         make(map[string]chan map[string]interface{}),
+		done:         make(chan struct{}),
+	}
+	c.applyDefaults()
+	return c
+}
+
+// Connect dials the server and blocks until the handshake completed.
+func (c *Client) Connect() error {
+	return c.connect()
+}
+
+func (c *Client) applyDefaults() {
+	if c.options.Namespace == "" {
+		c.options.Namespace = "/"
+	}
+	if c.options.Codec == nil {
+		c.options.Codec = JSONCodec{}
+	}
+	if c.options.Dialer == nil {
+		c.options.Dialer = ws.DefaultDialer
+	}
+	if c.options.ReconnectDelay <= 0 {
+		c.options.ReconnectDelay = time.Second
+	}
+	if c.options.MaxReconnectDelay <= 0 {
+		c.options.MaxReconnectDelay = 30 * time.Second
+	}
+	if c.options.PingInterval <= 0 {
+		c.options.PingInterval = 25 * time.Second
+	}
+	if c.options.PongWait <= 0 {
+		c.options.PongWait = 60 * time.Second
+	}
+}
+
+func (c *Client) connect() error {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return err
+	}
+	query := u.Query()
+	query.Set("namespace", c.options.Namespace)
+	c.mu.RLock()
+	if c.session != "" {
+		query.Set("session", c.session)
+	}
+	query.Set(chunkingParam, "1")
+	c.mu.RUnlock()
+	u.RawQuery = query.Encode()
+
+	conn, err := c.dial(u)
+	if err != nil {
+		return err
+	}
+
+	if c.options.Auth != nil {
+		err = c.writeFrameTo(conn, map[string]interface{}{
+			"event": "#auth",
+			"data":  c.options.Auth,
+		})
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	r := &reader{}
+	handshake, err := c.readHandshake(conn, r)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		conn.Close()
+		return ErrClosed
+	}
+	c.conn = conn
+	c.id, _ = handshake["clientId"].(string)
+	c.session, _ = handshake["session"].(string)
+	c.resumed, _ = handshake["resumed"].(bool)
+	connected := c.connectedHandler
+	c.mu.Unlock()
+
+	conn.SetReadDeadline(time.Now().Add(c.options.PongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(c.options.PongWait))
+	})
+
+	stop := make(chan struct{})
+	go c.pingLoop(conn, stop)
+	go c.readLoop(conn, r, stop)
+
+	if connected != nil {
+		connected(c)
+	}
+	return nil
+}
+
+// connection is what the client talks to the server over, a websocket or a
+// raw connection.
+type connection interface {
+	ReadMessage() (messageType int, data []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetPongHandler(h func(appData string) error)
+	Close() error
+}
+
+func (c *Client) dial(u *url.URL) (connection, error) {
+	if u.Scheme == rawScheme || u.Scheme == unixScheme {
+		return c.dialRaw(u)
+	}
+
+	conn, _, err := c.options.Dialer.Dial(u.String(), c.options.Header)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// readHandshake waits for the handshake frame. Events the server emits from its
+// connected handler arrive before it and are dispatched as usual.
+func (c *Client) readHandshake(conn connection, r *reader) (map[string]interface{}, error) {
+	conn.SetReadDeadline(time.Now().Add(c.options.PongWait))
+	defer conn.SetReadDeadline(time.Time{})
+
+	for {
+		messageType, raw, err := conn.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		if r.attaching != nil || c.isBinary(messageType) {
+			c.handleMessage(r, messageType, raw)
+			continue
+		}
+
+		frame, err := c.decodeFrame(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		data, _ := frame["data"].(map[string]interface{})
+
+		switch frame["event"] {
+		case "#handshake":
+			return data, nil
+		case "#error":
+			code, _ := data["code"].(string)
+			message, _ := data["message"].(string)
+			return nil, &HandshakeError{Code: code, Message: message}
+		default:
+			c.handleFrame(r, frame)
+		}
+	}
+}
+
+func (c *Client) pingLoop(conn connection, stop chan struct{}) {
+	ticker := time.NewTicker(c.options.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := conn.WriteControl(ws.PingMessage, nil, time.Now().Add(c.options.PongWait))
+			if err != nil {
+				conn.Close()
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// reader holds what a connection collects across messages.
+type reader struct {
+	// the frame whose attachments are being collected
+	attaching   map[string]interface{}
+	attachments [][]byte
+	chunked     map[uint32]*chunkedMessage
+}
+
+func (c *Client) readLoop(conn connection, r *reader, stop chan struct{}) {
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			close(stop)
+			c.handleDisconnect(conn, err)
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(c.options.PongWait))
+
+		c.handleMessage(r, messageType, data)
+	}
+}
+
+// isBinary reports whether the message is a binary event or attachment rather
+// than a frame of the codec.
+func (c *Client) isBinary(messageType int) bool {
+	return messageType == ws.BinaryMessage && c.options.Codec.MessageType() != ws.BinaryMessage
+}
+
+func (c *Client) handleMessage(r *reader, messageType int, data []byte) {
+	binary := c.isBinary(messageType)
+	if r.attaching != nil && binary {
+		r.attachments = append(r.attachments, data)
+		if len(r.attachments) == attachmentCount(r.attaching) {
+			r.attaching["data"] = resolvePlaceholders(r.attaching["data"], r.attachments)
+			c.dispatch(r.attaching)
+			r.attaching, r.attachments = nil, nil
+		}
+		return
+	}
+	r.attaching, r.attachments = nil, nil
+
+	if binary {
+		if err := c.handleBinary(r, data); err != nil {
+			c.reportError(err)
+		}
+		return
+	}
+
+	frame, err := c.decodeFrame(data)
+	if err != nil {
+		c.reportError(err)
+		return
+	}
+	c.handleFrame(r, frame)
+}
+
+func (c *Client) handleFrame(r *reader, frame map[string]interface{}) {
+	if isBinary, _ := frame["binary"].(bool); isBinary && (frame["event"] == chunkEvent || frame["event"] == streamEvent) {
+		payload, _ := frame["data"].([]byte)
+		var err error
+		if frame["event"] == chunkEvent {
+			err = c.addChunk(r, payload)
+		} else {
+			err = c.handleStream(payload)
+		}
+		if err != nil {
+			c.reportError(err)
+		}
+		return
+	}
+
+	if attachmentCount(frame) > 0 {
+		r.attaching = frame
+		return
+	}
+	if inline := inlineAttachments(frame); len(inline) > 0 {
+		frame["data"] = resolvePlaceholders(frame["data"], inline)
+	}
+	c.dispatch(frame)
+}
+
+func (c *Client) dispatch(frame map[string]interface{}) {
+	eventName, _ := frame["event"].(string)
+
+	if binary, _ := frame["binary"].(bool); binary {
+		payload, _ := frame["data"].([]byte)
+		c.dispatchBinaryEvent(eventName, payload)
+		return
+	}
+
+	data, _ := frame["data"].(map[string]interface{})
+
+	if eventName == "#error" {
+		code, _ := data["code"].(string)
+		message, _ := data["message"].(string)
+		c.reportError(&ServerError{Code: code, Message: message})
+		return
+	}
+	if eventName == replyEvent {
+		c.resolveReply(data)
+		return
+	}
+
+	c.mu.Lock()
+	ack, isAck := c.acks[eventName]
+	if isAck {
+		delete(c.acks, eventName)
+	}
+	listener, ok := c.events[eventName]
+	anyListener := c.anyListener
+	c.mu.Unlock()
+
+	if isAck {
+		ack <- data
+		return
+	}
+
+	if anyListener != nil {
+		anyListener(c, eventName, data)
+	}
+
+	callId, _ := frame["callId"].(string)
+	if !ok {
+		if callId != "" {
+			c.reply(callId, &RPCError{Code: CodeNotFound, Message: "no method " + strconv.Quote(eventName)})
+		}
+		return
+	}
+
+	result := listener(c, data)
+
+	// the server asks for an acknowledgement carrying the listener's result
+	if callId != "" {
+		c.reply(callId, result)
+	} else if ackId, _ := frame["ackId"].(string); ackId != "" {
+		c.Emit(eventName+"@ack:"+ackId, ackResponse(result))
+	}
+}
+
+// ackResponse acknowledges with the listener's result or, for an error, its
+// message.
+func ackResponse(result interface{}) map[string]interface{} {
+	if err, ok := result.(error); ok {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	return map[string]interface{}{
+		"result": result,
+	}
+}
+
+func (c *Client) reportError(err error) {
+	c.mu.RLock()
+	handler := c.errorHandler
+	c.mu.RUnlock()
+
+	if handler != nil {
+		handler(c, err)
+	}
+}
+
+func (c *Client) handleDisconnect(conn connection, err error) {
+	conn.Close()
+
+	c.mu.Lock()
+	if c.conn == conn {
+		c.conn = nil
+		c.id = ""
+	}
+	closed := c.closed
+	disconnected := c.disconnectedHandler
+	c.mu.Unlock()
+
+	c.closeStreams(ErrNotConnected)
+
+	if disconnected != nil {
+		disconnected(c, err)
+	}
+
+	if !closed && !c.options.DisableReconnect {
+		go c.reconnect()
+	}
+}
+
+func (c *Client) reconnect() {
+	delay := c.options.ReconnectDelay
+
+	for {
+		// jitter spreads reconnecting clients after a server restart
+		jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+
+		select {
+		case <-time.After(delay + jitter):
+		case <-c.done:
+			return
+		}
+
+		err := c.connect()
+		if err == nil || errors.Is(err, ErrClosed) {
+			return
+		}
+		c.reportError(err)
+
+		delay *= 2
+		if delay > c.options.MaxReconnectDelay {
+			delay = c.options.MaxReconnectDelay
+		}
+	}
+}
+
+func (c *Client) write(frame map[string]interface{}) error {
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	data, err := c.options.Codec.Marshal(frame)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.writeMessage(conn, c.options.Codec.MessageType(), data)
+}
+
+// Id returns the id the server assigned to the current connection or an empty
+// string while disconnected.
+func (c *Client) Id() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.id
+}
+
+// Resumed reports whether the current connection resumed the session of the
+// previous one, so the events emitted in between got replayed.
+func (c *Client) Resumed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.resumed
+}
+
+func (c *Client) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.conn != nil
+}
+
+func (c *Client) Emit(eventName string, data interface{}) error {
+	return c.write(map[string]interface{}{
+		"event": eventName,
+		"data":  data,
+	})
+}
+
+// EmitArgs emits a multi-argument event, received by OnArgs listeners on the
+// server.
+func (c *Client) EmitArgs(eventName string, args ...interface{}) error {
+	return c.write(map[string]interface{}{
+		"event": eventName,
+		"data":  args,
+		"args":  true,
+	})
+}
+
+// EmitWithAck emits the event and waits for the server's acknowledgement. Listeners
+// run on the read loop, so calling it from inside a listener would block forever.
+func (c *Client) EmitWithAck(eventName string, data interface{}, timeout time.Duration) (interface{}, error) {
+	ackId := strconv.FormatUint(rand.Uint64(), 36)
+	ackEvent := eventName + "@ack:" + ackId
+	ack := make(chan map[string]interface{}, 1)
+
+	c.mu.Lock()
+	c.acks[ackEvent] = ack
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.acks, ackEvent)
+		c.mu.Unlock()
+	}()
+
+	err := c.write(map[string]interface{}{
+		"event": eventName,
+		"data":  data,
+		"ackId": ackId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case response := <-ack:
+		if message, ok := response["error"].(string); ok {
+			return nil, newAckError(message, response)
+		}
+		return response["result"], nil
+	case <-timer.C:
+		return nil, ErrAckTimeout
+	case <-c.done:
+		return nil, ErrClosed
+	}
+}
+
+func (c *Client) On(eventName string, listener EventListener) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.events[eventName] = listener
+}
+
+func (c *Client) Once(eventName string, listener EventListener) {
+	c.On(eventName, func(client *Client, data map[string]interface{}) interface{} {
+		client.Off(eventName)
+		return listener(client, data)
+	})
+}
+
+func (c *Client) Off(eventName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.events, eventName)
+}
+
+func (c *Client) OnAny(listener AnyListener) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.anyListener = listener
+}
+
+func (c *Client) OffAny() {
+	c.OnAny(nil)
+}
+
+// OnConnected gets called after every completed handshake, including reconnects.
+func (c *Client) OnConnected(listener func(client *Client)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.connectedHandler = listener
+}
+
+func (c *Client) OnDisconnected(listener func(client *Client, err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.disconnectedHandler = listener
+}
+
+func (c *Client) OnError(listener func(client *Client, err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.errorHandler = listener
+}
+
+// Close disconnects from the server and stops reconnecting.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	c.writeMu.Lock()
+	conn.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(ws.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	c.writeMu.Unlock()
+
+	return conn.Close()
+}
@@ -0,0 +1,44 @@
+package igoclient
+
+import (
+	"github.com/goccy/go-json"
+	ws "github.com/gorilla/websocket"
+)
+
+// Codec mirrors the server's codec interface, so any server codec can be used
+// by the client as well. Both sides have to agree on the codec.
+type Codec interface {
+	MessageType() int
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) MessageType() int {
+	return ws.TextMessage
+}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (c *Client) writeFrameTo(conn connection, frame map[string]interface{}) error {
+	data, err := c.options.Codec.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(c.options.Codec.MessageType(), data)
+}
+
+func (c *Client) decodeFrame(data []byte) (map[string]interface{}, error) {
+	frame := make(map[string]interface{})
+	if err := c.options.Codec.Unmarshal(data, &frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
@@ -0,0 +1,251 @@
+package igoclient
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	fileStream       = "#file"
+	maxFileHeader    = 4 << 10
+	fileBufferSize   = 32 << 10
+	partialExtension = ".part"
+)
+
+const (
+	fileVerified byte = iota
+	fileCorrupted
+)
+
+var (
+	ErrChecksumMismatch = errors.New("igoclient: file checksum mismatch")
+	ErrFileRejected     = errors.New("igoclient: file rejected")
+)
+
+// FileProgress reports how far a file transfer got. Bytes includes what a
+// resumed transfer skipped.
+type FileProgress struct {
+	Name     string
+	Size     int64
+	Bytes    int64
+	Outbound bool
+}
+
+type fileHeader struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"sha256"`
+}
+
+// SendFile sends the file at path to the server, which accepts it with
+// ReceiveFile. It blocks until the server verified the checksum.
+func (c *Client) SendFile(path string) error {
+	stream, err := c.OpenStream(fileStream)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	return sendFile(stream, path, c.reportFileProgress)
+}
+
+// ReceiveFile stores the files the server sends into dir. Partial files are
+// kept with a ".part" extension until their checksum got verified, so the
+// server sending the file again resumes the transfer.
+func (c *Client) ReceiveFile(dir string, listener func(client *Client, path string, err error)) {
+	c.OnStream(fileStream, func(client *Client, stream *Stream) {
+		defer stream.Close()
+
+		path, err := receiveFile(stream, dir, client.reportFileProgress)
+		if listener != nil {
+			listener(client, path, err)
+		}
+	})
+}
+
+// OnFileProgress gets called whenever a file sent to or by the server got
+// further.
+func (c *Client) OnFileProgress(listener func(client *Client, progress FileProgress)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fileHandler = listener
+}
+
+func (c *Client) reportFileProgress(progress FileProgress) {
+	c.mu.RLock()
+	handler := c.fileHandler
+	c.mu.RUnlock()
+
+	if handler != nil {
+		handler(c, progress)
+	}
+}
+
+func sendFile(stream io.ReadWriter, path string, progress func(progress FileProgress)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+
+	header, err := json.Marshal(fileHeader{
+		Name:     filepath.Base(path),
+		Size:     info.Size(),
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+	})
+	if err != nil {
+		return err
+	}
+	message := make([]byte, 4+len(header))
+	binary.BigEndian.PutUint32(message, uint32(len(header)))
+	copy(message[4:], header)
+	if _, err := stream.Write(message); err != nil {
+		return err
+	}
+
+	var answer [8]byte
+	if _, err := io.ReadFull(stream, answer[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return ErrFileRejected
+		}
+		return err
+	}
+	offset := int64(binary.BigEndian.Uint64(answer[:]))
+	if offset > info.Size() {
+		return fmt.Errorf("%w: offset %d beyond size %d", ErrFileRejected, offset, info.Size())
+	}
+
+	buffer := make([]byte, fileBufferSize)
+	for sent := offset; sent < info.Size(); {
+		n, err := file.ReadAt(buffer[:min(int64(len(buffer)), info.Size()-sent)], sent)
+		if n == 0 && err != nil {
+			return err
+		}
+		if _, err := stream.Write(buffer[:n]); err != nil {
+			return err
+		}
+		sent += int64(n)
+		progress(FileProgress{Name: filepath.Base(path), Size: info.Size(), Bytes: sent, Outbound: true})
+	}
+
+	var status [1]byte
+	if _, err := io.ReadFull(stream, status[:]); err != nil {
+		// the receiver verified the file, but could not keep it
+		if errors.Is(err, io.EOF) {
+			return ErrFileRejected
+		}
+		return err
+	}
+	if status[0] != fileVerified {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+func receiveFile(stream io.ReadWriter, dir string, progress func(progress FileProgress)) (string, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(stream, length[:]); err != nil {
+		return "", err
+	}
+	size := binary.BigEndian.Uint32(length[:])
+	if size > maxFileHeader {
+		return "", fmt.Errorf("%w: header of %d bytes", ErrFileRejected, size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(stream, data); err != nil {
+		return "", err
+	}
+	var header fileHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrFileRejected, err)
+	}
+
+	// the name is the sender's, it must not point outside of dir
+	name := filepath.Base(header.Name)
+	if name != header.Name || name == "." || name == ".." || header.Size < 0 {
+		return "", fmt.Errorf("%w: %q of %d bytes", ErrFileRejected, header.Name, header.Size)
+	}
+	path := filepath.Join(dir, name)
+
+	file, err := os.OpenFile(path+partialExtension, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	offset := info.Size()
+	if offset > header.Size {
+		if err := file.Truncate(0); err != nil {
+			return "", err
+		}
+		offset = 0
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, offset)); err != nil {
+		return "", err
+	}
+
+	var answer [8]byte
+	binary.BigEndian.PutUint64(answer[:], uint64(offset))
+	if _, err := stream.Write(answer[:]); err != nil {
+		return "", err
+	}
+
+	buffer := make([]byte, fileBufferSize)
+	for received := offset; received < header.Size; {
+		n, err := stream.Read(buffer[:min(int64(len(buffer)), header.Size-received)])
+		if n > 0 {
+			if _, err := file.WriteAt(buffer[:n], received); err != nil {
+				return "", err
+			}
+			hash.Write(buffer[:n])
+			received += int64(n)
+			progress(FileProgress{Name: name, Size: header.Size, Bytes: received})
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != header.Checksum {
+		file.Close()
+		os.Remove(path + partialExtension)
+		stream.Write([]byte{fileCorrupted})
+		return "", ErrChecksumMismatch
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(path+partialExtension, path); err != nil {
+		return "", err
+	}
+	if _, err := stream.Write([]byte{fileVerified}); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,154 @@
+package igoclient
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// Urls with the tcp scheme, like "tcp://host:port", or the unix scheme, like
+// "unix:///run/app.sock", connect over the raw transport of the server instead
+// of a websocket: every message is its websocket message type as a byte, its
+// length as a big endian uint32 and the payload, and the first one carries the
+// query string.
+
+const (
+	rawScheme         = "tcp"
+	unixScheme        = "unix"
+	rawHeaderSize     = 5
+	maxControlPayload = 125
+)
+
+// DialUnix connects to a server listening on the Unix domain socket at the
+// path and blocks until the handshake completed.
+func DialUnix(path string, options *Options) (*Client, error) {
+	u := url.URL{Scheme: unixScheme, Opaque: path}
+	return Dial(u.String(), options)
+}
+
+func (c *Client) dialRaw(u *url.URL) (connection, error) {
+	network, address := "tcp", u.Host
+	if u.Scheme == unixScheme {
+		network, address = "unix", u.Path
+		// relative paths, like "unix:app.sock"
+		if u.Opaque != "" {
+			address = u.Opaque
+		}
+	}
+
+	dialer := net.Dialer{Timeout: c.options.Dialer.HandshakeTimeout}
+	netConn, err := dialer.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := newRawConn(netConn)
+	if err := conn.WriteMessage(ws.TextMessage, []byte(u.RawQuery)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// rawConn frames messages on a stream connection the way the raw transport
+// of the server expects them.
+type rawConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+	writer  *bufio.Writer
+	pong    func(appData string) error
+}
+
+func newRawConn(conn net.Conn) *rawConn {
+	return &rawConn{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		writer: bufio.NewWriter(conn),
+	}
+}
+
+func (c *rawConn) ReadMessage() (int, []byte, error) {
+	for {
+		var header [rawHeaderSize]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			return 0, nil, err
+		}
+		messageType := int(header[0])
+		length := binary.BigEndian.Uint32(header[1:])
+
+		switch messageType {
+		case ws.TextMessage, ws.BinaryMessage:
+		case ws.PingMessage, ws.PongMessage, ws.CloseMessage:
+			if length > maxControlPayload {
+				return 0, nil, fmt.Errorf("igoclient: control message of %d bytes", length)
+			}
+		default:
+			return 0, nil, fmt.Errorf("igoclient: unknown message type %d", messageType)
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return 0, nil, err
+		}
+
+		switch messageType {
+		case ws.PingMessage:
+			c.WriteControl(ws.PongMessage, data, time.Now().Add(time.Second))
+		case ws.PongMessage:
+			if c.pong != nil {
+				if err := c.pong(string(data)); err != nil {
+					return 0, nil, err
+				}
+			}
+		case ws.CloseMessage:
+			closeErr := &ws.CloseError{Code: ws.CloseNoStatusReceived}
+			if len(data) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(data))
+				closeErr.Text = string(data[2:])
+			}
+			c.WriteControl(ws.CloseMessage, data, time.Now().Add(time.Second))
+			return 0, nil, closeErr
+		default:
+			return messageType, data, nil
+		}
+	}
+}
+
+func (c *rawConn) WriteMessage(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	var header [rawHeaderSize]byte
+	header[0] = byte(messageType)
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+	c.writer.Write(header[:])
+	c.writer.Write(data)
+	return c.writer.Flush()
+}
+
+func (c *rawConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	c.conn.SetWriteDeadline(deadline)
+	defer c.conn.SetWriteDeadline(time.Time{})
+
+	return c.WriteMessage(messageType, data)
+}
+
+func (c *rawConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *rawConn) SetPongHandler(h func(appData string) error) {
+	c.pong = h
+}
+
+func (c *rawConn) Close() error {
+	return c.conn.Close()
+}
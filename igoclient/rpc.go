@@ -0,0 +1,143 @@
+package igoclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	replyEvent         = "#reply"
+	defaultCallTimeout = 30 * time.Second
+)
+
+// Codes of the RPCError a call fails with.
+const (
+	CodeNotFound        = "not_found"
+	CodeInvalidArgument = "invalid_argument"
+	CodeInternal        = "internal"
+)
+
+// RPCError is the error a call fails with once the server answered it with
+// one. Listeners return it to answer a call of the server with a code of
+// their own, other errors are answered with CodeInternal.
+type RPCError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return "igoclient: call failed (" + e.Code + "): " + e.Message
+}
+
+// fields returns the error as a map, binary codecs not knowing the json tags.
+func (e *RPCError) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"code":    e.Code,
+		"message": e.Message,
+	}
+	if e.Data != nil {
+		fields["data"] = e.Data
+	}
+	return fields
+}
+
+func (c *Client) reply(callId string, result interface{}) {
+	data := map[string]interface{}{
+		"id": callId,
+	}
+	if err, ok := result.(error); ok {
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &RPCError{Code: CodeInternal, Message: err.Error()}
+		}
+		data["error"] = rpcErr.fields()
+	} else {
+		data["result"] = result
+	}
+	c.Emit(replyEvent, data)
+}
+
+// Call calls the method, a listener of the server, with req and decodes its
+// result into resp, which may be nil. It fails with an RPCError once the
+// server answered with an error and gives up once ctx is done, after 30
+// seconds for contexts without a deadline.
+func (c *Client) Call(ctx context.Context, method string, req interface{}, resp interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultCallTimeout)
+		defer cancel()
+	}
+
+	callId := strconv.FormatUint(rand.Uint64(), 36)
+	reply := make(chan map[string]interface{}, 1)
+
+	c.mu.Lock()
+	if c.calls == nil {
+		c.calls = make(map[string]chan map[string]interface{})
+	}
+	c.calls[callId] = reply
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, callId)
+		c.mu.Unlock()
+	}()
+
+	err := c.write(map[string]interface{}{
+		"event":  method,
+		"data":   req,
+		"callId": callId,
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case answer := <-reply:
+		if rpcErr, ok := answer["error"].(map[string]interface{}); ok {
+			code, _ := rpcErr["code"].(string)
+			message, _ := rpcErr["message"].(string)
+			return &RPCError{Code: code, Message: message, Data: rpcErr["data"]}
+		}
+		if resp == nil || answer["result"] == nil {
+			return nil
+		}
+		// the result went through the codec already, it is encoded once more
+		// to decode it into resp
+		data, err := json.Marshal(answer["result"])
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, resp); err != nil {
+			return fmt.Errorf("igoclient: decoding result: %w", err)
+		}
+		return nil
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) resolveReply(data map[string]interface{}) {
+	callId, _ := data["id"].(string)
+
+	c.mu.Lock()
+	reply, ok := c.calls[callId]
+	if ok {
+		delete(c.calls, callId)
+	}
+	c.mu.Unlock()
+
+	if ok {
+		reply <- data
+	}
+}
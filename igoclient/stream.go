@@ -0,0 +1,315 @@
+package igoclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	streamEvent      = "#stream"
+	streamHeaderSize = 5
+	// streamWindow is the number of bytes a side may send before the other
+	// side read them.
+	streamWindow    = 256 << 10
+	streamFrameSize = 16 << 10
+	maxStreams      = 64
+)
+
+const (
+	streamOpen byte = iota
+	streamData
+	streamWindowUpdate
+	streamClose
+)
+
+var (
+	ErrStreamClosed   = errors.New("igoclient: stream closed")
+	ErrTooManyStreams = errors.New("igoclient: too many streams")
+)
+
+// Stream is a byte stream multiplexed over the connection to the server.
+// Writes block once the server has a window worth of bytes left to read, reads
+// return io.EOF after the server closed the stream. Streams end with the
+// connection.
+type Stream struct {
+	Name         string
+	id           uint32
+	client       *Client
+	mu           sync.Mutex
+	cond         *sync.Cond
+	buffer       bytes.Buffer
+	credit       int
+	consumed     int
+	closed       bool
+	remoteClosed bool
+	err          error
+}
+
+func newStream(client *Client, id uint32, name string) *Stream {
+	stream := &Stream{
+		Name:   name,
+		id:     id,
+		client: client,
+		credit: streamWindow,
+	}
+	stream.cond = sync.NewCond(&stream.mu)
+	return stream
+}
+
+// OpenStream opens a stream to the server, which accepts it with OnStream.
+func (c *Client) OpenStream(name string) (*Stream, error) {
+	c.streamsMu.Lock()
+	if len(c.streams) >= maxStreams {
+		c.streamsMu.Unlock()
+		return nil, ErrTooManyStreams
+	}
+	c.streamSeq++
+	stream := newStream(c, c.streamSeq*2, name)
+	c.addStream(stream)
+	c.streamsMu.Unlock()
+
+	if err := c.sendStream(stream.id, streamOpen, []byte(name)); err != nil {
+		c.removeStream(stream)
+		return nil, err
+	}
+	return stream, nil
+}
+
+// OnStream accepts the streams named name the server opens. The listener runs
+// on its own goroutine, streams without one get closed right away.
+func (c *Client) OnStream(name string, listener func(client *Client, stream *Stream)) {
+	c.streamsMu.Lock()
+	defer c.streamsMu.Unlock()
+
+	if c.streamListeners == nil {
+		c.streamListeners = make(map[string]func(client *Client, stream *Stream))
+	}
+	c.streamListeners[name] = listener
+}
+
+func (s *Stream) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	for s.buffer.Len() == 0 && !s.closed && !s.remoteClosed {
+		s.cond.Wait()
+	}
+
+	switch {
+	case s.closed:
+		s.mu.Unlock()
+		return 0, ErrStreamClosed
+	case s.buffer.Len() == 0:
+		err := s.err
+		s.mu.Unlock()
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, err
+	}
+
+	n, _ := s.buffer.Read(p)
+	s.consumed += n
+	grant := 0
+	if s.consumed >= streamWindow/2 && !s.remoteClosed {
+		grant, s.consumed = s.consumed, 0
+	}
+	s.mu.Unlock()
+
+	if grant > 0 {
+		update := make([]byte, 4)
+		binary.BigEndian.PutUint32(update, uint32(grant))
+		s.client.sendStream(s.id, streamWindowUpdate, update)
+	}
+	return n, nil
+}
+
+func (s *Stream) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		s.mu.Lock()
+		for s.credit == 0 && !s.closed && !s.remoteClosed {
+			s.cond.Wait()
+		}
+		if s.closed || s.remoteClosed {
+			err := s.err
+			s.mu.Unlock()
+			if err == nil {
+				err = ErrStreamClosed
+			}
+			return written, err
+		}
+		n := min(len(p), s.credit, streamFrameSize)
+		s.credit -= n
+		s.mu.Unlock()
+
+		if err := s.client.sendStream(s.id, streamData, p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Close closes the stream in both directions, discarding what was not read
+// yet.
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	remoteClosed := s.remoteClosed
+	s.buffer.Reset()
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	s.client.removeStream(s)
+	if remoteClosed {
+		return nil
+	}
+	return s.client.sendStream(s.id, streamClose, nil)
+}
+
+func (s *Stream) receive(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	if s.buffer.Len()+len(data) > streamWindow {
+		return fmt.Errorf("igoclient: stream %d exceeded its window", s.id)
+	}
+	s.buffer.Write(data)
+	s.cond.Broadcast()
+	return nil
+}
+
+func (s *Stream) grant(credit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.credit = min(s.credit+credit, streamWindow)
+	s.cond.Broadcast()
+}
+
+func (s *Stream) closeRemote(err error) {
+	s.mu.Lock()
+	if s.remoteClosed {
+		s.mu.Unlock()
+		return
+	}
+	s.remoteClosed = true
+	s.err = err
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	s.client.removeStream(s)
+}
+
+// addStream registers the stream, the caller holds streamsMu.
+func (c *Client) addStream(stream *Stream) {
+	if c.streams == nil {
+		c.streams = make(map[uint32]*Stream)
+	}
+	c.streams[stream.id] = stream
+}
+
+func (c *Client) removeStream(stream *Stream) {
+	c.streamsMu.Lock()
+	defer c.streamsMu.Unlock()
+
+	if c.streams[stream.id] == stream {
+		delete(c.streams, stream.id)
+	}
+}
+
+func (c *Client) closeStreams(err error) {
+	c.streamsMu.Lock()
+	streams := make([]*Stream, 0, len(c.streams))
+	for _, stream := range c.streams {
+		streams = append(streams, stream)
+	}
+	c.streamsMu.Unlock()
+
+	for _, stream := range streams {
+		stream.closeRemote(err)
+	}
+}
+
+func (c *Client) sendStream(id uint32, kind byte, data []byte) error {
+	payload := make([]byte, streamHeaderSize+len(data))
+	binary.BigEndian.PutUint32(payload, id)
+	payload[4] = kind
+	copy(payload[streamHeaderSize:], data)
+	// stream frames are no larger than streamFrameSize, the server does not
+	// take them in chunks
+	return c.emitBinary(streamEvent, payload, false)
+}
+
+func (c *Client) handleStream(payload []byte) error {
+	if len(payload) < streamHeaderSize {
+		return errors.New("igoclient: stream header too short")
+	}
+	id := binary.BigEndian.Uint32(payload)
+	kind := payload[4]
+	data := payload[streamHeaderSize:]
+
+	if kind == streamOpen {
+		return c.acceptStream(id, string(data))
+	}
+
+	c.streamsMu.Lock()
+	stream := c.streams[id]
+	c.streamsMu.Unlock()
+
+	if stream == nil {
+		return nil
+	}
+
+	switch kind {
+	case streamData:
+		if err := stream.receive(data); err != nil {
+			stream.Close()
+			return err
+		}
+	case streamWindowUpdate:
+		if len(data) < 4 {
+			return fmt.Errorf("igoclient: window update of stream %d too short", id)
+		}
+		stream.grant(int(binary.BigEndian.Uint32(data)))
+	case streamClose:
+		stream.closeRemote(nil)
+	default:
+		return fmt.Errorf("igoclient: unknown message %d on stream %d", kind, id)
+	}
+	return nil
+}
+
+func (c *Client) acceptStream(id uint32, name string) error {
+	if id%2 == 0 {
+		return fmt.Errorf("igoclient: stream id %d is even", id)
+	}
+
+	c.streamsMu.Lock()
+	if _, exists := c.streams[id]; exists {
+		c.streamsMu.Unlock()
+		return fmt.Errorf("igoclient: stream %d opened twice", id)
+	}
+	listener := c.streamListeners[name]
+	if listener == nil || len(c.streams) >= maxStreams {
+		c.streamsMu.Unlock()
+		return c.sendStream(id, streamClose, nil)
+	}
+	stream := newStream(c, id, name)
+	c.addStream(stream)
+	c.streamsMu.Unlock()
+
+	go listener(c, stream)
+	return nil
+}
@@ -0,0 +1,30 @@
+package igoclient
+
+import "time"
+
+// MessageListener receives the messages published to a topic the client is
+// subscribed to.
+type MessageListener func(client *Client, topic string, data interface{})
+
+// Subscribe subscribes to the topics matching the MQTT style filter, e.g.
+// "sensors/+/temp", and waits for the server to accept it. Subscriptions
+// survive resumed sessions only, so subscribe again from OnConnected.
+func (c *Client) Subscribe(filter string, timeout time.Duration) error {
+	_, err := c.EmitWithAck("#subscribe", map[string]interface{}{"topic": filter}, timeout)
+	return err
+}
+
+func (c *Client) Unsubscribe(filter string, timeout time.Duration) error {
+	_, err := c.EmitWithAck("#unsubscribe", map[string]interface{}{"topic": filter}, timeout)
+	return err
+}
+
+// OnMessage sets the listener of the published messages. It replaces any
+// listener added with On for "#message".
+func (c *Client) OnMessage(listener MessageListener) {
+	c.On("#message", func(client *Client, data map[string]interface{}) interface{} {
+		topic, _ := data["topic"].(string)
+		listener(client, topic, data["data"])
+		return nil
+	})
+}
@@ -0,0 +1,92 @@
+package socketigo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+var ErrAddressDenied = errors.New("socketigo: address denied")
+
+type IPFilterOptions struct {
+	// Allow lists the CIDRs or single addresses connections are accepted from.
+	// An empty list allows every address not denied.
+	Allow []string
+	// Deny lists the CIDRs or single addresses rejected, taking precedence over
+	// Allow.
+	Deny []string
+	// ClientIP extracts the address to check. Defaults to RemoteIP.
+	ClientIP func(r *http.Request) string
+}
+
+// IPFilter returns a request middleware rejecting connections by their peer
+// address before they get upgraded. Install it with UseRequest.
+func IPFilter(options *IPFilterOptions) (RequestMiddleware, error) {
+	if options == nil {
+		options = &IPFilterOptions{}
+	}
+
+	allow, err := parsePrefixes(options.Allow)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := parsePrefixes(options.Deny)
+	if err != nil {
+		return nil, err
+	}
+
+	clientIP := options.ClientIP
+	if clientIP == nil {
+		clientIP = RemoteIP
+	}
+
+	return func(r *http.Request) error {
+		ip := clientIP(r)
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return fmt.Errorf("%w: invalid address %q", ErrAddressDenied, ip)
+		}
+		addr = addr.Unmap()
+
+		if containsAddr(deny, addr) {
+			return fmt.Errorf("%w: %s", ErrAddressDenied, addr)
+		}
+		if len(allow) > 0 && !containsAddr(allow, addr) {
+			return fmt.Errorf("%w: %s is not allowed", ErrAddressDenied, addr)
+		}
+		return nil
+	}, nil
+}
+
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("socketigo: invalid address %q: %w", value, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("socketigo: invalid CIDR %q: %w", value, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
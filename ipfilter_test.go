@@ -0,0 +1,74 @@
+package socketigo_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/igoclient"
+)
+
+func TestIPFilter(t *testing.T) {
+	filter, err := socketigo.IPFilter(&socketigo.IPFilterOptions{
+		Allow: []string{"192.0.2.0/24", "2001:db8::/32", "198.51.100.7"},
+		Deny:  []string{"192.0.2.66"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		remoteAddr string
+		allowed    bool
+	}{
+		{"192.0.2.1:1234", true},
+		{"198.51.100.7:1234", true},
+		{"[2001:db8::1]:1234", true},
+		// IPv4 mapped into IPv6 is matched as IPv4
+		{"[::ffff:192.0.2.1]:1234", true},
+		// Deny takes precedence over Allow
+		{"192.0.2.66:1234", false},
+		{"198.51.100.8:1234", false},
+		{"203.0.113.1:1234", false},
+		{"pipe", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = test.remoteAddr
+		err := filter(r)
+		if test.allowed && err != nil {
+			t.Errorf("%s got denied: %v", test.remoteAddr, err)
+		}
+		if !test.allowed && !errors.Is(err, socketigo.ErrAddressDenied) {
+			t.Errorf("%s: error is %v, want ErrAddressDenied", test.remoteAddr, err)
+		}
+	}
+}
+
+func TestIPFilterInvalidOptions(t *testing.T) {
+	if _, err := socketigo.IPFilter(&socketigo.IPFilterOptions{Allow: []string{"192.0.2.0/33"}}); err == nil {
+		t.Error("invalid CIDR got accepted")
+	}
+	if _, err := socketigo.IPFilter(&socketigo.IPFilterOptions{Deny: []string{"not an address"}}); err == nil {
+		t.Error("invalid address got accepted")
+	}
+}
+
+func TestIPFilterRejectsUpgrade(t *testing.T) {
+	server := echoServer(nil)
+	filter, err := socketigo.IPFilter(&socketigo.IPFilterOptions{Deny: []string{"127.0.0.0/8", "::1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.UseRequest(filter)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	if client, err := igoclient.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), &igoclient.Options{DisableReconnect: true}); err == nil {
+		client.Close()
+		t.Fatal("client of a denied address got connected")
+	}
+}
@@ -0,0 +1,270 @@
+package socketigo
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+var (
+	ErrTokenMissing = errors.New("jwt: token missing")
+	ErrTokenInvalid = errors.New("jwt: token invalid")
+	ErrTokenExpired = errors.New("jwt: token expired")
+)
+
+type JWTClaims map[string]interface{}
+
+type JWTOptions struct {
+	// Secret is the key for HS256, HS384 and HS512 signed tokens.
+	Secret []byte
+	// PublicKey is the *rsa.PublicKey or *ecdsa.PublicKey for RS* and ES* signed tokens.
+	PublicKey crypto.PublicKey
+	// Issuer and Audience are checked against the "iss" and "aud" claims when set.
+	Issuer   string
+	Audience string
+	// Leeway is the tolerated clock skew for "exp" and "nbf".
+	Leeway time.Duration
+	// QueryParam, Header and PayloadField name the places the token is looked up
+	// in, in that order. They default to "token", "Authorization" and "token".
+	QueryParam   string
+	Header       string
+	PayloadField string
+}
+
+// JWTMiddleware validates a JWT taken from the query string, the request header
+// or the auth payload and attaches its claims to the client.
+func JWTMiddleware(options JWTOptions) Middleware {
+	if options.QueryParam == "" {
+		options.QueryParam = "token"
+	}
+	if options.Header == "" {
+		options.Header = "Authorization"
+	}
+	if options.PayloadField == "" {
+		options.PayloadField = "token"
+	}
+
+	return func(ctx *HandshakeContext) error {
+		token := findToken(ctx, &options)
+		if token == "" {
+			return ErrTokenMissing
+		}
+
+		claims, err := ParseJWT(token, &options)
+		if err != nil {
+			return err
+		}
+
+		ctx.Client.claims = claims
+		return nil
+	}
+}
+
+// JWTAuthenticator is JWTMiddleware in the shape of an Authenticator, for servers
+// expecting the token inside the handshake payload.
+func JWTAuthenticator(options JWTOptions) Authenticator {
+	middleware := JWTMiddleware(options)
+
+	return func(ctx *HandshakeContext, payload map[string]interface{}) error {
+		return middleware(ctx)
+	}
+}
+
+func findToken(ctx *HandshakeContext, options *JWTOptions) string {
+	if ctx.Request != nil {
+		if token := ctx.Request.URL.Query().Get(options.QueryParam); token != "" {
+			return token
+		}
+
+		if header := ctx.Request.Header.Get(options.Header); header != "" {
+			if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+				return header[7:]
+			}
+			return header
+		}
+	}
+
+	if token, ok := ctx.Auth[options.PayloadField].(string); ok {
+		return token
+	}
+	return ""
+}
+
+// ParseJWT verifies the signature and the registered time, issuer and audience
+// claims of the token and returns its claims.
+func ParseJWT(token string, options *JWTOptions) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrTokenInvalid)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrTokenInvalid)
+	}
+
+	if err := verifySignature(header.Alg, parts[0]+"."+parts[1], signature, options); err != nil {
+		return nil, err
+	}
+
+	claims := make(JWTClaims)
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	if err := validateClaims(claims, options); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: malformed segment", ErrTokenInvalid)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: malformed segment", ErrTokenInvalid)
+	}
+	return nil
+}
+
+func verifySignature(alg string, signed string, signature []byte, options *JWTOptions) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrTokenInvalid, alg)
+	}
+
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrTokenInvalid, alg)
+	}
+
+	// the key type has to match the algorithm family, otherwise a public key
+	// could be abused as an HMAC secret
+	switch {
+	case strings.HasPrefix(alg, "HS") && len(options.Secret) > 0:
+		mac := hmac.New(newHash(hash), options.Secret)
+		mac.Write([]byte(signed))
+		if hmac.Equal(mac.Sum(nil), signature) {
+			return nil
+		}
+
+	case strings.HasPrefix(alg, "RS"):
+		key, ok := options.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			break
+		}
+		if rsa.VerifyPKCS1v15(key, hash, digest(hash, signed), signature) == nil {
+			return nil
+		}
+
+	case strings.HasPrefix(alg, "ES"):
+		// the curve is fixed by the algorithm and the signature is r and s
+		// padded to its size each
+		key, ok := options.PublicKey.(*ecdsa.PublicKey)
+		if !ok || key.Curve != ecdsaCurves[alg] {
+			break
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			break
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if ecdsa.Verify(key, digest(hash, signed), r, s) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: signature mismatch", ErrTokenInvalid)
+}
+
+var ecdsaCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+	"ES512": elliptic.P521(),
+}
+
+func newHash(h crypto.Hash) func() hash.Hash {
+	switch h {
+	case crypto.SHA384:
+		return sha512.New384
+	case crypto.SHA512:
+		return sha512.New
+	default:
+		return sha256.New
+	}
+}
+
+func digest(h crypto.Hash, signed string) []byte {
+	d := newHash(h)()
+	d.Write([]byte(signed))
+	return d.Sum(nil)
+}
+
+func validateClaims(claims JWTClaims, options *JWTOptions) error {
+	now := time.Now()
+
+	if exp, ok := claims["exp"].(float64); ok {
+		if now.After(time.Unix(int64(exp), 0).Add(options.Leeway)) {
+			return ErrTokenExpired
+		}
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok {
+		if now.Add(options.Leeway).Before(time.Unix(int64(nbf), 0)) {
+			return fmt.Errorf("%w: token not yet valid", ErrTokenInvalid)
+		}
+	}
+
+	if options.Issuer != "" && claims["iss"] != options.Issuer {
+		return fmt.Errorf("%w: issuer mismatch", ErrTokenInvalid)
+	}
+
+	if options.Audience != "" && !hasAudience(claims["aud"], options.Audience) {
+		return fmt.Errorf("%w: audience mismatch", ErrTokenInvalid)
+	}
+	return nil
+}
+
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package socketigo_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash"
+	"net/http"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+// signToken builds a token from the claims, signing it with sign.
+func signToken(t *testing.T, alg string, claims map[string]interface{}, sign func(signed string) []byte) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign(signed))
+}
+
+func hmacSigner(newHash func() hash.Hash, secret []byte) func(string) []byte {
+	return func(signed string) []byte {
+		mac := hmac.New(newHash, secret)
+		mac.Write([]byte(signed))
+		return mac.Sum(nil)
+	}
+}
+
+// ecdsaSigner signs with r and s padded to size bytes each, as JWS does.
+func ecdsaSigner(t *testing.T, key *ecdsa.PrivateKey, newHash func() hash.Hash, size int) func(string) []byte {
+	return func(signed string) []byte {
+		d := newHash()
+		d.Write([]byte(signed))
+		r, s, err := ecdsa.Sign(rand.Reader, key, d.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		return signature
+	}
+}
+
+func TestParseJWT(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSigner := func(signed string) []byte {
+		digest := sha256.Sum256([]byte(signed))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+
+	claims := map[string]interface{}{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+	tests := []struct {
+		name    string
+		token   string
+		options socketigo.JWTOptions
+		err     error
+	}{
+		{"HS256", signToken(t, "HS256", claims, hmacSigner(sha256.New, secret)), socketigo.JWTOptions{Secret: secret}, nil},
+		{"HS512", signToken(t, "HS512", claims, hmacSigner(sha512.New, secret)), socketigo.JWTOptions{Secret: secret}, nil},
+		{"HS256 wrong secret", signToken(t, "HS256", claims, hmacSigner(sha256.New, []byte("guess"))), socketigo.JWTOptions{Secret: secret}, socketigo.ErrTokenInvalid},
+		{"RS256", signToken(t, "RS256", claims, rsaSigner), socketigo.JWTOptions{PublicKey: &rsaKey.PublicKey}, nil},
+		{"ES256", signToken(t, "ES256", claims, ecdsaSigner(t, p256Key, sha256.New, 32)), socketigo.JWTOptions{PublicKey: &p256Key.PublicKey}, nil},
+		{"ES384", signToken(t, "ES384", claims, ecdsaSigner(t, p384Key, sha512.New384, 48)), socketigo.JWTOptions{PublicKey: &p384Key.PublicKey}, nil},
+		{"ES512", signToken(t, "ES512", claims, ecdsaSigner(t, p521Key, sha512.New, 66)), socketigo.JWTOptions{PublicKey: &p521Key.PublicKey}, nil},
+		// a P-384 key must not verify ES256 though the signature is valid for it
+		{"ES256 on P-384", signToken(t, "ES256", claims, ecdsaSigner(t, p384Key, sha256.New, 48)), socketigo.JWTOptions{PublicKey: &p384Key.PublicKey}, socketigo.ErrTokenInvalid},
+		{"ES256 short signature", signToken(t, "ES256", claims, func(signed string) []byte {
+			return ecdsaSigner(t, p256Key, sha256.New, 32)(signed)[1:63]
+		}), socketigo.JWTOptions{PublicKey: &p256Key.PublicKey}, socketigo.ErrTokenInvalid},
+		// the RSA public key must not pass for an HMAC secret
+		{"HS256 with RSA key", signToken(t, "HS256", claims, hmacSigner(sha256.New, rsaKey.PublicKey.N.Bytes())), socketigo.JWTOptions{PublicKey: &rsaKey.PublicKey}, socketigo.ErrTokenInvalid},
+		{"none", signToken(t, "none", claims, func(string) []byte { return nil }), socketigo.JWTOptions{Secret: secret}, socketigo.ErrTokenInvalid},
+		{"expired", signToken(t, "HS256", map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}, hmacSigner(sha256.New, secret)), socketigo.JWTOptions{Secret: secret}, socketigo.ErrTokenExpired},
+		{"expired within leeway", signToken(t, "HS256", map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()}, hmacSigner(sha256.New, secret)), socketigo.JWTOptions{Secret: secret, Leeway: time.Hour}, nil},
+		{"not yet valid", signToken(t, "HS256", map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()}, hmacSigner(sha256.New, secret)), socketigo.JWTOptions{Secret: secret}, socketigo.ErrTokenInvalid},
+		{"issuer", signToken(t, "HS256", map[string]interface{}{"iss": "other"}, hmacSigner(sha256.New, secret)), socketigo.JWTOptions{Secret: secret, Issuer: "socketigo"}, socketigo.ErrTokenInvalid},
+		{"audience", signToken(t, "HS256", map[string]interface{}{"aud": []string{"a", "socketigo"}}, hmacSigner(sha256.New, secret)), socketigo.JWTOptions{Secret: secret, Audience: "socketigo"}, nil},
+		{"malformed", "not.a-token", socketigo.JWTOptions{Secret: secret}, socketigo.ErrTokenInvalid},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := socketigo.ParseJWT(test.token, &test.options)
+			if test.err == nil && err != nil {
+				t.Fatalf("token got rejected: %v", err)
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Fatalf("error is %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	server := echoServer(nil)
+	server.Use(socketigo.JWTMiddleware(socketigo.JWTOptions{Secret: secret}))
+	subjects := make(chan interface{}, 1)
+	server.OnConnected(func(client *socketigo.Client) {
+		subjects <- client.Claims()["sub"]
+	})
+
+	token := signToken(t, "HS256", map[string]interface{}{"sub": "alice"}, hmacSigner(sha256.New, secret))
+	testclient.MustConnect(t, server, &testclient.Options{Header: http.Header{"Authorization": {"Bearer " + token}}})
+	if subject := <-subjects; subject != "alice" {
+		t.Fatalf("subject is %v, want alice", subject)
+	}
+
+	if _, err := testclient.Connect(server, nil); err == nil {
+		t.Fatal("client without a token got connected")
+	}
+	forged := signToken(t, "HS256", map[string]interface{}{"sub": "mallory"}, hmacSigner(sha256.New, []byte("guess")))
+	if _, err := testclient.Connect(server, &testclient.Options{Auth: map[string]interface{}{"token": forged}}); err == nil {
+		t.Fatal("client with a forged token got connected")
+	}
+}
@@ -0,0 +1,78 @@
+package socketigo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	ws "github.com/gorilla/websocket"
+)
+
+const messageTooLargeCode = "message_too_large"
+
+var ErrMessageTooLarge = errors.New("socketigo: message too large")
+
+// errMessageDropped is returned by readMessage once it discarded an oversized
+// message, the reader just goes on with the next one.
+var errMessageDropped = errors.New("socketigo: message dropped")
+
+// readMessage reads the next message. Without DisconnectOversized, messages
+// above MaxMessageSize are read to their end without being buffered and
+// answered with an error frame. The message is read into a pooled buffer, to
+// be released once it got decoded.
+func (c *Client) readMessage() (int, *bytes.Buffer, error) {
+	messageType, reader, err := c.socket.NextReader()
+	if err != nil {
+		return messageType, nil, err
+	}
+
+	limit := c.Server.maxMessageSize
+	source := reader
+	if limit > 0 && !c.Server.disconnectOversized {
+		source = io.LimitReader(reader, limit+1)
+	}
+
+	buffer := acquireBuffer()
+	if _, err := buffer.ReadFrom(source); err != nil {
+		releaseBuffer(buffer)
+		return messageType, nil, err
+	}
+	if limit <= 0 || c.Server.disconnectOversized || int64(buffer.Len()) <= limit {
+		return messageType, buffer, nil
+	}
+
+	size := int64(buffer.Len())
+	releaseBuffer(buffer)
+
+	discarded, err := io.Copy(io.Discard, reader)
+	if err != nil {
+		return messageType, nil, err
+	}
+
+	c.rejectOversized(size+discarded, limit)
+	return messageType, nil, errMessageDropped
+}
+
+// rejectOversized reports a dropped message and answers it with an error
+// frame, returning the error.
+func (c *Client) rejectOversized(size int64, limit int64) error {
+	err := fmt.Errorf("%w: %d bytes, limit is %d", ErrMessageTooLarge, size, limit)
+	c.Server.emitError(c, nil, nil, err)
+	c.Emit(errorEvent, map[string]interface{}{
+		"code":    messageTooLargeCode,
+		"message": err.Error(),
+	})
+	return err
+}
+
+// handleReadLimit reports a client which got disconnected for exceeding
+// MaxMessageSize with DisconnectOversized.
+func (c *Client) handleReadLimit(err error) {
+	if !errors.Is(err, ws.ErrReadLimit) {
+		return
+	}
+
+	c.setDisconnectReason(ReasonMessageTooLarge)
+	c.Server.emitError(c, nil, ErrMessageTooLarge, fmt.Errorf("limit is %d bytes", c.Server.maxMessageSize))
+}
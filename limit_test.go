@@ -0,0 +1,88 @@
+package socketigo_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/igoclient"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+func echoServer(options *socketigo.IgoServerOptions) *socketigo.IgoServer {
+	server := socketigo.CreateIgoServer(options)
+	server.On("echo", func(client *socketigo.Client, data map[string]interface{}) interface{} {
+		client.Emit("echo", data)
+		return nil
+	})
+	return server
+}
+
+func TestMaxMessageSizeDropsOversizedMessage(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{MaxMessageSize: 1 << 10})
+
+	client := testclient.MustConnect(t, server, nil)
+	serverErrors := make(chan error, 1)
+	client.OnError(func(client *igoclient.Client, err error) {
+		serverErrors <- err
+	})
+
+	client.Emit("echo", map[string]interface{}{"text": strings.Repeat("a", 2<<10)})
+	select {
+	case err := <-serverErrors:
+		var serverErr *igoclient.ServerError
+		if !errors.As(err, &serverErr) || serverErr.Code != "message_too_large" {
+			t.Fatalf("error is %v, want message_too_large", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("oversized message did not get answered with an error")
+	}
+	client.ExpectNoEvent(t, "echo", 50*time.Millisecond)
+
+	// the client stays connected for the messages within the limit
+	client.Emit("echo", map[string]interface{}{"text": "small"})
+	if data := client.ExpectEvent(t, "echo"); data["text"] != "small" {
+		t.Fatalf("echo is %v, want the small message", data)
+	}
+}
+
+func TestMaxMessageSizeDisconnectOversized(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{MaxMessageSize: 1 << 10, DisconnectOversized: true})
+	reasons := make(chan socketigo.DisconnectReason, 1)
+	server.OnConnected(func(client *socketigo.Client) {
+		client.OnDisconnect(func(reason socketigo.DisconnectReason) {
+			reasons <- reason
+		})
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	client.Emit("echo", map[string]interface{}{"text": strings.Repeat("a", 2<<10)})
+
+	select {
+	case reason := <-reasons:
+		if reason != socketigo.ReasonMessageTooLarge {
+			t.Fatalf("disconnect reason is %v, want %v", reason, socketigo.ReasonMessageTooLarge)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("client sending an oversized message did not get disconnected")
+	}
+}
+
+func TestMaxMessageSizeLimitsAuthFrame(t *testing.T) {
+	server := socketigo.CreateIgoServer(&socketigo.IgoServerOptions{MaxMessageSize: 1 << 10})
+	server.SetAuthenticator(func(ctx *socketigo.HandshakeContext, payload map[string]interface{}) error {
+		return nil
+	})
+
+	auth := map[string]interface{}{"token": strings.Repeat("a", 2<<10)}
+	if _, err := testclient.Connect(server, &testclient.Options{Auth: auth}); err == nil {
+		t.Fatal("connecting with an oversized auth frame succeeded")
+	}
+
+	client := testclient.MustConnect(t, server, &testclient.Options{Auth: map[string]interface{}{"token": "a"}})
+	if !client.IsConnected() {
+		t.Fatal("client with a small auth frame is not connected")
+	}
+}
@@ -0,0 +1,118 @@
+package socketigo
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// The timeouts of the http.Server of ListenAndServe. There is no read or
+// write timeout, those would end the long lived requests of SSE.
+const (
+	httpReadHeaderTimeout = 10 * time.Second
+	httpIdleTimeout       = 2 * time.Minute
+)
+
+// ListenAndServe serves the server at the path of an http.Server listening on
+// the TCP address until the server shuts down, for a standalone realtime
+// server. Requests to any other path answer 404, the path has to match
+// exactly. Shutdown closes the listener, so ListenAndServe returns
+// ErrServerShutdown, and shuts the http.Server down once the clients are gone.
+func (s *IgoServer) ListenAndServe(addr string, path string) error {
+	return s.listenAndServe(addr, path, func(httpServer *http.Server, listener net.Listener) error {
+		return httpServer.Serve(listener)
+	})
+}
+
+// ListenAndServeTLS works like ListenAndServe, with the certificate and the
+// matching key in the files, see http.Server.ServeTLS.
+func (s *IgoServer) ListenAndServeTLS(addr string, path string, certFile string, keyFile string) error {
+	return s.listenAndServe(addr, path, func(httpServer *http.Server, listener net.Listener) error {
+		return httpServer.ServeTLS(listener, certFile, keyFile)
+	})
+}
+
+func (s *IgoServer) listenAndServe(addr string, path string, serve func(httpServer *http.Server, listener net.Listener) error) error {
+	if path == "" {
+		path = "/"
+	}
+	// the path only, unlike the patterns of an http.ServeMux ending with "/"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		s.ServeHTTP(w, r)
+	})
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: httpReadHeaderTimeout,
+		IdleTimeout:       httpIdleTimeout,
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	listener = s.proxyListener(listener)
+
+	s.trackListener(listener, true)
+	defer s.trackListener(listener, false)
+	s.trackHTTPServer(httpServer, true)
+
+	if s.isShuttingDown() {
+		listener.Close()
+		return ErrServerShutdown
+	}
+
+	err = serve(httpServer, listener)
+	// Shutdown still has to shut it down
+	if s.isShuttingDown() {
+		return ErrServerShutdown
+	}
+	s.trackHTTPServer(httpServer, false)
+	return err
+}
+
+func (s *IgoServer) trackHTTPServer(httpServer *http.Server, add bool) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+
+	if add {
+		s.httpServers[httpServer] = struct{}{}
+	} else {
+		delete(s.httpServers, httpServer)
+	}
+}
+
+// shutdownHTTPServers closes the idle connections of the servers of
+// ListenAndServe and waits for their handlers to return.
+func (s *IgoServer) shutdownHTTPServers(ctx context.Context) error {
+	s.listenersMu.Lock()
+	httpServers := make([]*http.Server, 0, len(s.httpServers))
+	for httpServer := range s.httpServers {
+		httpServers = append(httpServers, httpServer)
+	}
+	s.listenersMu.Unlock()
+
+	for _, httpServer := range httpServers {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeHTTPServers closes the servers of ListenAndServe along with all their
+// connections.
+func (s *IgoServer) closeHTTPServers() {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+
+	for httpServer := range s.httpServers {
+		httpServer.Close()
+	}
+}
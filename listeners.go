@@ -0,0 +1,152 @@
+package socketigo
+
+import "sync"
+
+// Subscription is the handle of a single registered listener.
+type Subscription struct {
+	off func()
+}
+
+// Off removes exactly the listener the subscription was returned for.
+func (s *Subscription) Off() {
+	s.off()
+}
+
+type listenerEntry[L any] struct {
+	id       uint64
+	pattern  string
+	listener L
+	// typed and raw listeners decode the frame themselves instead of using
+	// the map
+	typed bool
+}
+
+// listenerSet holds the listeners per event name and the pattern listeners.
+// Listeners get invoked in the order they were added, pattern ones included.
+type listenerSet[L any] struct {
+	mu        sync.RWMutex
+	seq       uint64
+	listeners map[string][]listenerEntry[L]
+	patterns  []listenerEntry[L]
+}
+
+func newListenerSet[L any]() *listenerSet[L] {
+	return &listenerSet[L]{
+		listeners: make(map[string][]listenerEntry[L]),
+	}
+}
+
+func (s *listenerSet[L]) add(eventName string, listener L) *Subscription {
+	return s.addEntry(eventName, listener, false)
+}
+
+// addTyped adds a listener which does not need the decoded map of the payload.
+func (s *listenerSet[L]) addTyped(eventName string, listener L) *Subscription {
+	return s.addEntry(eventName, listener, true)
+}
+
+func (s *listenerSet[L]) addEntry(eventName string, listener L, typed bool) *Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	id := s.seq
+
+	entry := listenerEntry[L]{id: id, listener: listener, typed: typed}
+	if isPattern(eventName) {
+		entry.pattern = eventName
+		s.patterns = append(s.patterns, entry)
+	} else {
+		s.listeners[eventName] = append(s.listeners[eventName], entry)
+	}
+
+	return &Subscription{off: func() {
+		s.remove(eventName, id)
+	}}
+}
+
+func (s *listenerSet[L]) remove(eventName string, id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if isPattern(eventName) {
+		for i, entry := range s.patterns {
+			if entry.id == id {
+				s.patterns = append(s.patterns[:i:i], s.patterns[i+1:]...)
+				break
+			}
+		}
+		return
+	}
+
+	entries := s.listeners[eventName]
+	for i, entry := range entries {
+		if entry.id == id {
+			entries = append(entries[:i:i], entries[i+1:]...)
+			break
+		}
+	}
+
+	if len(entries) == 0 {
+		delete(s.listeners, eventName)
+	} else {
+		s.listeners[eventName] = entries
+	}
+}
+
+// removeAll removes the listeners of the event name, or of the pattern if it is
+// one.
+func (s *listenerSet[L]) removeAll(eventName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !isPattern(eventName) {
+		delete(s.listeners, eventName)
+		return
+	}
+
+	patterns := s.patterns[:0:0]
+	for _, entry := range s.patterns {
+		if entry.pattern != eventName {
+			patterns = append(patterns, entry)
+		}
+	}
+	s.patterns = patterns
+}
+
+// get returns a copy of the listeners matching the event, so they can be
+// invoked while others get added or removed.
+func (s *listenerSet[L]) get(eventName string) []L {
+	listeners, _ := s.match(eventName)
+	return listeners
+}
+
+// match is get, also telling whether every listener is a typed one.
+func (s *listenerSet[L]) match(eventName string) ([]L, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := s.listeners[eventName]
+	listeners := make([]L, 0, len(entries))
+	typed := true
+
+	// both lists are ordered by id, so merging them keeps the registration order
+	i := 0
+	for _, pattern := range s.patterns {
+		if !matchPattern(pattern.pattern, eventName) {
+			continue
+		}
+		for i < len(entries) && entries[i].id < pattern.id {
+			listeners = append(listeners, entries[i].listener)
+			typed = typed && entries[i].typed
+			i++
+		}
+		listeners = append(listeners, pattern.listener)
+		typed = typed && pattern.typed
+	}
+	for ; i < len(entries); i++ {
+		listeners = append(listeners, entries[i].listener)
+		typed = typed && entries[i].typed
+	}
+	return listeners, typed
+}
@@ -0,0 +1,80 @@
+package socketigo
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// Logger receives the log records of the server. Args alternate between keys
+// and values as with slog, *slog.Logger implements it.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// defaultLogger logs to slog.Default, looked up on every call so slog.SetDefault
+// still applies once the server was created.
+type defaultLogger struct{}
+
+func (defaultLogger) Debug(msg string, args ...interface{}) { slog.Debug(msg, args...) }
+func (defaultLogger) Info(msg string, args ...interface{})  { slog.Info(msg, args...) }
+func (defaultLogger) Warn(msg string, args ...interface{})  { slog.Warn(msg, args...) }
+func (defaultLogger) Error(msg string, args ...interface{}) { slog.Error(msg, args...) }
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+
+// SetLogger installs the logger, which defaults to slog.Default. A nil logger
+// silences the server.
+func (s *IgoServer) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	s.logger = logger
+}
+
+// logFields prefixes args with the fields identifying the client.
+func (c *Client) logFields(args ...interface{}) []interface{} {
+	return append([]interface{}{"client", c.Id.String(), "namespace", c.Namespace.Name}, args...)
+}
+
+func (s *IgoServer) logError(client *Client, r *http.Request, err error) {
+	var args []interface{}
+	if client != nil {
+		args = client.logFields()
+	}
+	if r != nil {
+		args = append(args, "remote", r.RemoteAddr)
+	}
+	args = append(args, "error", err)
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		args = append(args, "event", panicErr.Event, "stack", string(panicErr.Stack))
+	}
+
+	switch {
+	case errors.Is(err, ErrClientClosed):
+		s.logger.Info("connection closed unexpectedly", args...)
+	case errors.Is(err, ErrUpgradeFailed),
+		errors.Is(err, ErrHandshakeRejected),
+		errors.Is(err, ErrAuthFailed),
+		errors.Is(err, ErrDecodeFailed),
+		errors.Is(err, ErrMalformedFrame),
+		errors.Is(err, ErrInvalidPayload),
+		errors.Is(err, ErrMessageTooLarge),
+		errors.Is(err, ErrRateLimited),
+		errors.Is(err, ErrTooManyConnections),
+		errors.Is(err, ErrServerFull):
+		s.logger.Warn("client error", args...)
+	default:
+		s.logger.Error("server error", args...)
+	}
+}
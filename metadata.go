@@ -0,0 +1,127 @@
+package socketigo
+
+import "time"
+
+// Set attaches a value to the client under the key, replacing the previous
+// one. Values outlive a resumed session.
+func (c *Client) Set(key string, value interface{}) {
+	c.metadataMu.Lock()
+	defer c.metadataMu.Unlock()
+
+	if c.metadata == nil {
+		c.metadata = make(map[string]interface{})
+	}
+	c.metadata[key] = value
+}
+
+func (c *Client) Get(key string) (interface{}, bool) {
+	c.metadataMu.RLock()
+	defer c.metadataMu.RUnlock()
+
+	value, ok := c.metadata[key]
+	return value, ok
+}
+
+func (c *Client) Delete(key string) {
+	c.metadataMu.Lock()
+	defer c.metadataMu.Unlock()
+
+	delete(c.metadata, key)
+}
+
+// Metadata returns a copy of every value attached to the client.
+func (c *Client) Metadata() map[string]interface{} {
+	c.metadataMu.RLock()
+	defer c.metadataMu.RUnlock()
+
+	metadata := make(map[string]interface{}, len(c.metadata))
+	for key, value := range c.metadata {
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// GetAs returns the value attached to the client under the key, ok is false
+// when there is none or it is not a T.
+func GetAs[T any](client *Client, key string) (T, bool) {
+	value, ok := client.Get(key)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+
+	typed, ok := value.(T)
+	return typed, ok
+}
+
+func (r *Room) CreatedAt() time.Time {
+	return r.createdAt
+}
+
+func (r *Room) SetTopic(topic string) {
+	r.metadataMu.Lock()
+	defer r.metadataMu.Unlock()
+
+	r.topic = topic
+}
+
+func (r *Room) Topic() string {
+	r.metadataMu.RLock()
+	defer r.metadataMu.RUnlock()
+
+	return r.topic
+}
+
+// SetOwner records who owns the room, e.g. the user Id of its creator. It
+// grants nothing by itself.
+func (r *Room) SetOwner(owner string) {
+	r.metadataMu.Lock()
+	defer r.metadataMu.Unlock()
+
+	r.owner = owner
+}
+
+func (r *Room) Owner() string {
+	r.metadataMu.RLock()
+	defer r.metadataMu.RUnlock()
+
+	return r.owner
+}
+
+// Set attaches a value to the room under the key, replacing the previous one.
+func (r *Room) Set(key string, value interface{}) {
+	r.metadataMu.Lock()
+	defer r.metadataMu.Unlock()
+
+	if r.metadata == nil {
+		r.metadata = make(map[string]interface{})
+	}
+	r.metadata[key] = value
+}
+
+func (r *Room) Get(key string) (interface{}, bool) {
+	r.metadataMu.RLock()
+	defer r.metadataMu.RUnlock()
+
+	value, ok := r.metadata[key]
+	return value, ok
+}
+
+func (r *Room) Delete(key string) {
+	r.metadataMu.Lock()
+	defer r.metadataMu.Unlock()
+
+	delete(r.metadata, key)
+}
+
+// Metadata returns a copy of every value attached to the room.
+func (r *Room) Metadata() map[string]interface{} {
+	r.metadataMu.RLock()
+	defer r.metadataMu.RUnlock()
+
+	metadata := make(map[string]interface{}, len(r.metadata))
+	for key, value := range r.metadata {
+		metadata[key] = value
+	}
+	return metadata
+}
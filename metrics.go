@@ -0,0 +1,47 @@
+package socketigo
+
+import (
+	"strings"
+	"time"
+)
+
+// Metrics receives the measurements of the server, see the prommetrics package
+// for a Prometheus implementation. Event names of acks are reported without
+// their ack id, as "event@ack".
+type Metrics interface {
+	ClientConnected(namespace string)
+	ClientDisconnected(namespace string, reason DisconnectReason)
+	EventReceived(namespace string, eventName string, bytes int)
+	EventSent(namespace string, eventName string, bytes int)
+	HandlerDuration(namespace string, eventName string, duration time.Duration)
+	AckTimeout(namespace string, eventName string)
+	Error(err error)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) ClientConnected(string)                        {}
+func (noopMetrics) ClientDisconnected(string, DisconnectReason)   {}
+func (noopMetrics) EventReceived(string, string, int)             {}
+func (noopMetrics) EventSent(string, string, int)                 {}
+func (noopMetrics) HandlerDuration(string, string, time.Duration) {}
+func (noopMetrics) AckTimeout(string, string)                     {}
+func (noopMetrics) Error(error)                                   {}
+
+// SetMetrics installs the metrics implementation. It has to be called before
+// the first client connects.
+func (s *IgoServer) SetMetrics(metrics Metrics) {
+	if metrics == nil {
+		metrics = noopMetrics{}
+	}
+	s.metrics = metrics
+}
+
+// metricEventName drops the ack id from ack events, which would make every ack
+// a metric of its own.
+func metricEventName(eventName string) string {
+	if i := strings.Index(eventName, "@ack:"); i >= 0 {
+		return eventName[:i] + "@ack"
+	}
+	return eventName
+}
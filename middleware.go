@@ -0,0 +1,96 @@
+package socketigo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HandshakeContext is handed to every middleware after the connection got
+// upgraded but before the client is registered and the connected event fires.
+type HandshakeContext struct {
+	Request   *http.Request
+	Conn      Conn
+	Client    *Client
+	Namespace *Namespace
+	// Auth holds the payload of the client's auth frame. It is only populated
+	// when an authenticator is set on the server.
+	Auth map[string]interface{}
+}
+
+type Middleware func(ctx *HandshakeContext) error
+
+// Use appends a middleware to the handshake chain. Middlewares run in the order
+// they were added; the first one returning an error rejects the connection.
+func (s *IgoServer) Use(middleware Middleware) {
+	s.middlewaresMu.Lock()
+	defer s.middlewaresMu.Unlock()
+
+	s.middlewares = append(s.middlewares, middleware)
+}
+
+func (s *IgoServer) runMiddlewares(ctx *HandshakeContext) error {
+	s.middlewaresMu.RLock()
+	middlewares := make([]Middleware, len(s.middlewares))
+	copy(middlewares, s.middlewares)
+	s.middlewaresMu.RUnlock()
+
+	for _, middleware := range middlewares {
+		if err := middleware(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RequestMiddleware runs before the connection gets upgraded. Returning an
+// error rejects the upgrade, with the status of a StatusError or 403.
+type RequestMiddleware func(r *http.Request) error
+
+// StatusError rejects an upgrade with the given HTTP status.
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%d %s: %v", e.Status, http.StatusText(e.Status), e.Err)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// UseRequest appends a middleware to the chain running before the upgrade.
+func (s *IgoServer) UseRequest(middleware RequestMiddleware) {
+	s.middlewaresMu.Lock()
+	defer s.middlewaresMu.Unlock()
+
+	s.requestMiddlewares = append(s.requestMiddlewares, middleware)
+}
+
+// runRequestMiddlewares answers the request itself once a middleware rejected
+// it.
+func (s *IgoServer) runRequestMiddlewares(w http.ResponseWriter, r *http.Request) bool {
+	s.middlewaresMu.RLock()
+	middlewares := make([]RequestMiddleware, len(s.requestMiddlewares))
+	copy(middlewares, s.requestMiddlewares)
+	s.middlewaresMu.RUnlock()
+
+	for _, middleware := range middlewares {
+		err := middleware(r)
+		if err == nil {
+			continue
+		}
+
+		status := http.StatusForbidden
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			status = statusErr.Status
+		}
+		s.emitError(nil, r, ErrHandshakeRejected, err)
+		http.Error(w, http.StatusText(status), status)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,23 @@
+// Package msgpack provides a MessagePack codec for socket.igo servers and clients.
+package msgpack
+
+import (
+	ws "github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes frames as MessagePack and sends them as binary messages. It
+// satisfies both socketigo.Codec and igoclient.Codec.
+type Codec struct{}
+
+func (Codec) MessageType() int {
+	return ws.BinaryMessage
+}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
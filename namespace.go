@@ -0,0 +1,181 @@
+package socketigo
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	uuid "github.com/google/uuid"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const DefaultNamespace = "/"
+
+type Namespace struct {
+	Name                string
+	rooms               []*Room
+	server              *IgoServer
+	clients             *clientRegistry
+	roomsMu             sync.RWMutex
+	eventsMu            sync.RWMutex
+	events              *listenerSet[ContextListener]
+	binaryEvents        *listenerSet[BinaryListener]
+	schemas             map[string]*jsonschema.Schema
+	timeouts            map[string]time.Duration
+	streams             map[string]StreamListener
+	anyListener         AnyListener
+	connectedHandler    func(client *Client)
+	reconnectedHandler  func(client *Client)
+	disconnectedHandler func(client *Client)
+	topicsMu            sync.RWMutex
+	topics              map[string]map[*Client]struct{}
+	subscribeGuard      func(client *Client, filter string) error
+	maxSubscriptions    int
+}
+
+func createNamespace(server *IgoServer, name string) *Namespace {
+	return &Namespace{
+		Name:             name,
+		rooms:            make([]*Room, 0),
+		server:           server,
+		clients:          newClientRegistry(),
+		events:           newListenerSet[ContextListener](),
+		binaryEvents:     newListenerSet[BinaryListener](),
+		schemas:          make(map[string]*jsonschema.Schema),
+		timeouts:         make(map[string]time.Duration),
+		streams:          make(map[string]StreamListener),
+		topics:           make(map[string]map[*Client]struct{}),
+		maxSubscriptions: defaultMaxSubscriptions,
+	}
+}
+
+func normalizeNamespace(name string) string {
+	if name == "" {
+		return DefaultNamespace
+	}
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	return name
+}
+
+func (n *Namespace) Server() *IgoServer {
+	return n.server
+}
+
+func (n *Namespace) OnConnected(listener func(client *Client)) {
+	n.connectedHandler = listener
+}
+
+func (n *Namespace) OnDisconnected(listener func(client *Client)) {
+	n.disconnectedHandler = listener
+}
+
+// On adds a listener for every client of the namespace. Listeners registered
+// on the client itself take precedence.
+func (n *Namespace) On(eventName string, listener EventListener) *Subscription {
+	return n.events.add(eventName, listener.contextListener())
+}
+
+func (n *Namespace) Off(eventName string) {
+	n.events.removeAll(eventName)
+}
+
+func (n *Namespace) Clients() []*Client {
+	return n.clients.snapshot()
+}
+
+func (n *Namespace) Emit(eventName string, data interface{}) {
+	n.emitPacket(&BroadcastPacket{
+		Event: eventName,
+		Data:  data,
+	})
+}
+
+func (n *Namespace) EmitExcept(client *Client, eventName string, data interface{}) {
+	n.emitPacket(&BroadcastPacket{
+		Except: []uuid.UUID{client.Id},
+		Event:  eventName,
+		Data:   data,
+	})
+}
+
+// CreateRoom creates the room of the name, or returns it if it exists
+// already.
+func (n *Namespace) CreateRoom(name string) *Room {
+	n.roomsMu.Lock()
+	defer n.roomsMu.Unlock()
+
+	for _, room := range n.rooms {
+		if room.Id == name {
+			return room
+		}
+	}
+
+	room := &Room{
+		Id:        name,
+		Namespace: n,
+		clients:   newClientRegistry(),
+		createdAt: time.Now(),
+	}
+	n.rooms = append(n.rooms, room)
+	return room
+}
+
+// ListRooms returns a copy of the rooms of the namespace, safe to iterate while
+// rooms get created and deleted.
+func (n *Namespace) ListRooms() []*Room {
+	n.roomsMu.RLock()
+	defer n.roomsMu.RUnlock()
+
+	return append([]*Room(nil), n.rooms...)
+}
+
+// matchRooms returns the rooms whose Id matches any of the patterns.
+func (n *Namespace) matchRooms(patterns []string) []*Room {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	var rooms []*Room
+	for _, room := range n.ListRooms() {
+		for _, pattern := range patterns {
+			if matchPattern(pattern, room.Id) {
+				rooms = append(rooms, room)
+				break
+			}
+		}
+	}
+	return rooms
+}
+
+func (n *Namespace) GetRoom(name string) *Room {
+	n.roomsMu.RLock()
+	defer n.roomsMu.RUnlock()
+
+	for _, room := range n.rooms {
+		if room.Id == name {
+			return room
+		}
+	}
+	return nil
+}
+
+// DeleteRoom removes the room from the namespace. Its members do not count it
+// among their rooms anymore.
+func (n *Namespace) DeleteRoom(room *Room) {
+	room.stopTimers()
+	room.clients.each(func(client *Client) {
+		client.removeRoom(room)
+	})
+
+	n.roomsMu.Lock()
+	defer n.roomsMu.Unlock()
+
+	for i, r := range n.rooms {
+		if r == room {
+			n.rooms = append(n.rooms[:i], n.rooms[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package natsadapter relays socket.igo broadcasts between server instances
+// over NATS.
+package natsadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/nats-io/nats.go"
+	socketigo "github.com/nauri-io/socket.igo"
+)
+
+const defaultSubject = "socketigo"
+
+type Options struct {
+	// Subject is the subject shared by all instances. Defaults to "socketigo".
+	Subject string
+}
+
+// Broker implements socketigo.Broker on top of a NATS connection.
+type Broker struct {
+	conn    *nats.Conn
+	subject string
+	mu      sync.Mutex
+	sub     *nats.Subscription
+}
+
+func New(conn *nats.Conn, options *Options) *Broker {
+	subject := defaultSubject
+	if options != nil && options.Subject != "" {
+		subject = options.Subject
+	}
+
+	return &Broker{
+		conn:    conn,
+		subject: subject,
+	}
+}
+
+func (b *Broker) Publish(packet *socketigo.BroadcastPacket) error {
+	data, err := json.Marshal(packet)
+	if err != nil {
+		return err
+	}
+	return b.conn.Publish(b.subject, data)
+}
+
+func (b *Broker) Subscribe(handler func(packet *socketigo.BroadcastPacket)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.sub != nil {
+		return errors.New("natsadapter: already subscribed")
+	}
+
+	sub, err := b.conn.Subscribe(b.subject, func(message *nats.Msg) {
+		packet := &socketigo.BroadcastPacket{}
+		if err := json.Unmarshal(message.Data, packet); err != nil {
+			return
+		}
+		handler(packet)
+	})
+	if err != nil {
+		return err
+	}
+
+	// make sure the server registered the subscription before broadcasts flow
+	if err := b.conn.Flush(); err != nil {
+		sub.Unsubscribe()
+		return err
+	}
+
+	b.sub = sub
+	return nil
+}
+
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.sub == nil {
+		return nil
+	}
+
+	err := b.sub.Unsubscribe()
+	b.sub = nil
+	return err
+}
+
+// Health reports whether the connection is up, see socketigo.HealthChecker.
+func (b *Broker) Health(ctx context.Context) error {
+	if status := b.conn.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("natsadapter: connection %s", status)
+	}
+	return nil
+}
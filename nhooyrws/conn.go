@@ -0,0 +1,274 @@
+// Package nhooyrws runs the websockets of a socket.igo server on
+// nhooyr.io/websocket instead of gorilla/websocket.
+//
+//	server := socketigo.CreateIgoServer(&socketigo.IgoServerOptions{
+//		WebSocket: nhooyrws.New(nil),
+//	})
+package nhooyrws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"nhooyr.io/websocket"
+)
+
+type Options struct {
+	// AcceptOptions configure the upgrade, see websocket.AcceptOptions.
+	AcceptOptions *websocket.AcceptOptions
+}
+
+// Upgrader implements socketigo.Transport for websocket requests.
+type Upgrader struct {
+	acceptOptions *websocket.AcceptOptions
+}
+
+func New(options *Options) *Upgrader {
+	upgrader := &Upgrader{}
+	if options != nil {
+		upgrader.acceptOptions = options.AcceptOptions
+	}
+	return upgrader
+}
+
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request) (socketigo.Conn, error) {
+	socket, err := websocket.Accept(w, r, u.acceptOptions)
+	if err != nil {
+		return nil, err
+	}
+	// the limit is up to the server, see SetReadLimit
+	socket.SetReadLimit(-1)
+
+	return newConn(socket), nil
+}
+
+// conn emulates the deadlines of gorilla/websocket with the contexts of
+// nhooyr.io/websocket.
+type conn struct {
+	socket *websocket.Conn
+	// reads share a context which gets canceled once the read deadline passed
+	readCtx       context.Context
+	cancelRead    context.CancelFunc
+	mu            sync.Mutex
+	readTimer     *time.Timer
+	readDeadline  time.Time
+	expired       bool
+	readLimit     int64
+	pong          func(appData string) error
+	writeMu       sync.Mutex
+	writeDeadline time.Time
+	closeOnce     sync.Once
+}
+
+func newConn(socket *websocket.Conn) *conn {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &conn{
+		socket:     socket,
+		readCtx:    ctx,
+		cancelRead: cancel,
+	}
+}
+
+func (c *conn) NextReader() (int, io.Reader, error) {
+	messageType, reader, err := c.socket.Reader(c.readCtx)
+	if err != nil {
+		return 0, nil, c.readError(err)
+	}
+
+	c.mu.Lock()
+	limit := c.readLimit
+	c.mu.Unlock()
+
+	return int(messageType), &messageReader{conn: c, reader: reader, limit: limit}, nil
+}
+
+// readError translates the errors of nhooyr.io/websocket to the ones the
+// server tells apart.
+func (c *conn) readError(err error) error {
+	var closeErr websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return socketigo.NewCloseError(int(closeErr.Code), closeErr.Reason)
+	}
+
+	c.mu.Lock()
+	expired := c.expired
+	c.mu.Unlock()
+	if expired {
+		return os.ErrDeadlineExceeded
+	}
+	return err
+}
+
+// messageReader tells a message exceeding the read limit apart from other read
+// errors. nhooyr.io/websocket hands out one byte more than the limit before it
+// fails the read, closing the connection with 1009.
+type messageReader struct {
+	conn   *conn
+	reader io.Reader
+	limit  int64
+	read   int64
+}
+
+func (r *messageReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+
+	if err != nil && err != io.EOF {
+		if r.limit > 0 && r.read > r.limit {
+			return n, socketigo.ErrReadLimit
+		}
+		err = r.conn.readError(err)
+	}
+	return n, err
+}
+
+func (c *conn) SetReadLimit(limit int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readLimit = limit
+	c.socket.SetReadLimit(limit)
+}
+
+func (c *conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case socketigo.TextMessage, socketigo.BinaryMessage:
+	case socketigo.PingMessage, socketigo.PongMessage, socketigo.CloseMessage:
+		c.writeMu.Lock()
+		deadline := c.writeDeadline
+		c.writeMu.Unlock()
+		return c.WriteControl(messageType, data, deadline)
+	default:
+		return fmt.Errorf("nhooyrws: unknown message type %d", messageType)
+	}
+
+	ctx, cancel := c.writeContext()
+	defer cancel()
+
+	return c.socket.Write(ctx, websocket.MessageType(messageType), data)
+}
+
+func (c *conn) writeContext() (context.Context, context.CancelFunc) {
+	c.writeMu.Lock()
+	deadline := c.writeDeadline
+	c.writeMu.Unlock()
+
+	if deadline.IsZero() {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithDeadline(context.Background(), deadline)
+}
+
+// WriteControl maps the control messages to nhooyr.io/websocket, which
+// answers pings itself: a ping waits for its pong in the background, a close
+// runs the closing handshake.
+func (c *conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	switch messageType {
+	case socketigo.PingMessage:
+		go c.ping(deadline)
+		return nil
+	case socketigo.PongMessage:
+		return nil
+	case socketigo.CloseMessage:
+		code := websocket.StatusNormalClosure
+		reason := ""
+		if len(data) >= 2 {
+			code = websocket.StatusCode(int(data[0])<<8 | int(data[1]))
+			reason = string(data[2:])
+		}
+		return c.socket.Close(code, reason)
+	default:
+		return fmt.Errorf("nhooyrws: unknown control message type %d", messageType)
+	}
+}
+
+func (c *conn) ping(deadline time.Time) {
+	ctx := context.Background()
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	if err := c.socket.Ping(ctx); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	pong := c.pong
+	c.mu.Unlock()
+	if pong != nil {
+		pong("")
+	}
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readDeadline = t
+	if c.readTimer != nil {
+		c.readTimer.Stop()
+		c.readTimer = nil
+	}
+	if !t.IsZero() {
+		c.readTimer = time.AfterFunc(time.Until(t), c.expire)
+	}
+	return nil
+}
+
+// expire cancels the reads unless the deadline got extended meanwhile.
+func (c *conn) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readDeadline.IsZero() || time.Now().Before(c.readDeadline) {
+		return
+	}
+	c.expired = true
+	c.cancelRead()
+}
+
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.writeDeadline = t
+	return nil
+}
+
+func (c *conn) SetPongHandler(h func(appData string) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pong = h
+}
+
+func (c *conn) EnableWriteCompression(enable bool) {}
+
+func (c *conn) Subprotocol() string {
+	return c.socket.Subprotocol()
+}
+
+func (c *conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		if c.readTimer != nil {
+			c.readTimer.Stop()
+		}
+		c.mu.Unlock()
+
+		err = c.socket.CloseNow()
+		c.cancelRead()
+	})
+	return err
+}
@@ -0,0 +1,105 @@
+// Package oteltracing traces the events of a socket.igo server with
+// OpenTelemetry.
+package oteltracing
+
+import (
+	"context"
+	"net/http"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/nauri-io/socket.igo/oteltracing"
+
+type Options struct {
+	// TracerProvider creates the tracer. Defaults to the global provider.
+	TracerProvider trace.TracerProvider
+	// Propagator reads and writes the trace context of events. Defaults to W3C
+	// trace context.
+	Propagator propagation.TextMapPropagator
+	// HandshakeHeaders continues the trace passed in the headers of the upgrade
+	// request, e.g. traceparent, for every event of the connection.
+	HandshakeHeaders bool
+}
+
+// Tracer implements socketigo.Tracer. Install it with SetTracer.
+type Tracer struct {
+	tracer           trace.Tracer
+	propagator       propagation.TextMapPropagator
+	handshakeHeaders bool
+}
+
+func New(options *Options) *Tracer {
+	if options == nil {
+		options = &Options{}
+	}
+
+	provider := options.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	propagator := options.Propagator
+	if propagator == nil {
+		propagator = propagation.TraceContext{}
+	}
+
+	return &Tracer{
+		tracer:           provider.Tracer(instrumentationName),
+		propagator:       propagator,
+		handshakeHeaders: options.HandshakeHeaders,
+	}
+}
+
+func (t *Tracer) StartConnection(ctx context.Context, r *http.Request) context.Context {
+	if !t.handshakeHeaders {
+		return ctx
+	}
+	return t.propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+}
+
+func (t *Tracer) StartEvent(ctx context.Context, client *socketigo.Client, eventName string, headers map[string]string) (context.Context, func(err error)) {
+	if len(headers) > 0 {
+		ctx = t.propagator.Extract(ctx, propagation.MapCarrier(headers))
+	}
+
+	ctx, span := t.tracer.Start(ctx, "socketigo.event "+eventName,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attributes(client, eventName)...),
+	)
+	return ctx, endFunc(span)
+}
+
+func (t *Tracer) StartAck(ctx context.Context, client *socketigo.Client, eventName string) (map[string]string, func(err error)) {
+	ctx, span := t.tracer.Start(ctx, "socketigo.ack "+eventName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes(client, eventName)...),
+	)
+
+	headers := propagation.MapCarrier{}
+	t.propagator.Inject(ctx, headers)
+	return headers, endFunc(span)
+}
+
+func attributes(client *socketigo.Client, eventName string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("socketigo.event", eventName),
+		attribute.String("socketigo.client_id", client.Id.String()),
+		attribute.String("socketigo.namespace", client.Namespace.Name),
+	}
+}
+
+func endFunc(span trace.Span) func(err error) {
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,57 @@
+package socketigo
+
+import "strings"
+
+// isPattern reports whether the event name subscribes to a pattern. A "*"
+// matches any run of characters, a "+" a single segment, where segments are
+// separated by "/", ":" or ".". So "chat:*" matches "chat:room:1", while
+// "device/+/status" matches "device/42/status" but not "device/4/2/status".
+func isPattern(eventName string) bool {
+	return strings.ContainsAny(eventName, "*+")
+}
+
+func isSeparator(c byte) bool {
+	return c == '/' || c == ':' || c == '.'
+}
+
+// matchPattern walks the pattern and the event name once, remembering the
+// last "*". On a mismatch the star takes one more character and the walk
+// goes on behind it, the stars before it never need to be revisited.
+func matchPattern(pattern string, eventName string) bool {
+	p, e := 0, 0
+	star, starMatch := -1, 0
+
+	for e < len(eventName) {
+		if p < len(pattern) {
+			switch {
+			case pattern[p] == '*':
+				star, starMatch = p, e
+				p++
+				continue
+			case pattern[p] == '+':
+				i := e
+				for i < len(eventName) && !isSeparator(eventName[i]) {
+					i++
+				}
+				if i > e {
+					p, e = p+1, i
+					continue
+				}
+			case pattern[p] == eventName[e]:
+				p, e = p+1, e+1
+				continue
+			}
+		}
+
+		if star < 0 {
+			return false
+		}
+		starMatch++
+		p, e = star+1, starMatch
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,48 @@
+package socketigo
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBuffer bounds the buffers kept for reuse, so a single huge message
+// does not pin its memory for the lifetime of the server.
+const maxPooledBuffer = 64 << 10
+
+// readBuffers recycles the buffers inbound messages are read into. A buffer is
+// released once its message got decoded, so codecs must not retain the data
+// they unmarshal.
+var readBuffers = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// frames recycles the frames inbound messages are decoded into. Only the frame
+// itself is reused, its data is handed to listeners and never recycled.
+var frames = sync.Pool{
+	New: func() interface{} {
+		return new(Frame)
+	},
+}
+
+func acquireBuffer() *bytes.Buffer {
+	return readBuffers.Get().(*bytes.Buffer)
+}
+
+func releaseBuffer(buffer *bytes.Buffer) {
+	if buffer == nil || buffer.Cap() > maxPooledBuffer {
+		return
+	}
+	buffer.Reset()
+	readBuffers.Put(buffer)
+}
+
+func acquireFrame() *Frame {
+	return frames.Get().(*Frame)
+}
+
+func releaseFrame(frame *Frame) {
+	*frame = Frame{}
+	frames.Put(frame)
+}
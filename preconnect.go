@@ -0,0 +1,67 @@
+package socketigo
+
+import (
+	"errors"
+	"net/http"
+
+	ws "github.com/gorilla/websocket"
+)
+
+const connectionRejectedCode = "connection_rejected"
+
+// PreConnectContext is handed to the handler set with OnPreConnectContext
+// right after the upgrade, before the handshake.
+type PreConnectContext struct {
+	Request   *http.Request
+	Conn      Conn
+	Namespace *Namespace
+}
+
+// RejectError rejects a connection in the pre-connect handler with the given
+// close code.
+type RejectError struct {
+	Code   int
+	Reason string
+}
+
+func (e *RejectError) Error() string {
+	return e.Reason
+}
+
+// Reject returns a RejectError closing the connection with the code.
+func Reject(code int, reason string) error {
+	return &RejectError{Code: code, Reason: reason}
+}
+
+// OnPreConnectContext sets a pre-connect handler able to reject the connection
+// by returning an error. A RejectError chooses the close code, other errors
+// close with 1008 policy violation. Rejecting with an HTTP status instead has
+// to happen before the upgrade, see UseRequest and StatusError.
+func (s *IgoServer) OnPreConnectContext(listener func(ctx *PreConnectContext) error) {
+	s.preConnectCheck = listener
+}
+
+func (s *IgoServer) preConnect(r *http.Request, conn Conn, ns *Namespace) bool {
+	if s.preConnectCheck == nil {
+		return true
+	}
+
+	err := s.preConnectCheck(&PreConnectContext{
+		Request:   r,
+		Conn:      conn,
+		Namespace: ns,
+	})
+	if err == nil {
+		return true
+	}
+
+	closeCode := ws.ClosePolicyViolation
+	var rejectErr *RejectError
+	if errors.As(err, &rejectErr) {
+		closeCode = rejectErr.Code
+	}
+
+	rejectHandshake(conn, s.codec, closeCode, connectionRejectedCode, err)
+	s.emitError(nil, r, ErrHandshakeRejected, err)
+	return false
+}
@@ -0,0 +1,191 @@
+package socketigo
+
+import (
+	"sort"
+	"sync"
+)
+
+// Presence rooms keep a roster of their members. A joining client gets
+// "presence:roster" carrying {"room", "members"}, the others get
+// "presence:member_added" and "presence:member_removed" carrying {"room",
+// "member"}. Clients bound to the same user with SetUserId count as a single
+// member, added with the first of them and removed with the last. The roster
+// holds the members of this node.
+
+const (
+	presenceRosterEvent = "presence:roster"
+	memberAddedEvent    = "presence:member_added"
+	memberRemovedEvent  = "presence:member_removed"
+)
+
+type PresenceMember struct {
+	Id   string      `json:"id"`
+	Info interface{} `json:"info,omitempty"`
+}
+
+type presence struct {
+	mu         sync.Mutex
+	memberInfo func(client *Client) interface{}
+	members    map[string]*presenceEntry
+	// the member of every client, in case its user changes meanwhile
+	memberIds map[*Client]string
+}
+
+type presenceEntry struct {
+	member  PresenceMember
+	clients map[*Client]struct{}
+}
+
+func presenceMemberId(client *Client) string {
+	if userId := client.UserId(); userId != "" {
+		return userId
+	}
+	return client.Id.String()
+}
+
+// EnablePresence turns the room into a presence room. memberInfo provides the
+// metadata of a member when it joins, e.g. its name and avatar, and may be nil.
+func (r *Room) EnablePresence(memberInfo func(client *Client) interface{}) {
+	r.joinMu.Lock()
+	defer r.joinMu.Unlock()
+
+	if r.presence == nil {
+		r.presence = &presence{
+			members:   make(map[string]*presenceEntry),
+			memberIds: make(map[*Client]string),
+		}
+	}
+	r.presence.memberInfo = memberInfo
+}
+
+// CreatePresenceRoom creates a room with EnablePresence.
+func (n *Namespace) CreatePresenceRoom(name string, memberInfo func(client *Client) interface{}) *Room {
+	room := n.CreateRoom(name)
+	room.EnablePresence(memberInfo)
+	return room
+}
+
+func (r *Room) getPresence() *presence {
+	r.joinMu.Lock()
+	defer r.joinMu.Unlock()
+
+	return r.presence
+}
+
+// Members returns the roster of a presence room, sorted by member Id.
+func (r *Room) Members() []PresenceMember {
+	p := r.getPresence()
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.roster()
+}
+
+func (p *presence) roster() []PresenceMember {
+	members := make([]PresenceMember, 0, len(p.members))
+	for _, entry := range p.members {
+		members = append(members, entry.member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Id < members[j].Id
+	})
+	return members
+}
+
+// presenceJoined adds the client to the roster, sends it the roster and
+// announces it to the others if it is a new member.
+func (r *Room) presenceJoined(client *Client) {
+	p := r.getPresence()
+	if p == nil {
+		return
+	}
+
+	id := presenceMemberId(client)
+
+	p.mu.Lock()
+	entry, ok := p.members[id]
+	if !ok {
+		member := PresenceMember{Id: id}
+		if p.memberInfo != nil {
+			member.Info = p.memberInfo(client)
+		}
+		entry = &presenceEntry{member: member, clients: make(map[*Client]struct{})}
+		p.members[id] = entry
+	}
+	entry.clients[client] = struct{}{}
+	p.memberIds[client] = id
+	roster := p.roster()
+	p.mu.Unlock()
+
+	client.Emit(presenceRosterEvent, map[string]interface{}{
+		"room":    r.Id,
+		"members": roster,
+	})
+	if !ok {
+		r.emitLocal(client, memberAddedEvent, map[string]interface{}{
+			"room":   r.Id,
+			"member": entry.member,
+		})
+	}
+}
+
+func (r *Room) presenceLeft(client *Client) {
+	p := r.getPresence()
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	id, ok := p.memberIds[client]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.memberIds, client)
+	entry := p.members[id]
+	delete(entry.clients, client)
+	gone := len(entry.clients) == 0
+	if gone {
+		delete(p.members, id)
+	}
+	p.mu.Unlock()
+
+	if gone {
+		r.emitLocal(client, memberRemovedEvent, map[string]interface{}{
+			"room":   r.Id,
+			"member": entry.member,
+		})
+	}
+}
+
+// presenceReplaced swaps a detached client for the one resuming its session,
+// without announcing anything.
+func (r *Room) presenceReplaced(old *Client, client *Client) {
+	p := r.getPresence()
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if id, ok := p.memberIds[old]; ok {
+		delete(p.memberIds, old)
+		p.memberIds[client] = id
+		delete(p.members[id].clients, old)
+		p.members[id].clients[client] = struct{}{}
+	}
+}
+
+// emitLocal emits to the members of the room on this node except the client.
+func (r *Room) emitLocal(except *Client, eventName string, data interface{}) {
+	for _, client := range r.clients.snapshot() {
+		if client != except {
+			client.Emit(eventName, data)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Package prommetrics exports the metrics of a socket.igo server to Prometheus.
+package prommetrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const defaultNamespace = "socketigo"
+
+type Options struct {
+	// Namespace prefixes every metric name. Defaults to "socketigo".
+	Namespace string
+	// Registry the metrics are registered with. Defaults to a new registry.
+	Registry *prometheus.Registry
+	// Buckets of the handler duration histogram. Defaults to prometheus.DefBuckets.
+	Buckets []float64
+}
+
+// Metrics implements socketigo.Metrics. Install it with SetMetrics and serve
+// Handler on the metrics endpoint.
+type Metrics struct {
+	registry        *prometheus.Registry
+	connections     *prometheus.GaugeVec
+	connects        *prometheus.CounterVec
+	disconnects     *prometheus.CounterVec
+	eventsReceived  *prometheus.CounterVec
+	eventsSent      *prometheus.CounterVec
+	bytesReceived   *prometheus.CounterVec
+	bytesSent       *prometheus.CounterVec
+	handlerDuration *prometheus.HistogramVec
+	ackTimeouts     *prometheus.CounterVec
+	errors          *prometheus.CounterVec
+}
+
+func New(options *Options) *Metrics {
+	if options == nil {
+		options = &Options{}
+	}
+
+	namespace := options.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	registry := options.Registry
+	if registry == nil {
+		registry = prometheus.NewRegistry()
+	}
+
+	buckets := options.Buckets
+	if buckets == nil {
+		buckets = prometheus.DefBuckets
+	}
+
+	m := &Metrics{
+		registry: registry,
+		connections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "connections",
+			Help:      "Number of connected clients.",
+		}, []string{"nsp"}),
+		connects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "connects_total",
+			Help:      "Number of clients that connected.",
+		}, []string{"nsp"}),
+		disconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "disconnects_total",
+			Help:      "Number of clients that disconnected, by reason.",
+		}, []string{"nsp", "reason"}),
+		eventsReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_received_total",
+			Help:      "Number of events received from clients.",
+		}, []string{"nsp", "event"}),
+		eventsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_sent_total",
+			Help:      "Number of events sent to clients.",
+		}, []string{"nsp", "event"}),
+		bytesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "received_bytes_total",
+			Help:      "Number of bytes received from clients.",
+		}, []string{"nsp"}),
+		bytesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sent_bytes_total",
+			Help:      "Number of bytes queued for clients.",
+		}, []string{"nsp"}),
+		handlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "handler_duration_seconds",
+			Help:      "Time the listeners of an event took.",
+			Buckets:   buckets,
+		}, []string{"nsp", "event"}),
+		ackTimeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ack_timeouts_total",
+			Help:      "Number of acks clients did not send in time.",
+		}, []string{"nsp", "event"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Number of errors reported to the error handler, by kind.",
+		}, []string{"kind"}),
+	}
+
+	registry.MustRegister(
+		m.connections, m.connects, m.disconnects,
+		m.eventsReceived, m.eventsSent, m.bytesReceived, m.bytesSent,
+		m.handlerDuration, m.ackTimeouts, m.errors,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+func (m *Metrics) ClientConnected(namespace string) {
+	m.connections.WithLabelValues(namespace).Inc()
+	m.connects.WithLabelValues(namespace).Inc()
+}
+
+func (m *Metrics) ClientDisconnected(namespace string, reason socketigo.DisconnectReason) {
+	m.connections.WithLabelValues(namespace).Dec()
+	m.disconnects.WithLabelValues(namespace, reason.String()).Inc()
+}
+
+func (m *Metrics) EventReceived(namespace string, eventName string, bytes int) {
+	m.eventsReceived.WithLabelValues(namespace, eventName).Inc()
+	m.bytesReceived.WithLabelValues(namespace).Add(float64(bytes))
+}
+
+func (m *Metrics) EventSent(namespace string, eventName string, bytes int) {
+	m.eventsSent.WithLabelValues(namespace, eventName).Inc()
+	m.bytesSent.WithLabelValues(namespace).Add(float64(bytes))
+}
+
+func (m *Metrics) HandlerDuration(namespace string, eventName string, duration time.Duration) {
+	m.handlerDuration.WithLabelValues(namespace, eventName).Observe(duration.Seconds())
+}
+
+func (m *Metrics) AckTimeout(namespace string, eventName string) {
+	m.ackTimeouts.WithLabelValues(namespace, eventName).Inc()
+}
+
+func (m *Metrics) Error(err error) {
+	m.errors.WithLabelValues(errorKind(err)).Inc()
+}
+
+var errorKinds = []struct {
+	err  error
+	kind string
+}{
+	{socketigo.ErrMalformedFrame, "malformed_frame"},
+	{socketigo.ErrDecodeFailed, "decode_failed"},
+	{socketigo.ErrClientClosed, "client_closed"},
+	{socketigo.ErrUpgradeFailed, "upgrade_failed"},
+	{socketigo.ErrHandshakeRejected, "handshake_rejected"},
+	{socketigo.ErrAuthFailed, "auth_failed"},
+	{socketigo.ErrInvalidPayload, "invalid_payload"},
+	{socketigo.ErrListenerPanic, "listener_panic"},
+	{socketigo.ErrHandlerTimeout, "handler_timeout"},
+	{socketigo.ErrMessageTooLarge, "message_too_large"},
+	{socketigo.ErrRateLimited, "rate_limited"},
+	{socketigo.ErrTooManyConnections, "too_many_connections"},
+	{socketigo.ErrServerFull, "server_full"},
+}
+
+func errorKind(err error) string {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind.kind
+		}
+	}
+	return "other"
+}
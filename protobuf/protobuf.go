@@ -0,0 +1,89 @@
+// Package protobuf sends and receives protobuf messages as binary socket.igo events.
+package protobuf
+
+import (
+	"fmt"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/igoclient"
+	"google.golang.org/protobuf/proto"
+)
+
+type BinaryEmitter interface {
+	EmitBinary(eventName string, data []byte) error
+}
+
+type BinaryBroadcaster interface {
+	EmitBinary(eventName string, data []byte)
+}
+
+func newMessage[T proto.Message]() T {
+	var zero T
+	return zero.ProtoReflect().New().Interface().(T)
+}
+
+func decode[T proto.Message](eventName string, data []byte) (T, error) {
+	msg := newMessage[T]()
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return msg, fmt.Errorf("%w: event %q: %w", socketigo.ErrDecodeFailed, eventName, err)
+	}
+	return msg, nil
+}
+
+// On registers T as the payload type of the client's event. Payloads failing to
+// decode are reported to the server's error handler.
+func On[T proto.Message](client *socketigo.Client, eventName string, listener func(client *socketigo.Client, msg T)) *socketigo.Subscription {
+	return client.OnBinary(eventName, func(client *socketigo.Client, data []byte) {
+		msg, err := decode[T](eventName, data)
+		if err != nil {
+			client.Server.ReportError(client, err)
+			return
+		}
+		listener(client, msg)
+	})
+}
+
+// OnNamespace registers T as the payload type of the event for every client of
+// the namespace.
+func OnNamespace[T proto.Message](ns *socketigo.Namespace, eventName string, listener func(client *socketigo.Client, msg T)) *socketigo.Subscription {
+	return ns.OnBinary(eventName, func(client *socketigo.Client, data []byte) {
+		msg, err := decode[T](eventName, data)
+		if err != nil {
+			client.Server.ReportError(client, err)
+			return
+		}
+		listener(client, msg)
+	})
+}
+
+// OnClient registers T as the payload type of the event on a Go client.
+func OnClient[T proto.Message](client *igoclient.Client, eventName string, listener func(client *igoclient.Client, msg T)) {
+	client.OnBinary(eventName, func(client *igoclient.Client, data []byte) {
+		msg, err := decode[T](eventName, data)
+		if err != nil {
+			return
+		}
+		listener(client, msg)
+	})
+}
+
+// Emit sends the message to a single client, either a server side
+// socketigo.Client or an igoclient.Client.
+func Emit(to BinaryEmitter, eventName string, msg proto.Message) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return to.EmitBinary(eventName, data)
+}
+
+// Broadcast sends the message to a namespace, room or broadcast operator. The
+// message is only encoded once.
+func Broadcast(to BinaryBroadcaster, eventName string, msg proto.Message) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	to.EmitBinary(eventName, data)
+	return nil
+}
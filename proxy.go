@@ -0,0 +1,278 @@
+package socketigo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const proxyHeaderTimeout = 5 * time.Second
+
+// proxyV2Signature starts every header of version 2 of the PROXY protocol.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// trustsPeer tells whether the address a connection comes from is one of
+// TrustedProxies. Peers without an IP address, like the ones of a Unix domain
+// socket, are trusted by ProxyProtocol only, file permissions decide who may
+// connect to those.
+func (s *IgoServer) trustsPeer(addr string) bool {
+	addrPort, err := netip.ParseAddrPort(addr)
+	if err != nil {
+		return false
+	}
+	return containsAddr(s.trustedProxies, addrPort.Addr().Unmap())
+}
+
+// resolveRemoteAddr returns the request with the address of the client in
+// RemoteAddr, taken from X-Forwarded-For or X-Real-IP if it came through a
+// trusted proxy.
+func (s *IgoServer) resolveRemoteAddr(r *http.Request) *http.Request {
+	if len(s.trustedProxies) == 0 || !s.trustsPeer(r.RemoteAddr) {
+		return r
+	}
+
+	addr, ok := s.forwardedAddr(r)
+	if !ok {
+		return r
+	}
+	resolved := r.WithContext(r.Context())
+	resolved.RemoteAddr = addr.String()
+	return resolved
+}
+
+// forwardedAddr walks X-Forwarded-For from the right, the hop closest to the
+// server, up to the first address which is no trusted proxy. A client can put
+// anything in front of the list, only the hops added by the proxies count.
+func (s *IgoServer) forwardedAddr(r *http.Request) (netip.AddrPort, bool) {
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+
+	var client netip.AddrPort
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHop(hops[i])
+		if !ok {
+			break
+		}
+		client = addr
+		if !containsAddr(s.trustedProxies, addr.Addr()) {
+			break
+		}
+	}
+	if client.IsValid() {
+		return client, true
+	}
+
+	return parseHop(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+}
+
+// parseHop parses an address of a forwarding header, which comes with a port
+// or without one.
+func parseHop(hop string) (netip.AddrPort, bool) {
+	if addr, err := netip.ParseAddr(hop); err == nil {
+		return netip.AddrPortFrom(addr.Unmap(), 0), true
+	}
+	addrPort, err := netip.ParseAddrPort(hop)
+	if err != nil {
+		return netip.AddrPort{}, false
+	}
+	return netip.AddrPortFrom(addrPort.Addr().Unmap(), addrPort.Port()), true
+}
+
+// proxyListener reads the PROXY protocol header off the connections of
+// trusted proxies, whose RemoteAddr becomes the address of the client.
+type proxyListener struct {
+	net.Listener
+	server *IgoServer
+}
+
+// proxyListener wraps the listener if the server expects PROXY protocol
+// headers.
+func (s *IgoServer) proxyListener(listener net.Listener) net.Listener {
+	if !s.proxyProtocol {
+		return listener
+	}
+	return &proxyListener{Listener: listener, server: s}
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	trusted := true
+	if _, ok := conn.RemoteAddr().(*net.UnixAddr); !ok {
+		trusted = l.server.trustsPeer(conn.RemoteAddr().String())
+	}
+	return &proxyConn{Conn: conn, trusted: trusted}, nil
+}
+
+// proxyConn reads the header on the first Read or RemoteAddr, so a slow
+// proxy holds up its own connection only, not the accepting.
+type proxyConn struct {
+	net.Conn
+	trusted bool
+	once    sync.Once
+	reader  *bufio.Reader
+	remote  net.Addr
+	err     error
+}
+
+func (c *proxyConn) readHeader() {
+	c.once.Do(func() {
+		c.remote = c.Conn.RemoteAddr()
+		if !c.trusted {
+			return
+		}
+
+		c.reader = bufio.NewReader(c.Conn)
+		c.Conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+		defer c.Conn.SetReadDeadline(time.Time{})
+
+		addr, err := readProxyHeader(c.reader)
+		if err != nil {
+			c.err = fmt.Errorf("socketigo: proxy protocol: %w", err)
+			return
+		}
+		if addr != nil {
+			c.remote = addr
+		}
+	})
+}
+
+func (c *proxyConn) Read(p []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	if c.reader != nil {
+		return c.reader.Read(p)
+	}
+	return c.Conn.Read(p)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.readHeader()
+	return c.remote
+}
+
+// readProxyHeader reads a header of version 1 or 2 of the PROXY protocol. It
+// returns no address for headers of health checks of the proxy itself or of
+// address families other than TCP and UDP over IPv4 and IPv6.
+func readProxyHeader(reader *bufio.Reader) (net.Addr, error) {
+	signature, err := reader.Peek(len(proxyV2Signature))
+	if err == nil && bytes.Equal(signature, proxyV2Signature) {
+		return readProxyHeaderV2(reader)
+	}
+
+	prefix, err := reader.Peek(6)
+	if err != nil {
+		return nil, err
+	}
+	if string(prefix) != "PROXY " {
+		return nil, errors.New("missing header")
+	}
+	return readProxyHeaderV1(reader)
+}
+
+// readProxyHeaderV1 reads a line like "PROXY TCP4 192.0.2.1 192.0.2.2 56324 443".
+func readProxyHeaderV1(reader *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		// the longest valid header has 107 bytes
+		if len(line) >= 107 {
+			return nil, errors.New("header too long")
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("malformed header")
+	}
+
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errors.New("malformed header")
+	}
+
+	addr, err := netip.ParseAddr(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, err
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, uint16(port))), nil
+}
+
+// readProxyHeaderV2 reads the binary header: the signature, the version and
+// command, the address family and protocol, the length of the rest and the
+// addresses, followed by extensions which get skipped.
+func readProxyHeaderV2(reader *bufio.Reader) (net.Addr, error) {
+	var header [16]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported version %d", header[12]>>4)
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+
+	switch header[12] & 0x0f {
+	case 0x0:
+		// LOCAL, a connection of the proxy itself
+		return nil, nil
+	case 0x1:
+	default:
+		return nil, fmt.Errorf("unknown command %d", header[12]&0x0f)
+	}
+
+	var size int
+	switch header[13] >> 4 {
+	case 0x1:
+		size = 4
+	case 0x2:
+		size = 16
+	default:
+		return nil, nil
+	}
+	if len(payload) < 2*size+4 {
+		return nil, errors.New("malformed header")
+	}
+
+	addr, _ := netip.AddrFromSlice(payload[:size])
+	port := binary.BigEndian.Uint16(payload[2*size:])
+	addrPort := netip.AddrPortFrom(addr.Unmap(), port)
+	if header[13]&0x0f == 0x2 {
+		return net.UDPAddrFromAddrPort(addrPort), nil
+	}
+	return net.TCPAddrFromAddrPort(addrPort), nil
+}
@@ -0,0 +1,178 @@
+package socketigo_test
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/igoclient"
+)
+
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
+// remoteAddrs reports the RemoteAddr of every client connecting to the server.
+func remoteAddrs(server *socketigo.IgoServer) chan string {
+	addrs := make(chan string, 4)
+	server.OnConnected(func(client *socketigo.Client) {
+		addrs <- client.RemoteAddr()
+	})
+	return addrs
+}
+
+// expectRemoteAddr expects the next client to have the address, or the one of
+// its peer on the loopback interface if want is empty.
+func expectRemoteAddr(t *testing.T, addrs chan string, want string) {
+	t.Helper()
+
+	select {
+	case addr := <-addrs:
+		if want == "" && !strings.HasPrefix(addr, "127.0.0.1:") {
+			t.Fatalf("client has RemoteAddr %q, want the address of the peer", addr)
+		}
+		if want != "" && addr != want {
+			t.Fatalf("client has RemoteAddr %q, want %q", addr, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("client did not connect")
+	}
+}
+
+func TestTrustedProxyForwardedFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted []netip.Prefix
+		header  http.Header
+		want    string
+	}{
+		{"client of the proxy", loopback, http.Header{"X-Forwarded-For": {"203.0.113.7"}}, "203.0.113.7:0"},
+		// the hops in front of the first untrusted one are up to the client
+		{"spoofed hops", append(loopback, netip.MustParsePrefix("10.0.0.0/8")), http.Header{"X-Forwarded-For": {"198.51.100.1, 203.0.113.7, 10.0.0.2"}}, "203.0.113.7:0"},
+		{"real ip", loopback, http.Header{"X-Real-Ip": {"203.0.113.7"}}, "203.0.113.7:0"},
+		// an untrusted peer keeps its own address
+		{"untrusted peer", []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, http.Header{"X-Forwarded-For": {"203.0.113.7"}}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := socketigo.CreateIgoServer(&socketigo.IgoServerOptions{TrustedProxies: test.trusted})
+			addrs := remoteAddrs(server)
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+
+			client, err := igoclient.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), &igoclient.Options{Header: test.header, DisableReconnect: true})
+			if err != nil {
+				t.Fatalf("connecting failed: %v", err)
+			}
+			defer client.Close()
+			expectRemoteAddr(t, addrs, test.want)
+		})
+	}
+}
+
+// proxyFront forwards the connections it accepts to the address, starting each
+// with the header, as a load balancer speaking the PROXY protocol does.
+func proxyFront(t *testing.T, addr string, header []byte) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			upstream, err := net.Dial("tcp", addr)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			upstream.Write(header)
+			go func() {
+				io.Copy(upstream, conn)
+				upstream.Close()
+			}()
+			go func() {
+				io.Copy(conn, upstream)
+				conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func proxyHeaderV2(src netip.AddrPort, dst netip.AddrPort) []byte {
+	header := []byte("\r\n\r\n\x00\r\nQUIT\n")
+	// version 2, PROXY command, TCP over IPv4 and 12 bytes of addresses
+	header = append(header, 0x21, 0x11, 0, 12)
+	header = append(header, src.Addr().AsSlice()...)
+	header = append(header, dst.Addr().AsSlice()...)
+	header = binary.BigEndian.AppendUint16(header, src.Port())
+	return binary.BigEndian.AppendUint16(header, dst.Port())
+}
+
+func TestProxyProtocol(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		want   string
+	}{
+		{"version 1", []byte("PROXY TCP4 203.0.113.7 192.0.2.1 56324 443\r\n"), "203.0.113.7:56324"},
+		{"version 2", proxyHeaderV2(netip.MustParseAddrPort("203.0.113.7:56324"), netip.MustParseAddrPort("192.0.2.1:443")), "203.0.113.7:56324"},
+		// health checks of the proxy carry no client
+		{"version 1 unknown", []byte("PROXY UNKNOWN\r\n"), ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := echoServer(&socketigo.IgoServerOptions{TrustedProxies: loopback, ProxyProtocol: true})
+			addrs := remoteAddrs(server)
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			go server.ServeRaw(listener)
+			defer listener.Close()
+
+			addr := proxyFront(t, listener.Addr().String(), test.header)
+			client, err := igoclient.Dial("tcp://"+addr, &igoclient.Options{DisableReconnect: true})
+			if err != nil {
+				t.Fatalf("connecting failed: %v", err)
+			}
+			defer client.Close()
+
+			expectRemoteAddr(t, addrs, test.want)
+		})
+	}
+}
+
+func TestProxyProtocolUntrustedPeer(t *testing.T) {
+	// the proxy header is only read off the trusted proxies, anyone else is
+	// served as is
+	server := echoServer(&socketigo.IgoServerOptions{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, ProxyProtocol: true})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.ServeRaw(listener)
+	defer listener.Close()
+
+	addr := proxyFront(t, listener.Addr().String(), []byte("PROXY TCP4 203.0.113.7 192.0.2.1 56324 443\r\n"))
+	if client, err := igoclient.Dial("tcp://"+addr, &igoclient.Options{DisableReconnect: true}); err == nil {
+		client.Close()
+		t.Fatal("client sending a proxy header of its own got connected")
+	}
+}
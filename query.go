@@ -0,0 +1,97 @@
+package socketigo
+
+import (
+	"time"
+
+	uuid "github.com/google/uuid"
+)
+
+type ClientPredicate func(client *Client) bool
+
+// ClientSelection targets the clients matching a predicate. Predicates cannot
+// travel to other nodes, so only the clients of this node are targeted.
+type ClientSelection struct {
+	candidates func() []*Client
+	predicates []ClientPredicate
+}
+
+// MetadataEquals matches the clients having the value attached under the key.
+// The value has to be comparable.
+func MetadataEquals(key string, value interface{}) ClientPredicate {
+	return func(client *Client) bool {
+		current, ok := client.Get(key)
+		return ok && current == value
+	}
+}
+
+func filterClients(clients []*Client, predicate ClientPredicate) []*Client {
+	matching := make([]*Client, 0, len(clients))
+	for _, client := range clients {
+		if predicate(client) {
+			matching = append(matching, client)
+		}
+	}
+	return matching
+}
+
+// FindClients returns the clients of every namespace matching the predicate.
+func (s *IgoServer) FindClients(predicate ClientPredicate) []*Client {
+	return filterClients(s.clients.snapshot(), predicate)
+}
+
+func (n *Namespace) FindClients(predicate ClientPredicate) []*Client {
+	return filterClients(n.clients.snapshot(), predicate)
+}
+
+// Where selects the clients of every namespace matching the predicate.
+func (s *IgoServer) Where(predicate ClientPredicate) *ClientSelection {
+	return &ClientSelection{
+		candidates: s.clients.snapshot,
+		predicates: []ClientPredicate{predicate},
+	}
+}
+
+func (n *Namespace) Where(predicate ClientPredicate) *ClientSelection {
+	return &ClientSelection{
+		candidates: n.clients.snapshot,
+		predicates: []ClientPredicate{predicate},
+	}
+}
+
+// Where narrows the selection down to the clients matching the predicate too.
+func (c *ClientSelection) Where(predicate ClientPredicate) *ClientSelection {
+	return &ClientSelection{
+		candidates: c.candidates,
+		predicates: append(append([]ClientPredicate(nil), c.predicates...), predicate),
+	}
+}
+
+// Clients resolves the selected clients, the predicates run on every call.
+func (c *ClientSelection) Clients() []*Client {
+	clients := c.candidates()
+	for _, predicate := range c.predicates {
+		clients = filterClients(clients, predicate)
+	}
+	return clients
+}
+
+func (c *ClientSelection) Emit(eventName string, data interface{}) {
+	clients := c.Clients()
+	if len(clients) == 0 {
+		return
+	}
+	clients[0].Server.emitToClients(clients, map[string]interface{}{
+		"event": eventName,
+		"data":  data,
+	}, nil, false)
+}
+
+func (c *ClientSelection) EmitBinary(eventName string, data []byte) {
+	for _, client := range c.Clients() {
+		client.EmitBinary(eventName, data)
+	}
+}
+
+func (c *ClientSelection) EmitWithAcks(eventName string, data interface{}, timeout time.Duration) map[uuid.UUID]AckResult {
+	return emitWithAcks(c.Clients(), eventName, data, timeout)
+}
@@ -0,0 +1,175 @@
+package socketigo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimitedCode   = "rate_limited"
+	rateLimitWarnRate = time.Second
+)
+
+var ErrRateLimited = errors.New("socketigo: rate limit exceeded")
+
+// RateLimitPolicy decides what happens with messages above the rate limit of a
+// client.
+type RateLimitPolicy int
+
+const (
+	// RateLimitDrop drops the message and sends an error frame.
+	RateLimitDrop RateLimitPolicy = iota
+	// RateLimitWarn still handles the message but sends an error frame.
+	RateLimitWarn
+	// RateLimitDisconnect disconnects the client with ReasonRateLimited.
+	RateLimitDisconnect
+)
+
+func (p RateLimitPolicy) String() string {
+	switch p {
+	case RateLimitDrop:
+		return "drop"
+	case RateLimitWarn:
+		return "warn"
+	case RateLimitDisconnect:
+		return "disconnect"
+	default:
+		return "unknown"
+	}
+}
+
+// RateLimit limits the messages a client may send with token buckets. Zero
+// rates are unlimited. The bursts default to a second worth of the rate, so a
+// message larger than ByteBurst is always above the limit.
+type RateLimit struct {
+	Messages     float64
+	MessageBurst int
+	Bytes        float64
+	ByteBurst    int
+	Policy       RateLimitPolicy
+}
+
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+
+	size := float64(burst)
+	if burst <= 0 {
+		size = math.Max(1, math.Ceil(rate))
+	}
+	return &tokenBucket{rate: rate, burst: size, tokens: size, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+type rateLimiter struct {
+	mu         sync.Mutex
+	policy     RateLimitPolicy
+	messages   *tokenBucket
+	bytes      *tokenBucket
+	lastWarned time.Time
+}
+
+func newRateLimiter(limit *RateLimit) *rateLimiter {
+	if limit == nil {
+		return nil
+	}
+
+	now := time.Now()
+	return &rateLimiter{
+		policy:   limit.Policy,
+		messages: newTokenBucket(limit.Messages, limit.MessageBurst, now),
+		bytes:    newTokenBucket(limit.Bytes, limit.ByteBurst, now),
+	}
+}
+
+// allow takes the tokens of a message of the given size, only if both buckets
+// hold enough of them.
+func (l *rateLimiter) allow(size int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.messages != nil {
+		l.messages.refill(now)
+		if l.messages.tokens < 1 {
+			return false
+		}
+	}
+	if l.bytes != nil {
+		l.bytes.refill(now)
+		if l.bytes.tokens < float64(size) {
+			return false
+		}
+		l.bytes.tokens -= float64(size)
+	}
+	if l.messages != nil {
+		l.messages.tokens--
+	}
+	return true
+}
+
+// warn reports whether the client should be told about a violation, which
+// happens at most once per second.
+func (l *rateLimiter) warn() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastWarned) < rateLimitWarnRate {
+		return false
+	}
+	l.lastWarned = now
+	return true
+}
+
+// SetRateLimit replaces the rate limit of the client, which defaults to the
+// RateLimit option. Nil lifts the limit.
+func (c *Client) SetRateLimit(limit *RateLimit) {
+	c.limiterMu.Lock()
+	defer c.limiterMu.Unlock()
+
+	c.limiter = newRateLimiter(limit)
+}
+
+// allowMessage applies the rate limit to a message the client sent and
+// reports whether it should be handled.
+func (c *Client) allowMessage(size int) bool {
+	c.limiterMu.RLock()
+	limiter := c.limiter
+	c.limiterMu.RUnlock()
+
+	if limiter == nil || limiter.allow(size) {
+		return true
+	}
+
+	if limiter.policy == RateLimitDisconnect {
+		c.Server.emitError(c, nil, ErrRateLimited, nil)
+		c.closeWithReason(ReasonRateLimited)
+		return false
+	}
+
+	if limiter.warn() {
+		err := fmt.Errorf("%w: policy %s", ErrRateLimited, limiter.policy)
+		c.Server.emitError(c, nil, nil, err)
+		c.Emit(errorEvent, map[string]interface{}{
+			"code":    rateLimitedCode,
+			"message": err.Error(),
+		})
+	}
+	return limiter.policy == RateLimitWarn
+}
@@ -0,0 +1,123 @@
+package socketigo_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/igoclient"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+// rateLimitErrors reports the rate_limited error frames the client receives.
+func rateLimitErrors(client *testclient.Client) chan error {
+	errs := make(chan error, 8)
+	client.OnError(func(client *igoclient.Client, err error) {
+		var serverErr *igoclient.ServerError
+		if errors.As(err, &serverErr) && serverErr.Code == "rate_limited" {
+			errs <- err
+		}
+	})
+	return errs
+}
+
+func TestRateLimitDrop(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{RateLimit: &socketigo.RateLimit{Messages: 1, MessageBurst: 2}})
+
+	client := testclient.MustConnect(t, server, nil)
+	errs := rateLimitErrors(client)
+	for i := 0; i < 4; i++ {
+		client.Emit("echo", map[string]interface{}{"n": i})
+	}
+
+	// the burst gets through, the rest is dropped
+	for i := 0; i < 2; i++ {
+		if data := client.ExpectEvent(t, "echo"); data["n"] != float64(i) {
+			t.Fatalf("echo is %v, want n %d", data, i)
+		}
+	}
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("dropped message did not get answered with an error")
+	}
+	client.ExpectNoEvent(t, "echo", 50*time.Millisecond)
+}
+
+func TestRateLimitWarn(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{RateLimit: &socketigo.RateLimit{Messages: 1, MessageBurst: 1, Policy: socketigo.RateLimitWarn}})
+
+	client := testclient.MustConnect(t, server, nil)
+	errs := rateLimitErrors(client)
+	for i := 0; i < 3; i++ {
+		client.Emit("echo", map[string]interface{}{"n": i})
+	}
+
+	// every message is handled, the client only gets warned
+	for i := 0; i < 3; i++ {
+		client.ExpectEvent(t, "echo")
+	}
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("client above the limit did not get warned")
+	}
+}
+
+func TestRateLimitDisconnect(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{RateLimit: &socketigo.RateLimit{Messages: 1, MessageBurst: 1, Policy: socketigo.RateLimitDisconnect}})
+	reasons := make(chan socketigo.DisconnectReason, 1)
+	server.OnConnected(func(client *socketigo.Client) {
+		client.OnDisconnect(func(reason socketigo.DisconnectReason) {
+			reasons <- reason
+		})
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	client.Emit("echo", nil)
+	client.Emit("echo", nil)
+
+	select {
+	case reason := <-reasons:
+		if reason != socketigo.ReasonRateLimited {
+			t.Fatalf("disconnect reason is %v, want %v", reason, socketigo.ReasonRateLimited)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("client above the limit did not get disconnected")
+	}
+}
+
+func TestRateLimitBytes(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{RateLimit: &socketigo.RateLimit{Bytes: 1 << 10, ByteBurst: 1 << 10}})
+
+	client := testclient.MustConnect(t, server, nil)
+	errs := rateLimitErrors(client)
+	// a message larger than the burst never fits
+	client.Emit("echo", map[string]interface{}{"text": string(make([]byte, 2<<10))})
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("message above the byte burst did not get answered with an error")
+	}
+	client.ExpectNoEvent(t, "echo", 50*time.Millisecond)
+}
+
+func TestSetRateLimit(t *testing.T) {
+	server := echoServer(&socketigo.IgoServerOptions{RateLimit: &socketigo.RateLimit{Messages: 1, MessageBurst: 1}})
+	server.OnConnected(func(client *socketigo.Client) {
+		if client.Header().Get("X-Trusted") != "" {
+			client.SetRateLimit(nil)
+		}
+	})
+
+	client := testclient.MustConnect(t, server, &testclient.Options{Header: http.Header{"X-Trusted": {"1"}}})
+	for i := 0; i < 5; i++ {
+		client.Emit("echo", map[string]interface{}{"n": i})
+	}
+	// the lifted limit lets every message through
+	for i := 0; i < 5; i++ {
+		client.ExpectEvent(t, "echo")
+	}
+}
@@ -0,0 +1,64 @@
+package socketigo
+
+import (
+	"errors"
+
+	"github.com/goccy/go-json"
+)
+
+var ErrInvalidRawPayload = errors.New("socketigo: raw payload is not valid JSON")
+
+// rawData checks a pre-encoded payload. It is embedded as it is into the frames
+// of JSONCodec, other codecs get it decoded since the wire format is theirs.
+func (s *IgoServer) rawData(payload json.RawMessage) (interface{}, error) {
+	if !json.Valid(payload) {
+		return nil, ErrInvalidRawPayload
+	}
+	if _, isJSON := s.codec.(JSONCodec); isJSON {
+		return payload, nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, ErrInvalidRawPayload
+	}
+	return data, nil
+}
+
+// EmitRaw emits the event with a payload which already is JSON, e.g. taken from
+// a message bus, without decoding and encoding it again.
+func (c *Client) EmitRaw(eventName string, payload json.RawMessage) error {
+	data, err := c.Server.rawData(payload)
+	if err != nil {
+		return err
+	}
+	return c.Emit(eventName, data)
+}
+
+// EmitRaw broadcasts a pre-encoded payload to the namespace, see Client.EmitRaw.
+func (n *Namespace) EmitRaw(eventName string, payload json.RawMessage) error {
+	data, err := n.server.rawData(payload)
+	if err != nil {
+		return err
+	}
+	n.Emit(eventName, data)
+	return nil
+}
+
+func (b *BroadcastOperator) EmitRaw(eventName string, payload json.RawMessage) error {
+	data, err := b.namespace.server.rawData(payload)
+	if err != nil {
+		return err
+	}
+	b.Emit(eventName, data)
+	return nil
+}
+
+func (r *Room) EmitRaw(eventName string, payload json.RawMessage) error {
+	data, err := r.Namespace.server.rawData(payload)
+	if err != nil {
+		return err
+	}
+	r.Emit(eventName, data)
+	return nil
+}
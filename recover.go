@@ -0,0 +1,54 @@
+package socketigo
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+const internalErrorCode = "internal_error"
+
+var ErrListenerPanic = errors.New("socketigo: listener panicked")
+
+// PanicError is reported to the error handler when a listener panicked. The
+// client stays connected.
+type PanicError struct {
+	Event string
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v: event %q: %v", ErrListenerPanic, e.Event, e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrListenerPanic
+}
+
+// handlePanic reports a recovered panic and answers a pending ack or call of
+// the frame with an error, so the client does not wait for it until its
+// timeout. The panic value
+// itself is only sent to the client with SendPanicErrors.
+func (c *Client) handlePanic(eventName string, frame *Frame, value interface{}) error {
+	panicErr := &PanicError{
+		Event: eventName,
+		Value: value,
+		Stack: debug.Stack(),
+	}
+	c.Server.emitError(c, nil, nil, panicErr)
+
+	message := "internal error"
+	if c.Server.sendPanicErrors {
+		message = panicErr.Error()
+
+		c.Emit(errorEvent, map[string]interface{}{
+			"code":    internalErrorCode,
+			"message": message,
+			"event":   eventName,
+		})
+	}
+
+	c.acknowledge(frame, errors.New(message))
+	return panicErr
+}
@@ -0,0 +1,96 @@
+// Package redisadapter relays socket.igo broadcasts between server instances
+// over Redis pub/sub and stores the messages of offline users in Redis.
+package redisadapter
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/goccy/go-json"
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultChannel = "socketigo"
+
+type Options struct {
+	// Channel is the pub/sub channel shared by all instances. Defaults to "socketigo".
+	Channel string
+}
+
+// Broker implements socketigo.Broker on top of a Redis client.
+type Broker struct {
+	client  redis.UniversalClient
+	channel string
+	mu      sync.Mutex
+	pubsub  *redis.PubSub
+}
+
+func New(client redis.UniversalClient, options *Options) *Broker {
+	channel := defaultChannel
+	if options != nil && options.Channel != "" {
+		channel = options.Channel
+	}
+
+	return &Broker{
+		client:  client,
+		channel: channel,
+	}
+}
+
+func (b *Broker) Publish(packet *socketigo.BroadcastPacket) error {
+	data, err := json.Marshal(packet)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(context.Background(), b.channel, data).Err()
+}
+
+func (b *Broker) Subscribe(handler func(packet *socketigo.BroadcastPacket)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.pubsub != nil {
+		return errors.New("redisadapter: already subscribed")
+	}
+
+	ctx := context.Background()
+	pubsub := b.client.Subscribe(ctx, b.channel)
+
+	// wait for the subscription to be confirmed, so no broadcast gets lost
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return err
+	}
+	b.pubsub = pubsub
+
+	go func() {
+		for message := range pubsub.Channel() {
+			packet := &socketigo.BroadcastPacket{}
+			if err := json.Unmarshal([]byte(message.Payload), packet); err != nil {
+				continue
+			}
+			handler(packet)
+		}
+	}()
+	return nil
+}
+
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.pubsub == nil {
+		return nil
+	}
+
+	err := b.pubsub.Close()
+	b.pubsub = nil
+	return err
+}
+
+// Health pings Redis, see socketigo.HealthChecker.
+func (b *Broker) Health(ctx context.Context) error {
+	return b.client.Ping(ctx).Err()
+}
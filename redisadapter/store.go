@@ -0,0 +1,91 @@
+package redisadapter
+
+import (
+	"context"
+	"time"
+
+	"github.com/goccy/go-json"
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultKeyPrefix = "socketigo:offline:"
+
+// pushScript appends to the list and only ever extends its expiry, so messages
+// with a shorter TTL never expire the ones queued before them.
+var pushScript = redis.NewScript(`
+local ttl = redis.call("PTTL", KEYS[1])
+redis.call("RPUSH", KEYS[1], ARGV[1])
+if ttl < tonumber(ARGV[2]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+type StoreOptions struct {
+	// KeyPrefix is prepended to the user id to get the key of its message list.
+	// Defaults to "socketigo:offline:".
+	KeyPrefix string
+}
+
+// Store implements socketigo.Store with a Redis list per user, which expires
+// together with its longest living message.
+type Store struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewStore(client redis.UniversalClient, options *StoreOptions) *Store {
+	prefix := defaultKeyPrefix
+	if options != nil && options.KeyPrefix != "" {
+		prefix = options.KeyPrefix
+	}
+
+	return &Store{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *Store) Push(userId string, message *socketigo.StoredMessage) error {
+	ttl := time.Until(message.ExpiresAt).Milliseconds()
+	if ttl <= 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	return pushScript.Run(ctx, s.client, []string{s.prefix + userId}, data, ttl).Err()
+}
+
+func (s *Store) Drain(userId string) ([]*socketigo.StoredMessage, error) {
+	ctx := context.Background()
+	key := s.prefix + userId
+
+	var values *redis.StringSliceCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		values = pipe.LRange(ctx, key, 0, -1)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	messages := make([]*socketigo.StoredMessage, 0, len(values.Val()))
+	for _, value := range values.Val() {
+		message := &socketigo.StoredMessage{}
+		if err := json.Unmarshal([]byte(value), message); err != nil {
+			continue
+		}
+		if !message.Expired(now) {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
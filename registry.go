@@ -0,0 +1,107 @@
+package socketigo
+
+import (
+	"sync"
+
+	uuid "github.com/google/uuid"
+)
+
+type clientRegistry struct {
+	mu      sync.RWMutex
+	clients []*Client
+	byId    map[uuid.UUID]*Client
+}
+
+func newClientRegistry() *clientRegistry {
+	return &clientRegistry{
+		clients: make([]*Client, 0),
+		byId:    make(map[uuid.UUID]*Client),
+	}
+}
+
+func (r *clientRegistry) add(client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.byId[client.Id]; ok {
+		return
+	}
+
+	r.clients = append(r.clients, client)
+	r.byId[client.Id] = client
+}
+
+func (r *clientRegistry) remove(client *Client) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.byId[client.Id] != client {
+		return false
+	}
+	delete(r.byId, client.Id)
+
+	for i, c := range r.clients {
+		if c == client {
+			r.clients = append(r.clients[:i], r.clients[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (r *clientRegistry) has(client *Client) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.byId[client.Id] == client
+}
+
+func (r *clientRegistry) get(id uuid.UUID) *Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.byId[id]
+}
+
+// snapshot returns a copy of the registered clients, so callers can iterate
+// and emit without holding the lock while writing to sockets.
+func (r *clientRegistry) snapshot() []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clients := make([]*Client, len(r.clients))
+	copy(clients, r.clients)
+	return clients
+}
+
+func (r *clientRegistry) each(fn func(client *Client)) {
+	for _, client := range r.snapshot() {
+		fn(client)
+	}
+}
+
+func (r *clientRegistry) len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.clients)
+}
+
+// replace swaps the registered client for another one with the same Id.
+func (r *clientRegistry) replace(old *Client, client *Client) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.byId[old.Id] != old {
+		return false
+	}
+	r.byId[client.Id] = client
+
+	for i, c := range r.clients {
+		if c == old {
+			r.clients[i] = client
+			break
+		}
+	}
+	return true
+}
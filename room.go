@@ -1,10 +1,46 @@
 package socketigo
 
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	uuid "github.com/google/uuid"
+)
+
+const roomFullEvent = "room:full"
+
+var (
+	ErrRoomFull    = errors.New("socketigo: room is full")
+	ErrJoinRefused = errors.New("socketigo: join refused")
+)
+
 type Room struct {
-	Id            string
-	clients       []*Client
-	joinedHandler func(client *Client)
-	leftHandler   func(client *Client)
+	Id             string
+	Namespace      *Namespace
+	clients        *clientRegistry
+	joinedHandler  func(client *Client)
+	leftHandler    func(client *Client)
+	emptyHandler   func(room *Room)
+	createdAt      time.Time
+	metadataMu     sync.RWMutex
+	topic          string
+	owner          string
+	metadata       map[string]interface{}
+	joinMu         sync.Mutex
+	maxClients     int
+	notifyFull     bool
+	joinGuard      func(client *Client) error
+	ttlTimer       *time.Timer
+	emptyTTL       time.Duration
+	emptyTimer     *time.Timer
+	expired        bool
+	expiredHandler func(room *Room)
+	presence       *presence
+	history        *roomHistory
+	seq            uint64
 }
 
 func (r *Room) OnClientJoined(listener func(client *Client)) {
@@ -15,16 +51,143 @@ func (r *Room) OnClientLeft(listener func(client *Client)) {
 	r.leftHandler = listener
 }
 
-func (r *Room) Emit(eventName string, data interface{}) {
-	for _, client := range r.clients {
-		client.Emit(eventName, data)
+// Clients returns a copy of the members of the room on this node.
+func (r *Room) Clients() []*Client {
+	return r.clients.snapshot()
+}
+
+func (r *Room) Size() int {
+	return r.clients.len()
+}
+
+func (r *Room) Has(client *Client) bool {
+	return r.clients.has(client)
+}
+
+// SetMaxClients caps the members of the room, further joins fail with
+// ErrRoomFull. Zero removes the cap.
+func (r *Room) SetMaxClients(n int) {
+	r.joinMu.Lock()
+	defer r.joinMu.Unlock()
+
+	r.maxClients = n
+}
+
+// NotifyFull emits "room:full" carrying {"room"} to every client failing to
+// join because the room is at capacity.
+func (r *Room) NotifyFull(notify bool) {
+	r.joinMu.Lock()
+	defer r.joinMu.Unlock()
+
+	r.notifyFull = notify
+}
+
+// SetJoinGuard gates joining the room, e.g. on a password, an invitation or a
+// role. Join fails with the error of the guard, wrapped in ErrJoinRefused.
+func (r *Room) SetJoinGuard(guard func(client *Client) error) {
+	r.joinMu.Lock()
+	defer r.joinMu.Unlock()
+
+	r.joinGuard = guard
+}
+
+func (r *Room) guard(client *Client) error {
+	r.joinMu.Lock()
+	guard := r.joinGuard
+	r.joinMu.Unlock()
+
+	if guard == nil || r.clients.has(client) {
+		return nil
 	}
+	if err := guard(client); err != nil {
+		return fmt.Errorf("%w: %w", ErrJoinRefused, err)
+	}
+	return nil
 }
 
-func (r *Room) EmitExcept(client *Client, eventName string, data interface{}) {
-	for _, c := range r.clients {
-		if c != client {
-			c.Emit(eventName, data)
+// add makes the client a member unless the room is full. Joining a room twice
+// is a no-op.
+func (r *Room) add(client *Client) (bool, error) {
+	r.joinMu.Lock()
+	defer r.joinMu.Unlock()
+
+	if r.expired {
+		return false, ErrRoomExpired
+	}
+	if r.clients.has(client) {
+		return false, nil
+	}
+	if r.maxClients > 0 && r.clients.len() >= r.maxClients {
+		if r.notifyFull {
+			client.Emit(roomFullEvent, map[string]interface{}{"room": r.Id})
 		}
+		return false, ErrRoomFull
+	}
+
+	client.Server.adapter.AddToRoom(r, client)
+	r.armEmptyTimer()
+	return true, nil
+}
+
+// OnEmpty gets called once the last client left the room, before the room gets
+// deleted with DeleteEmptyRooms, so its state can be persisted.
+func (r *Room) OnEmpty(listener func(room *Room)) {
+	r.emptyHandler = listener
+}
+
+// removed runs the handlers of a client which left the room.
+func (r *Room) removed(client *Client) {
+	r.presenceLeft(client)
+	if r.leftHandler != nil {
+		r.leftHandler(client)
+	}
+	if r.clients.len() > 0 {
+		return
+	}
+
+	r.joinMu.Lock()
+	r.armEmptyTimer()
+	r.joinMu.Unlock()
+
+	if r.emptyHandler != nil {
+		r.emptyHandler(r)
+	}
+	// someone may have joined from the empty handler
+	if r.Namespace.server.deleteEmptyRooms && r.clients.len() == 0 {
+		r.Namespace.DeleteRoom(r)
+	}
+}
+
+func (r *Room) Emit(eventName string, data interface{}) {
+	r.record(eventName, data)
+	r.Namespace.emitPacket(&BroadcastPacket{
+		Rooms:   []string{r.Id},
+		Event:   eventName,
+		Data:    data,
+		RoomSeq: r.nextSeq(),
+	})
+}
+
+func (r *Room) EmitExcept(client *Client, eventName string, data interface{}) {
+	r.record(eventName, data)
+	r.Namespace.emitPacket(&BroadcastPacket{
+		Rooms:   []string{r.Id},
+		Except:  []uuid.UUID{client.Id},
+		Event:   eventName,
+		Data:    data,
+		RoomSeq: r.nextSeq(),
+	})
+}
+
+// Seq returns the sequence number of the last event emitted to the room on
+// this node, see IgoServerOptions.SequenceNumbers.
+func (r *Room) Seq() uint64 {
+	return atomic.LoadUint64(&r.seq)
+}
+
+func (r *Room) nextSeq() uint64 {
+	if !r.Namespace.server.sequenceNumbers {
+		return 0
 	}
+	return atomic.AddUint64(&r.seq, 1)
 }
@@ -0,0 +1,102 @@
+package socketigo_test
+
+import (
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+// roomServer lets clients join a room with "join" and say something to the
+// rest of it with "say".
+func roomServer() *socketigo.IgoServer {
+	server := socketigo.CreateIgoServer(nil)
+	server.On("join", func(client *socketigo.Client, data map[string]interface{}) interface{} {
+		room, _ := data["room"].(string)
+		if err := client.Join(client.Namespace.CreateRoom(room)); err != nil {
+			return err
+		}
+		return true
+	})
+	server.On("say", func(client *socketigo.Client, data map[string]interface{}) interface{} {
+		room, _ := data["room"].(string)
+		client.Namespace.GetRoom(room).EmitExcept(client, "said", data)
+		return nil
+	})
+	return server
+}
+
+func TestRoomEmitExcept(t *testing.T) {
+	server := roomServer()
+
+	alice := testclient.MustConnect(t, server, nil)
+	bob := testclient.MustConnect(t, server, nil)
+	carol := testclient.MustConnect(t, server, nil)
+	alice.ExpectAck(t, "join", map[string]interface{}{"room": "lobby"})
+	bob.ExpectAck(t, "join", map[string]interface{}{"room": "lobby"})
+
+	alice.Emit("say", map[string]interface{}{"room": "lobby", "text": "hi"})
+
+	if data := bob.ExpectEvent(t, "said"); data["text"] != "hi" {
+		t.Fatalf("bob got %v, want the text of alice", data)
+	}
+	alice.ExpectNoEvent(t, "said", 50*time.Millisecond)
+	carol.ExpectNoEvent(t, "said", 50*time.Millisecond)
+}
+
+func TestCreateRoomReturnsExistingRoom(t *testing.T) {
+	server := roomServer()
+
+	alice := testclient.MustConnect(t, server, nil)
+	bob := testclient.MustConnect(t, server, nil)
+	alice.ExpectAck(t, "join", map[string]interface{}{"room": "lobby"})
+	bob.ExpectAck(t, "join", map[string]interface{}{"room": "lobby"})
+
+	room := server.CreateRoom("lobby")
+	if room != server.GetRoom("lobby") {
+		t.Fatal("CreateRoom created a second room of the same name")
+	}
+	if size := len(room.Clients()); size != 2 {
+		t.Fatalf("room has %d clients, want 2", size)
+	}
+}
+
+func TestBroadcastExcept(t *testing.T) {
+	server := roomServer()
+	connected := make(chan *socketigo.Client, 3)
+	server.OnConnected(func(client *socketigo.Client) {
+		connected <- client
+	})
+
+	alice := testclient.MustConnect(t, server, nil)
+	first := <-connected
+	bob := testclient.MustConnect(t, server, nil)
+	<-connected
+	carol := testclient.MustConnect(t, server, nil)
+	<-connected
+	alice.ExpectAck(t, "join", map[string]interface{}{"room": "staff"})
+	carol.ExpectAck(t, "join", map[string]interface{}{"room": "staff"})
+
+	server.To().Except(first).ExceptRooms("staff").Emit("news", nil)
+	bob.ExpectEvent(t, "news")
+	alice.ExpectNoEvent(t, "news", 50*time.Millisecond)
+	carol.ExpectNoEvent(t, "news", 50*time.Millisecond)
+}
+
+func TestBroadcastJoinAndLeave(t *testing.T) {
+	server := roomServer()
+
+	alice := testclient.MustConnect(t, server, nil)
+	bob := testclient.MustConnect(t, server, nil)
+	alice.ExpectAck(t, "join", map[string]interface{}{"room": "lobby"})
+
+	server.To("lobby").Join("announcements")
+	server.To("announcements").Emit("notice", nil)
+	alice.ExpectEvent(t, "notice")
+	bob.ExpectNoEvent(t, "notice", 50*time.Millisecond)
+
+	server.To("lobby").Leave("announcements")
+	server.To("announcements").Emit("notice", nil)
+	alice.ExpectNoEvent(t, "notice", 50*time.Millisecond)
+}
@@ -0,0 +1,98 @@
+package socketigo
+
+import (
+	"errors"
+	"time"
+)
+
+const roomExpiredEvent = "room:expired"
+
+var ErrRoomExpired = errors.New("socketigo: room expired")
+
+// SetTTL expires the room once ttl elapsed, counted from now. Zero cancels it.
+func (r *Room) SetTTL(ttl time.Duration) {
+	r.joinMu.Lock()
+	defer r.joinMu.Unlock()
+
+	if r.ttlTimer != nil {
+		r.ttlTimer.Stop()
+		r.ttlTimer = nil
+	}
+	if ttl > 0 && !r.expired {
+		r.ttlTimer = time.AfterFunc(ttl, r.expire)
+	}
+}
+
+// SetEmptyTTL expires the room once it has been empty for ttl. Zero cancels
+// it.
+func (r *Room) SetEmptyTTL(ttl time.Duration) {
+	r.joinMu.Lock()
+	defer r.joinMu.Unlock()
+
+	r.emptyTTL = ttl
+	r.armEmptyTimer()
+}
+
+// OnExpired gets called once the room expired, after its remaining members
+// got "room:expired" carrying {"room"} and left it.
+func (r *Room) OnExpired(listener func(room *Room)) {
+	r.expiredHandler = listener
+}
+
+// armEmptyTimer starts the empty timer of an empty room and stops it once
+// somebody joined. It requires joinMu.
+func (r *Room) armEmptyTimer() {
+	if r.emptyTimer != nil {
+		r.emptyTimer.Stop()
+		r.emptyTimer = nil
+	}
+	if r.emptyTTL <= 0 || r.expired || r.clients.len() > 0 {
+		return
+	}
+
+	r.emptyTimer = time.AfterFunc(r.emptyTTL, func() {
+		r.joinMu.Lock()
+		empty := r.clients.len() == 0
+		r.joinMu.Unlock()
+
+		if empty {
+			r.expire()
+		}
+	})
+}
+
+func (r *Room) stopTimers() {
+	r.joinMu.Lock()
+	defer r.joinMu.Unlock()
+
+	if r.ttlTimer != nil {
+		r.ttlTimer.Stop()
+	}
+	if r.emptyTimer != nil {
+		r.emptyTimer.Stop()
+	}
+}
+
+func (r *Room) expire() {
+	r.joinMu.Lock()
+	if r.expired {
+		r.joinMu.Unlock()
+		return
+	}
+	r.expired = true
+	r.joinMu.Unlock()
+	r.stopTimers()
+
+	r.Emit(roomExpiredEvent, map[string]interface{}{"room": r.Id})
+
+	for _, client := range r.clients.snapshot() {
+		if r.Namespace.server.adapter.RemoveFromRoom(r, client) && r.leftHandler != nil {
+			r.leftHandler(client)
+		}
+	}
+
+	if r.expiredHandler != nil {
+		r.expiredHandler(r)
+	}
+	r.Namespace.DeleteRoom(r)
+}
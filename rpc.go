@@ -0,0 +1,187 @@
+package socketigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// Calls are events carrying a "callId", answered with a "#reply" event whose
+// data holds the id along with the result or the error of the listeners. Any
+// listener serves calls the same way it serves acked events.
+
+const (
+	replyEvent         = "#reply"
+	defaultCallTimeout = 30 * time.Second
+)
+
+// Codes of the RPCError a call fails with.
+const (
+	CodeNotFound        = "not_found"
+	CodeInvalidArgument = "invalid_argument"
+	CodeInternal        = "internal"
+)
+
+// RPCError is the error a call fails with once the other side answered it
+// with one. Listeners return it to answer a call with a code of their own,
+// other errors are answered with CodeInternal, or CodeInvalidArgument for
+// payloads failing to decode or validate.
+type RPCError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return "socketigo: call failed (" + e.Code + "): " + e.Message
+}
+
+// fields returns the error as a map, binary codecs not knowing the json tags.
+func (e *RPCError) fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"code":    e.Code,
+		"message": e.Message,
+	}
+	if e.Data != nil {
+		fields["data"] = e.Data
+	}
+	return fields
+}
+
+type callReply struct {
+	Id     string          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *RPCError       `json:"error,omitempty"`
+}
+
+func rpcError(err error) *RPCError {
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return &RPCError{Code: CodeInvalidArgument, Message: err.Error(), Data: validationErr.Fields}
+	case errors.Is(err, ErrDecodeFailed), errors.Is(err, ErrMalformedFrame), errors.Is(err, ErrInvalidPayload):
+		return &RPCError{Code: CodeInvalidArgument, Message: err.Error()}
+	}
+	return &RPCError{Code: CodeInternal, Message: err.Error()}
+}
+
+// reply answers the call with the result of its listeners.
+func (c *Client) reply(callId string, result interface{}) {
+	data := map[string]interface{}{
+		"id": callId,
+	}
+	if err, ok := result.(error); ok {
+		data["error"] = rpcError(err).fields()
+	} else {
+		data["result"] = result
+	}
+	c.Emit(replyEvent, data)
+}
+
+// Call calls the method, a listener of the client, with req and decodes its
+// result into resp, which may be nil. It fails with an RPCError once the
+// client answered with an error and gives up once ctx is done, after 30
+// seconds for contexts without a deadline. Like EmitWithAck, calling it from
+// a listener of the same client has to happen in a separate goroutine unless
+// listeners run on workers.
+func (c *Client) Call(ctx context.Context, method string, req interface{}, resp interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultCallTimeout)
+		defer cancel()
+	}
+
+	callId := strconv.FormatUint(atomic.AddUint64(&c.ackSeq, 1), 36)
+	reply := make(chan callReply, 1)
+
+	c.acksMu.Lock()
+	if c.calls == nil {
+		c.calls = make(map[string]chan callReply)
+	}
+	c.calls[callId] = reply
+	c.acksMu.Unlock()
+
+	defer func() {
+		c.acksMu.Lock()
+		delete(c.calls, callId)
+		c.acksMu.Unlock()
+	}()
+
+	trace, end := c.Server.tracer.StartAck(ctx, c, method)
+	frame := map[string]interface{}{
+		"event":  method,
+		"data":   req,
+		"callId": callId,
+	}
+	if len(trace) > 0 {
+		frame["trace"] = trace
+	}
+
+	err := c.awaitReply(ctx, method, frame, reply, resp)
+	end(err)
+	return err
+}
+
+func (c *Client) awaitReply(ctx context.Context, method string, frame map[string]interface{}, reply chan callReply, resp interface{}) error {
+	if err := c.enqueueFrame(frame); err != nil {
+		return err
+	}
+
+	select {
+	case answer := <-reply:
+		if answer.Error != nil {
+			return answer.Error
+		}
+		if resp == nil || len(answer.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(answer.Result, resp); err != nil {
+			return fmt.Errorf("%w: %w", ErrDecodeFailed, err)
+		}
+		return nil
+	case <-c.done:
+		return ErrClientClosed
+	case <-ctx.Done():
+		select {
+		case <-c.done:
+			return ErrClientClosed
+		default:
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			c.Server.metrics.AckTimeout(c.Namespace.Name, method)
+			c.Server.logger.Debug("call timed out", c.logFields("event", method)...)
+		}
+		return ctx.Err()
+	}
+}
+
+// resolveReply hands a reply to the waiting Call, replies to calls that gave
+// up already are dropped.
+func (c *Client) resolveReply(frame *Frame) {
+	var answer callReply
+	if err := frame.decode(&answer); err != nil {
+		c.rejectFrame(frame, err)
+		return
+	}
+
+	c.acksMu.Lock()
+	reply, ok := c.calls[answer.Id]
+	if ok {
+		delete(c.calls, answer.Id)
+	}
+	c.acksMu.Unlock()
+
+	if ok {
+		reply <- answer
+	}
+}
@@ -0,0 +1,173 @@
+package socketigo
+
+import (
+	"sync"
+	"time"
+)
+
+// Emitter is anything events can be emitted to: a namespace, a room, a
+// broadcast operator, a selection, a user or, through AsEmitter, a client.
+type Emitter interface {
+	Emit(eventName string, data interface{})
+}
+
+type clientEmitter struct {
+	client *Client
+}
+
+func (e clientEmitter) Emit(eventName string, data interface{}) {
+	e.client.Emit(eventName, data)
+}
+
+// AsEmitter returns the client as an Emitter, dropping the error of Emit.
+func (c *Client) AsEmitter() Emitter {
+	return clientEmitter{client: c}
+}
+
+// Scheduled is an emit scheduled with EmitAfter.
+type Scheduled struct {
+	server *IgoServer
+	mu     sync.Mutex
+	timer  *time.Timer
+	done   bool
+}
+
+// Cancel stops the emit and reports whether it did not happen yet.
+func (s *Scheduled) Cancel() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return false
+	}
+	s.done = true
+	s.timer.Stop()
+	s.server.unschedule(s)
+	return true
+}
+
+// finish marks the emit as happened and reports whether it was still pending.
+func (s *Scheduled) finish() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return false
+	}
+	s.done = true
+	s.server.unschedule(s)
+	return true
+}
+
+// EmitAfter emits the event to the target once delay elapsed. Pending emits
+// get canceled by Shutdown.
+func (s *IgoServer) EmitAfter(delay time.Duration, target Emitter, eventName string, data interface{}) *Scheduled {
+	scheduled := &Scheduled{server: s}
+
+	s.scheduledMu.Lock()
+	defer s.scheduledMu.Unlock()
+
+	if s.isShuttingDown() {
+		scheduled.done = true
+		return scheduled
+	}
+
+	scheduled.timer = time.AfterFunc(delay, func() {
+		if scheduled.finish() {
+			target.Emit(eventName, data)
+		}
+	})
+	s.scheduled[scheduled] = struct{}{}
+	return scheduled
+}
+
+func (s *IgoServer) unschedule(scheduled *Scheduled) {
+	s.scheduledMu.Lock()
+	defer s.scheduledMu.Unlock()
+
+	delete(s.scheduled, scheduled)
+}
+
+// cancelScheduled cancels every pending and periodic emit, on shutdown.
+func (s *IgoServer) cancelScheduled() {
+	s.scheduledMu.Lock()
+	pending := make([]*Scheduled, 0, len(s.scheduled))
+	for scheduled := range s.scheduled {
+		pending = append(pending, scheduled)
+	}
+	periodic := make([]*Periodic, 0, len(s.periodic))
+	for p := range s.periodic {
+		periodic = append(periodic, p)
+	}
+	s.scheduledMu.Unlock()
+
+	for _, scheduled := range pending {
+		scheduled.Cancel()
+	}
+	for _, p := range periodic {
+		p.Stop()
+	}
+}
+
+// Periodic is a broadcast repeated by EmitEvery.
+type Periodic struct {
+	server *IgoServer
+	ticker *time.Ticker
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// Stop ends the broadcast.
+func (p *Periodic) Stop() {
+	p.once.Do(func() {
+		p.ticker.Stop()
+		close(p.stop)
+
+		p.server.scheduledMu.Lock()
+		delete(p.server.periodic, p)
+		p.server.scheduledMu.Unlock()
+	})
+}
+
+/*
+EmitEvery emits the event to the target every interval, with the data the
+factory returns at that moment, until Stop gets called or the server shuts
+down.
+
+Targets listing their clients, like rooms, namespaces, broadcast operators,
+selections and users, are skipped while they have no clients on this node,
+without calling the factory.
+*/
+func (s *IgoServer) EmitEvery(interval time.Duration, target Emitter, eventName string, factory func() interface{}) *Periodic {
+	periodic := &Periodic{
+		server: s,
+		ticker: time.NewTicker(interval),
+		stop:   make(chan struct{}),
+	}
+
+	s.scheduledMu.Lock()
+	if s.isShuttingDown() {
+		s.scheduledMu.Unlock()
+		periodic.Stop()
+		return periodic
+	}
+	s.periodic[periodic] = struct{}{}
+	s.scheduledMu.Unlock()
+
+	lister, lists := target.(interface{ Clients() []*Client })
+
+	go func() {
+		for {
+			select {
+			case <-periodic.ticker.C:
+				if lists && len(lister.Clients()) == 0 {
+					continue
+				}
+				target.Emit(eventName, factory())
+			case <-periodic.stop:
+				return
+			}
+		}
+	}()
+	return periodic
+}
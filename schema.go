@@ -0,0 +1,75 @@
+package socketigo
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const invalidPayloadCode = "invalid_payload"
+
+// SetSchema attaches a JSON Schema to the event. Payloads not matching it are
+// rejected with an error frame before any listener runs and reported as
+// ErrInvalidPayload.
+func (n *Namespace) SetSchema(eventName string, schema string) error {
+	resource := "mem://socketigo" + strings.TrimSuffix(n.Name, "/") + "/" + url.PathEscape(eventName) + ".json"
+	compiled, err := jsonschema.CompileString(resource, schema)
+	if err != nil {
+		return err
+	}
+
+	n.eventsMu.Lock()
+	defer n.eventsMu.Unlock()
+
+	n.schemas[eventName] = compiled
+	return nil
+}
+
+func (n *Namespace) RemoveSchema(eventName string) {
+	n.eventsMu.Lock()
+	defer n.eventsMu.Unlock()
+
+	delete(n.schemas, eventName)
+}
+
+// validatePayload checks the payload against the schema of the event, if any.
+// Invalid payloads are answered with an error frame and, for acked events and
+// calls, an error ack or reply.
+func (c *Client) validatePayload(frame *Frame, data map[string]interface{}) bool {
+	eventName := frame.Event
+
+	c.Namespace.eventsMu.RLock()
+	schema, ok := c.Namespace.schemas[eventName]
+	c.Namespace.eventsMu.RUnlock()
+
+	if !ok {
+		return true
+	}
+
+	var payload interface{} = data
+	var err error
+	if _, isJSON := c.Server.codec.(JSONCodec); !isJSON || data == nil {
+		// the schema validator only knows the types encoding/json produces,
+		// and there is no map of payloads other than objects
+		payload, err = frame.any()
+	}
+	if err == nil {
+		err = schema.Validate(payload)
+	}
+	if err == nil {
+		return true
+	}
+
+	err = fmt.Errorf("event %q: %w", eventName, err)
+	c.Server.emitError(c, nil, ErrInvalidPayload, err)
+
+	c.Emit(errorEvent, map[string]interface{}{
+		"code":    invalidPayloadCode,
+		"message": err.Error(),
+		"event":   eventName,
+	})
+	c.acknowledge(frame, err)
+	return false
+}
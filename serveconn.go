@@ -0,0 +1,151 @@
+package socketigo
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// ServeConn serves a websocket connection which got upgraded outside the
+// server, like one of a custom TLS termination or a proxy handing connections
+// off, with the request it got upgraded for. The server takes over right after
+// the handshake, which must not have negotiated compression, a subprotocol
+// negotiated elsewhere is not known to it. The remote address and TLS state of
+// the request are taken from the connection if missing. ServeConn returns once
+// the client disconnected and closes the connection.
+func (s *IgoServer) ServeConn(conn net.Conn, r *http.Request) {
+	fillConnRequest(conn, r)
+
+	socket, err := s.wrapWebSocket(conn, r)
+	if err != nil {
+		conn.Close()
+		s.emitError(nil, r, ErrUpgradeFailed, err)
+		return
+	}
+	s.ServeUpgraded(socket, r)
+}
+
+// ServeUpgraded serves a connection which got upgraded by another websocket
+// library or HTTP stack, with the request it got upgraded for. The request
+// goes through the same checks as in ServeHTTP, a rejected one gets closed
+// with an error frame as on the raw transport. ServeUpgraded returns once the
+// client disconnected and closes the connection.
+func (s *IgoServer) ServeUpgraded(conn Conn, r *http.Request) {
+	defer conn.Close()
+
+	response := &rawResponse{header: make(http.Header)}
+	admission, ok := s.Admit(response, r)
+	if !ok {
+		response.reject(conn, s.codec)
+		return
+	}
+	admission.Serve(conn)
+}
+
+// ServeHijacked serves a connection hijacked from an http.Server which
+// speaks the framing of the raw transport, see NewStreamConn, with the
+// request it got hijacked on. What the reader of rw buffered already is read
+// first, rw may be nil. The remote address and TLS state of the request are
+// taken from the connection if missing.
+func (s *IgoServer) ServeHijacked(conn net.Conn, rw *bufio.ReadWriter, r *http.Request) {
+	fillConnRequest(conn, r)
+
+	var stream io.ReadWriteCloser = conn
+	if rw != nil {
+		if err := rw.Flush(); err != nil {
+			conn.Close()
+			return
+		}
+		stream = &hijackedStream{Conn: conn, reader: rw.Reader}
+	}
+	s.ServeUpgraded(NewStreamConn(stream), r)
+}
+
+func fillConnRequest(conn net.Conn, r *http.Request) {
+	if r.RemoteAddr == "" {
+		r.RemoteAddr = conn.RemoteAddr().String()
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok && r.TLS == nil {
+		state := tlsConn.ConnectionState()
+		r.TLS = &state
+	}
+}
+
+// wrapWebSocket makes a websocket of the server side of a connection whose
+// handshake is over. gorilla/websocket only creates them by upgrading, so it
+// upgrades a copy of the request over the connection and the response to it
+// gets discarded.
+func (s *IgoServer) wrapWebSocket(conn net.Conn, r *http.Request) (*ws.Conn, error) {
+	upgrade := r.Clone(r.Context())
+	upgrade.Method = http.MethodGet
+	upgrade.Header.Set("Connection", "Upgrade")
+	upgrade.Header.Set("Upgrade", "websocket")
+	upgrade.Header.Set("Sec-WebSocket-Version", "13")
+	upgrade.Header.Set("Sec-WebSocket-Key", "c29ja2V0aWdvIHVwZ3JhZGU=")
+	upgrade.Header.Del("Sec-WebSocket-Extensions")
+	upgrade.Header.Del("Sec-WebSocket-Protocol")
+
+	upgrader := &ws.Upgrader{
+		ReadBufferSize:  s.upgrader.ReadBufferSize,
+		WriteBufferSize: s.upgrader.WriteBufferSize,
+		WriteBufferPool: s.upgrader.WriteBufferPool,
+		// the origin was up to whoever upgraded the connection
+		CheckOrigin: func(r *http.Request) bool {
+			return true
+		},
+	}
+	return upgrader.Upgrade(&handshakeWriter{conn: &handshakeConn{Conn: conn}}, upgrade, nil)
+}
+
+// handshakeWriter hands the connection to the upgrader of wrapWebSocket.
+type handshakeWriter struct {
+	conn   *handshakeConn
+	header http.Header
+}
+
+func (w *handshakeWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *handshakeWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (w *handshakeWriter) WriteHeader(status int) {}
+
+func (w *handshakeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.conn, bufio.NewReadWriter(bufio.NewReader(w.conn), bufio.NewWriter(w.conn)), nil
+}
+
+// handshakeConn discards the first write, the response of the upgrade, which
+// the client got from whoever upgraded the connection already.
+type handshakeConn struct {
+	net.Conn
+	responded bool
+}
+
+func (c *handshakeConn) Write(p []byte) (int, error) {
+	if !c.responded {
+		c.responded = true
+		return len(p), nil
+	}
+	return c.Conn.Write(p)
+}
+
+// hijackedStream reads a hijacked connection through the reader it came with,
+// passing the deadlines on to the connection.
+type hijackedStream struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (s *hijackedStream) Read(p []byte) (int, error) {
+	return s.reader.Read(p)
+}
@@ -0,0 +1,59 @@
+package socketigo_test
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nauri-io/socket.igo/igoclient"
+)
+
+// acceptKey answers the websocket key of the request, for the handshakes done
+// outside the server.
+func acceptKey(r *http.Request) string {
+	digest := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+func TestServeConn(t *testing.T) {
+	server := echoServer(nil)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + acceptKey(r) + "\r\n\r\n")
+		if err := rw.Flush(); err != nil {
+			t.Error(err)
+			return
+		}
+		server.ServeConn(conn, r)
+	}))
+	defer httpServer.Close()
+
+	echoes := make(chan map[string]interface{}, 1)
+	client, err := igoclient.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), &igoclient.Options{DisableReconnect: true})
+	if err != nil {
+		t.Fatalf("connecting failed: %v", err)
+	}
+	defer client.Close()
+	client.On("echo", func(client *igoclient.Client, data map[string]interface{}) interface{} {
+		echoes <- data
+		return nil
+	})
+
+	client.Emit("echo", map[string]interface{}{"text": "upgraded elsewhere"})
+	select {
+	case data := <-echoes:
+		if data["text"] != "upgraded elsewhere" {
+			t.Fatalf("echo is %v, want the message sent", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("connection served by ServeConn did not echo")
+	}
+}
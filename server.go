@@ -1,9 +1,17 @@
 package socketigo
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
 
-	"github.com/goccy/go-json"
+	uuid "github.com/google/uuid"
 	ws "github.com/gorilla/websocket"
 )
 
@@ -12,21 +20,223 @@ Events:
 - preconnect: Gets called when the connection is established but the handshake is not yet completed.
 - connected: Gets called when the connection is established and the handshake is completed.
 - disconnected: Gets called when the connection is closed.
+
+The connected and disconnected events, rooms and broadcasts of the server itself
+are scoped to the default namespace "/". Use Of to address other namespaces.
 */
 type IgoServer struct {
-	Clients             []*Client
-	Rooms               []*Room
-	upgrader            *ws.Upgrader
-	preConnectHandler   func(conn *ws.Conn)
-	connectedHandler    func(client *Client)
-	disconnectedHandler func(client *Client)
-	errHandler          func(err error)
+	*Namespace
+	clients                 *clientRegistry
+	namespacesMu            sync.RWMutex
+	namespaces              map[string]*Namespace
+	upgrader                *ws.Upgrader
+	middlewaresMu           sync.RWMutex
+	middlewares             []Middleware
+	requestMiddlewares      []RequestMiddleware
+	authenticator           Authenticator
+	authTimeout             time.Duration
+	codec                   Codec
+	pingInterval            time.Duration
+	pongWait                time.Duration
+	readWait                time.Duration
+	writeWait               time.Duration
+	idleTimeout             time.Duration
+	sendQueueSize           int
+	backpressure            BackpressurePolicy
+	blockTimeout            time.Duration
+	compressionLevel        int
+	compressionMin          int
+	chunkSize               int
+	maxChunkedSize          int64
+	maxMessageSize          int64
+	disconnectOversized     bool
+	deleteEmptyRooms        bool
+	sequenceNumbers         bool
+	rateLimit               *RateLimit
+	maxConnectionsPerIP     int
+	connectionKey           func(r *http.Request) string
+	connections             *connectionCounter
+	clientSlots             chan struct{}
+	maxClientsWait          time.Duration
+	resumeWindow            time.Duration
+	sessionsMu              sync.Mutex
+	sessions                map[string]*session
+	transportsMu            sync.RWMutex
+	transports              map[string]Transport
+	websocket               Transport
+	listenersMu             sync.Mutex
+	listeners               map[net.Listener]struct{}
+	trustedProxies          []netip.Prefix
+	proxyProtocol           bool
+	httpServers             map[*http.Server]struct{}
+	filesMu                 sync.Mutex
+	receivingFiles          map[string]struct{}
+	sseMu                   sync.Mutex
+	sseConns                map[string]*sseConn
+	store                   Store
+	offlineTTL              time.Duration
+	nodeId                  string
+	startedAt               time.Time
+	tagsMu                  sync.RWMutex
+	tags                    map[string]map[*Client]struct{}
+	scheduledMu             sync.Mutex
+	scheduled               map[*Scheduled]struct{}
+	periodic                map[*Periodic]struct{}
+	usersMu                 sync.RWMutex
+	users                   map[string]*User
+	shuttingDown            int32
+	adapter                 Adapter
+	anyMu                   sync.RWMutex
+	anyListener             AnyListener
+	metrics                 Metrics
+	tracer                  Tracer
+	logger                  Logger
+	sendPanicErrors         bool
+	handlerTimeout          time.Duration
+	workers                 *workerPool
+	workerQueueSize         int
+	dispatchOrder           DispatchOrder
+	preConnectHandler       func(conn Conn)
+	preConnectCheck         func(ctx *PreConnectContext) error
+	errHandler              func(err error)
+	slowConsumerHandler     func(client *Client, policy BackpressurePolicy)
+	serverFullHandler       func(r *http.Request)
+	userConnectedHandler    func(user *User)
+	userDisconnectedHandler func(user *User)
+}
+
+// BufferPool is a pool of write buffers, like a *sync.Pool.
+type BufferPool interface {
+	Get() interface{}
+	Put(interface{})
 }
 
 type IgoServerOptions struct {
+	// WebSocket upgrades websocket requests in place of gorilla/websocket, e.g.
+	// the backend of the nhooyrws package. The options up to UpgradeError only
+	// apply to gorilla/websocket.
+	WebSocket       Transport
 	ReadBufferSize  int
 	WriteBufferSize int
-	CheckOrigin     func(r *http.Request) bool
+	// WriteBufferPool shares the write buffers between connections, see
+	// websocket.Upgrader.
+	WriteBufferPool BufferPool
+	// CheckOrigin decides whether the origin of an upgrade request is allowed.
+	// Defaults to allowing every origin.
+	CheckOrigin func(r *http.Request) bool
+	// Subprotocols are the supported subprotocols in order of preference, the
+	// one negotiated is available from Client.Subprotocol.
+	Subprotocols []string
+	// HandshakeTimeout is the time the upgrade handshake may take.
+	HandshakeTimeout time.Duration
+	// EnableCompression negotiates permessage-deflate with clients supporting
+	// it. Messages smaller than CompressionThreshold, defaulting to 512 bytes,
+	// are sent uncompressed. CompressionLevel is a compress/flate level and
+	// defaults to flate.BestSpeed.
+	EnableCompression    bool
+	CompressionThreshold int
+	CompressionLevel     int
+	// UpgradeError writes the response of failed upgrades. Defaults to an
+	// http.Error with the status.
+	UpgradeError func(w http.ResponseWriter, r *http.Request, status int, reason error)
+	// AuthTimeout is the time a client has to send its auth frame once an
+	// authenticator is set. Defaults to 10 seconds. The auth frame may be as
+	// large as MaxMessageSize, or 64 KiB without one.
+	AuthTimeout time.Duration
+	// Codec encodes the frames on the wire. Defaults to JSONCodec.
+	Codec Codec
+	// PingInterval is the interval pings are sent to every client in, PongWait the
+	// time a client has to answer before it gets disconnected. Default to 25 and
+	// 60 seconds.
+	PingInterval time.Duration
+	PongWait     time.Duration
+	// ReadWait is the time the reader waits for the next message or pong before
+	// the connection is considered dead. Defaults to PongWait and should exceed
+	// PingInterval.
+	ReadWait time.Duration
+	// IdleTimeout disconnects clients which did not send any message for the
+	// duration, with ReasonIdle. Zero disables it.
+	IdleTimeout time.Duration
+	// WriteWait is the time a single write to the socket may take, so a stalled
+	// peer cannot hold its writer. Defaults to 10 seconds.
+	WriteWait time.Duration
+	// SendQueueSize is the number of outbound messages buffered per client.
+	// Defaults to 256.
+	SendQueueSize int
+	// Backpressure is applied to emits once the outbound queue is full. Block
+	// waits for at most BlockTimeout, which defaults to one second.
+	Backpressure BackpressurePolicy
+	BlockTimeout time.Duration
+	// MaxMessageSize is the largest message in bytes a client may send, zero
+	// disables the limit. Larger messages are dropped and answered with an error
+	// frame, or disconnect the client with DisconnectOversized.
+	MaxMessageSize      int64
+	DisconnectOversized bool
+	// RateLimit limits the messages every client may send, Client.SetRateLimit
+	// overrides it per client.
+	RateLimit *RateLimit
+	// MaxConnectionsPerIP limits the concurrent connections per key, rejecting
+	// further upgrades with 429. Zero disables the limit. ConnectionKey derives
+	// the key from the request and defaults to RemoteIP.
+	MaxConnectionsPerIP int
+	ConnectionKey       func(r *http.Request) string
+	// TrustedProxies are the networks of the proxies in front of the server.
+	// Requests coming from one get the address of the client from
+	// X-Forwarded-For or X-Real-IP as RemoteAddr before the request
+	// middlewares run, so Client.RemoteAddr, RemoteIP and with it the
+	// connection limits and IPFilter see the client instead of the proxy.
+	// With ProxyProtocol the connections ListenAndServe and ServeRaw accept
+	// from them, or from any peer of a Unix domain socket, have to start with
+	// a PROXY protocol header of version 1 or 2 carrying the client address.
+	TrustedProxies []netip.Prefix
+	ProxyProtocol  bool
+	// MaxClients caps the open connections of the server, including the ones
+	// still in their handshake. Further upgrades wait for at most MaxClientsWait
+	// for a connection to close and are rejected with 503 otherwise. Zero
+	// disables the cap.
+	MaxClients     int
+	MaxClientsWait time.Duration
+	// ResumeWindow is the time a disconnected client can resume its session in,
+	// getting the events emitted to it meanwhile. The reconnecting client has
+	// to authenticate as the same user with the same claims, otherwise it gets
+	// a new session. Zero disables resumption.
+	ResumeWindow time.Duration
+	// OfflineTTL is the time messages for offline users are kept in the store.
+	// Defaults to 24 hours.
+	OfflineTTL time.Duration
+	// SendPanicErrors sends an error frame carrying the panic value to clients
+	// whose listener panicked. Panics are reported to the error handler either way.
+	SendPanicErrors bool
+	// HandlerTimeout is the time the listeners of an event get before their
+	// context gets canceled and an acked event is answered with a timeout
+	// error. Zero disables it, SetTimeout overrides it per event.
+	HandlerTimeout time.Duration
+	// Workers is the number of goroutines the listeners of incoming events run
+	// on. Zero runs them on the reader of each client, one event after another.
+	// WorkerQueueSize bounds the events waiting for a worker, defaulting to 1024.
+	Workers         int
+	WorkerQueueSize int
+	// DispatchOrder decides whether the events of a client may run concurrently
+	// on the workers. Defaults to OrderPerClient.
+	DispatchOrder DispatchOrder
+	// DeleteEmptyRooms deletes a room once its last client left it, right after
+	// its empty handler ran.
+	DeleteEmptyRooms bool
+	// SequenceNumbers stamps every frame sent to a client with "seq", counting
+	// up from 1 per connection and carrying on in a resumed session, so clients
+	// can detect dropped messages. Room emits additionally carry "room" and the
+	// "roomSeq" of the room. The handshake and raw binary frames are not stamped.
+	SequenceNumbers bool
+	// ChunkSize splits messages above it into chunks of at most that many bytes
+	// for clients connecting with the "chunking" query parameter, so large
+	// payloads do not hold up the messages emitted meanwhile. Zero disables it.
+	// MaxChunkedSize bounds the messages clients may send in chunks, as well as
+	// a frame along with its attachments, and defaults to 32 MiB, or
+	// MaxMessageSize if that is smaller. Chunks from
+	// clients are rejected unless chunking is enabled and they connected with
+	// "chunking".
+	ChunkSize      int
+	MaxChunkedSize int64
 }
 
 type IgoServerHandle func(w http.ResponseWriter, r *http.Request)
@@ -36,140 +246,694 @@ func CreateIgoServer(options *IgoServerOptions) *IgoServer {
 		options = &IgoServerOptions{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
-			CheckOrigin: nil,
+			CheckOrigin:     nil,
+		}
+	}
+
+	authTimeout := options.AuthTimeout
+	if authTimeout <= 0 {
+		authTimeout = defaultAuthTimeout
+	}
+
+	var codec Codec = JSONCodec{}
+	if options.Codec != nil {
+		codec = options.Codec
+	}
+
+	checkOrigin := options.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = func(r *http.Request) bool {
+			return true
 		}
 	}
 
-	return &IgoServer{
-		Clients: make([]*Client, 0),
-		Rooms:   make([]*Room, 0),
+	compressionMin := options.CompressionThreshold
+	if compressionMin <= 0 {
+		compressionMin = defaultCompressionThreshold
+	}
+
+	connectionKey := options.ConnectionKey
+	if connectionKey == nil {
+		connectionKey = RemoteIP
+	}
+
+	trustedProxies := make([]netip.Prefix, 0, len(options.TrustedProxies))
+	for _, prefix := range options.TrustedProxies {
+		trustedProxies = append(trustedProxies, prefix.Masked())
+	}
+
+	pongWait := options.PongWait
+	if pongWait <= 0 {
+		pongWait = defaultPongWait
+	}
+
+	pingInterval := options.PingInterval
+	if pingInterval <= 0 {
+		pingInterval = defaultPingInterval
+	}
+	if pingInterval >= pongWait {
+		pingInterval = pongWait * 9 / 10
+	}
+
+	readWait := options.ReadWait
+	if readWait <= 0 {
+		readWait = pongWait
+	}
+
+	writeWait := options.WriteWait
+	if writeWait <= 0 {
+		writeWait = defaultWriteWait
+	}
+
+	sendQueueSize := options.SendQueueSize
+	if sendQueueSize <= 0 {
+		sendQueueSize = defaultSendQueueSize
+	}
+
+	blockTimeout := options.BlockTimeout
+	if blockTimeout <= 0 {
+		blockTimeout = defaultBlockTimeout
+	}
+
+	offlineTTL := options.OfflineTTL
+	if offlineTTL <= 0 {
+		offlineTTL = defaultOfflineTTL
+	}
+
+	maxChunkedSize := options.MaxChunkedSize
+	if maxChunkedSize <= 0 {
+		maxChunkedSize = defaultMaxChunkedSize
+	}
+	// chunks must not get around the limit of whole messages
+	if options.MaxMessageSize > 0 && maxChunkedSize > options.MaxMessageSize {
+		maxChunkedSize = options.MaxMessageSize
+	}
+
+	server := &IgoServer{
+		clients:    newClientRegistry(),
+		namespaces: make(map[string]*Namespace),
 		upgrader: &ws.Upgrader{
-			ReadBufferSize:  options.ReadBufferSize,
-			WriteBufferSize: options.WriteBufferSize,
-			CheckOrigin:     options.CheckOrigin,
+			ReadBufferSize:    options.ReadBufferSize,
+			WriteBufferSize:   options.WriteBufferSize,
+			WriteBufferPool:   options.WriteBufferPool,
+			CheckOrigin:       checkOrigin,
+			Subprotocols:      options.Subprotocols,
+			HandshakeTimeout:  options.HandshakeTimeout,
+			EnableCompression: options.EnableCompression,
+			Error:             options.UpgradeError,
 		},
+		authTimeout:         authTimeout,
+		codec:               codec,
+		pingInterval:        pingInterval,
+		pongWait:            pongWait,
+		readWait:            readWait,
+		writeWait:           writeWait,
+		idleTimeout:         options.IdleTimeout,
+		sendQueueSize:       sendQueueSize,
+		backpressure:        options.Backpressure,
+		blockTimeout:        blockTimeout,
+		compressionLevel:    options.CompressionLevel,
+		compressionMin:      compressionMin,
+		maxMessageSize:      options.MaxMessageSize,
+		disconnectOversized: options.DisconnectOversized,
+		deleteEmptyRooms:    options.DeleteEmptyRooms,
+		sequenceNumbers:     options.SequenceNumbers,
+		chunkSize:           options.ChunkSize,
+		maxChunkedSize:      maxChunkedSize,
+		rateLimit:           options.RateLimit,
+		maxConnectionsPerIP: options.MaxConnectionsPerIP,
+		connectionKey:       connectionKey,
+		connections:         newConnectionCounter(),
+		trustedProxies:      trustedProxies,
+		proxyProtocol:       options.ProxyProtocol,
+		maxClientsWait:      options.MaxClientsWait,
+		resumeWindow:        options.ResumeWindow,
+		sessions:            make(map[string]*session),
+		transports:          make(map[string]Transport),
+		listeners:           make(map[net.Listener]struct{}),
+		httpServers:         make(map[*http.Server]struct{}),
+		sseConns:            make(map[string]*sseConn),
+		receivingFiles:      make(map[string]struct{}),
+		tags:                make(map[string]map[*Client]struct{}),
+		users:               make(map[string]*User),
+		scheduled:           make(map[*Scheduled]struct{}),
+		periodic:            make(map[*Periodic]struct{}),
+		offlineTTL:          offlineTTL,
+		sendPanicErrors:     options.SendPanicErrors,
+		metrics:             noopMetrics{},
+		tracer:              noopTracer{},
+		logger:              defaultLogger{},
+		handlerTimeout:      options.HandlerTimeout,
+		nodeId:              uuid.NewString(),
+		startedAt:           time.Now(),
 		preConnectHandler:   nil,
-		connectedHandler:    nil,
-		disconnectedHandler: nil,
 		errHandler:          nil,
 	}
+	if options.MaxClients > 0 {
+		server.clientSlots = make(chan struct{}, options.MaxClients)
+	}
+	if options.Workers > 0 {
+		queueSize := options.WorkerQueueSize
+		if queueSize <= 0 {
+			queueSize = defaultWorkerQueueSize
+		}
+		server.workers = newWorkerPool(options.Workers, queueSize)
+		server.workerQueueSize = queueSize
+		server.dispatchOrder = options.DispatchOrder
+	}
+
+	server.transports[sseTransport] = sseUpgrader{server: server}
+	server.websocket = options.WebSocket
+	if server.websocket == nil {
+		server.websocket = gorillaUpgrader{server: server}
+	}
+
+	server.adapter = NewMemoryAdapter()
+	server.adapter.Init(server)
+	server.Namespace = createNamespace(server, DefaultNamespace)
+	server.namespaces[DefaultNamespace] = server.Namespace
+
+	return server
 }
 
-func (s *IgoServer) OnPreConnect(listener func(conn *ws.Conn)) {
-	s.preConnectHandler = listener
+// Of returns the namespace with the given name, creating it if it does not exist yet.
+func (s *IgoServer) Of(name string) *Namespace {
+	name = normalizeNamespace(name)
+
+	s.namespacesMu.Lock()
+	defer s.namespacesMu.Unlock()
+
+	if ns, ok := s.namespaces[name]; ok {
+		return ns
+	}
+
+	ns := createNamespace(s, name)
+	s.namespaces[name] = ns
+	return ns
+}
+
+func (s *IgoServer) getNamespace(name string) *Namespace {
+	s.namespacesMu.RLock()
+	defer s.namespacesMu.RUnlock()
+
+	return s.namespaces[normalizeNamespace(name)]
 }
 
-func (s *IgoServer) OnConnected(listener func(client *Client)) {
-	s.connectedHandler = listener
+// Namespaces returns the namespaces of the server, sorted by name.
+func (s *IgoServer) Namespaces() []*Namespace {
+	s.namespacesMu.RLock()
+	namespaces := make([]*Namespace, 0, len(s.namespaces))
+	for _, ns := range s.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	s.namespacesMu.RUnlock()
+
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+	return namespaces
 }
 
-func (s *IgoServer) OnDisconnected(listener func(client *Client)) {
-	s.disconnectedHandler = listener
+// Rooms returns the rooms of every namespace.
+func (s *IgoServer) Rooms() []*Room {
+	var rooms []*Room
+	for _, ns := range s.Namespaces() {
+		rooms = append(rooms, ns.ListRooms()...)
+	}
+	return rooms
 }
 
-func (s *IgoServer) Emit(eventName string, data interface{}) {
-	for _, client := range s.Clients {
-		client.Emit(eventName, data)
+// EachRoom calls fn for the rooms of every namespace, without holding any lock
+// meanwhile.
+func (s *IgoServer) EachRoom(fn func(room *Room)) {
+	for _, room := range s.Rooms() {
+		fn(room)
 	}
 }
 
-func (s *IgoServer) EmitExcept(client *Client, eventName string, data interface{}) {
-	for _, c := range s.Clients {
-		if c != client {
-			c.Emit(eventName, data)
+// OnPreConnect observes every connection right after the upgrade, use
+// OnPreConnectContext to reject connections. The connections of the default
+// websocket backend are a *websocket.Conn of gorilla/websocket.
+func (s *IgoServer) OnPreConnect(listener func(conn Conn)) {
+	s.preConnectHandler = listener
+}
+
+// Clients returns all connected clients across every namespace.
+func (s *IgoServer) Clients() []*Client {
+	return s.clients.snapshot()
+}
+
+func (s *IgoServer) GetClient(id uuid.UUID) *Client {
+	return s.clients.get(id)
+}
+
+func (s *IgoServer) ClientCount() int {
+	return s.clients.len()
+}
+
+// Handle returns the handler of the server, see ServeHTTP.
+func (s *IgoServer) Handle() IgoServerHandle {
+	return s.ServeHTTP
+}
+
+// ServeHTTP upgrades the request to a connection of the transport it asks for
+// and serves the client until it disconnects, so the server can be mounted
+// wherever an http.Handler goes.
+func (s *IgoServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost && isSSERequest(r) {
+		// the posts of an event stream pass the checks its upgrade passed
+		if s.rejectShutdown(w) {
+			return
+		}
+		r = s.resolveRemoteAddr(r)
+		if !s.runRequestMiddlewares(w, r) {
+			return
 		}
+		s.receiveSSE(w, r)
+		return
 	}
+
+	transport, ok := s.getTransport(r)
+	if !ok {
+		s.logger.Debug("unknown transport", "transport", r.URL.Query().Get(transportParam), "remote", r.RemoteAddr)
+		http.Error(w, "unknown transport", http.StatusBadRequest)
+		return
+	}
+
+	admission, ok := s.Admit(w, r)
+	if !ok {
+		return
+	}
+
+	conn, err := transport.Upgrade(w, r)
+	if err != nil {
+		admission.Fail(err)
+		return
+	}
+	// the transport may live on the response, which must not be written
+	// to once the handler returned
+	defer conn.Close()
+
+	admission.Serve(conn)
+}
+
+// Admission is a request which passed the checks of the server, so the
+// connection it gets upgraded to can be served. It lets other HTTP stacks
+// upgrade the request themselves.
+type Admission struct {
+	server    *IgoServer
+	request   *http.Request
+	namespace *Namespace
+	release   func()
+	once      sync.Once
 }
 
-func (s *IgoServer) CreateRoom(name string) *Room {
-	room := &Room{
-		Id:      name,
-		clients: make([]*Client, 0),
+// Admit runs the checks Handle runs before the upgrade: the shutdown, the
+// namespace, the request middlewares and the connection limits. The admitted
+// request carries the address of the client behind TrustedProxies. A request
+// failing one gets answered on w. An admitted one holds its connection slot
+// until Serve returned or Fail got called.
+func (s *IgoServer) Admit(w http.ResponseWriter, r *http.Request) (*Admission, bool) {
+	if s.rejectShutdown(w) {
+		return nil, false
+	}
+	r = s.resolveRemoteAddr(r)
+
+	ns := s.getNamespace(r.URL.Query().Get("namespace"))
+	if ns == nil {
+		s.logger.Debug("unknown namespace", "namespace", r.URL.Query().Get("namespace"), "remote", r.RemoteAddr)
+		http.Error(w, "unknown namespace", http.StatusNotFound)
+		return nil, false
 	}
-	s.Rooms = append(s.Rooms, room)
-	return room
+
+	if !s.runRequestMiddlewares(w, r) {
+		return nil, false
+	}
+
+	release, ok := s.acquireConnection(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	return &Admission{
+		server:    s,
+		request:   r,
+		namespace: ns,
+		release:   release,
+	}, true
 }
 
-func (s *IgoServer) GetRoom(name string) *Room {
-	for _, room := range s.Rooms {
-		if room.Id == name {
-			return room
-		}
+// Serve runs the client on the connection the request got upgraded to,
+// returning once it disconnected.
+func (a *Admission) Serve(conn Conn) {
+	defer a.once.Do(a.release)
+
+	a.server.limitReads(conn)
+	a.server.serve(conn, a.request, a.namespace)
+}
+
+// Fail frees the connection slot of a request whose upgrade failed and
+// reports err to the error handler.
+func (a *Admission) Fail(err error) {
+	a.once.Do(a.release)
+	a.server.emitError(nil, a.request, ErrUpgradeFailed, err)
+}
+
+// UpgradeOptions are the websocket options of IgoServerOptions, for adapters
+// upgrading requests with another websocket library. CheckOrigin is set, to
+// the default allowing every origin if the options had none.
+type UpgradeOptions struct {
+	ReadBufferSize    int
+	WriteBufferSize   int
+	WriteBufferPool   BufferPool
+	CheckOrigin       func(r *http.Request) bool
+	Subprotocols      []string
+	HandshakeTimeout  time.Duration
+	EnableCompression bool
+	CompressionLevel  int
+}
+
+// UpgradeOptions returns the options the server upgrades websocket requests
+// with.
+func (s *IgoServer) UpgradeOptions() UpgradeOptions {
+	return UpgradeOptions{
+		ReadBufferSize:    s.upgrader.ReadBufferSize,
+		WriteBufferSize:   s.upgrader.WriteBufferSize,
+		WriteBufferPool:   s.upgrader.WriteBufferPool,
+		CheckOrigin:       s.upgrader.CheckOrigin,
+		Subprotocols:      append([]string(nil), s.upgrader.Subprotocols...),
+		HandshakeTimeout:  s.upgrader.HandshakeTimeout,
+		EnableCompression: s.upgrader.EnableCompression,
+		CompressionLevel:  s.compressionLevel,
 	}
-	return nil
 }
 
-func (s *IgoServer) DeleteRoom(room *Room) {
-	for i, r := range s.Rooms {
-		if r == room {
-			s.Rooms = append(s.Rooms[:i], s.Rooms[i+1:]...)
-			return
+// gorillaUpgrader upgrades websocket requests with gorilla/websocket, unless
+// the WebSocket option replaces it.
+type gorillaUpgrader struct {
+	server *IgoServer
+}
+
+func (u gorillaUpgrader) Upgrade(w http.ResponseWriter, r *http.Request) (Conn, error) {
+	s := u.server
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.compressionLevel != 0 {
+		if err := conn.SetCompressionLevel(s.compressionLevel); err != nil {
+			s.emitError(nil, r, nil, err)
 		}
 	}
+	return conn, nil
 }
 
-func (s *IgoServer) Handle() IgoServerHandle {
-	return func(w http.ResponseWriter, r *http.Request) {
-		s.upgrader.CheckOrigin = func(r *http.Request) bool {
-			return true
-		}
+// serve runs the lifecycle of a client on the connection, returning once it
+// disconnected.
+func (s *IgoServer) serve(conn Conn, r *http.Request, ns *Namespace) {
+	if s.preConnectHandler != nil {
+		s.preConnectHandler(conn)
+	}
 
-		conn, err := s.upgrader.Upgrade(w, r, nil)
-		if err != nil {
-			if s.errHandler != nil {
-				s.errHandler(err)
+	if !s.preConnect(r, conn, ns) {
+		return
+	}
+
+	client := createClient(s.tracer.StartConnection(r.Context(), r), s, ns, conn, r)
+
+	if !s.handshake(&HandshakeContext{
+		Request:   r,
+		Conn:      conn,
+		Client:    client,
+		Namespace: ns,
+	}) {
+		return
+	}
+
+	if s.isShuttingDown() {
+		rejectHandshake(conn, s.codec, ws.CloseGoingAway, shuttingDownCode, ErrServerShutdown)
+		return
+	}
+
+	s.startHeartbeat(client)
+	s.startIdleTimer(client)
+
+	// the connection may get reused once serve returned, as the hijacked ones
+	// of fasthttp, so the writer has to be done with it by then
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		s.writePump(client)
+	}()
+	defer func() {
+		client.markClosed()
+		<-written
+	}()
+
+	if s.resumeWindow > 0 {
+		if sess := s.getSession(r.URL.Query().Get(sessionParam)); sess != nil {
+			if s.resume(sess, client) {
+				wsReader(client)
+				return
 			}
-			return
 		}
+		s.createSession(client)
+	}
 
-		if s.preConnectHandler != nil {
-			s.preConnectHandler(conn)
-		}
+	s.clients.add(client)
+	ns.clients.add(client)
+	client.setState(StateConnected)
+	s.metrics.ClientConnected(ns.Name)
+	s.logger.Debug("client connected", client.logFields("remote", client.RemoteAddr())...)
+	s.bindUser(client, client.UserId())
+
+	if ns.connectedHandler != nil {
+		ns.connectedHandler(client)
+	}
+
+	client.Emit(handshakeEvent, s.handshakeData(client, client.session, false))
+	s.deliverStored(client)
+
+	wsReader(client)
+}
+
+func (s *IgoServer) handshakeData(client *Client, sess *session, resumed bool) map[string]interface{} {
+	data := map[string]interface{}{
+		"clientId":  client.Id.String(),
+		"namespace": client.Namespace.Name,
+	}
+	if sess != nil {
+		data["session"] = sess.token
+		data["resumed"] = resumed
+	}
+	return data
+}
 
-		client := createClient(s, conn)
-		s.Clients = append(s.Clients, client)
+func (s *IgoServer) handshake(ctx *HandshakeContext) bool {
+	reject := func(code string, kind error, err error) bool {
+		rejectHandshake(ctx.Conn, s.codec, ws.ClosePolicyViolation, code, err)
+		s.emitError(ctx.Client, ctx.Request, kind, err)
+		return false
+	}
 
-		if s.connectedHandler != nil {
-			s.connectedHandler(client)
+	authenticator := s.authenticator
+	if authenticator != nil {
+		limit := s.maxMessageSize
+		if limit <= 0 {
+			limit = defaultMaxAuthSize
+		}
+		payload, err := readAuthFrame(ctx.Conn, s.codec, s.authTimeout, limit)
+		if errors.Is(err, ErrMessageTooLarge) {
+			rejectHandshake(ctx.Conn, s.codec, ws.CloseMessageTooBig, messageTooLargeCode, err)
+			s.emitError(ctx.Client, ctx.Request, ErrMessageTooLarge, err)
+			return false
+		}
+		if err != nil {
+			return reject("auth_required", ErrAuthFailed, err)
 		}
+		ctx.Auth = payload
+	}
 
-		client.Emit("#handshake", map[string]interface{}{
-			"clientId": client.Id,
-		})
+	if err := s.runMiddlewares(ctx); err != nil {
+		return reject("handshake_rejected", ErrHandshakeRejected, err)
+	}
 
-		wsReader(client)
+	if authenticator != nil {
+		if err := authenticator(ctx, ctx.Auth); err != nil {
+			return reject("auth_failed", ErrAuthFailed, err)
+		}
 	}
+	return true
 }
 
 func wsReader(client *Client) {
 	for {
-		_, data, err := client.socket.ReadMessage()
+		messageType, buffer, err := client.readMessage()
+		if err == nil || errors.Is(err, errMessageDropped) {
+			// every message proves the peer alive, not only pongs
+			client.socket.SetReadDeadline(time.Now().Add(client.Server.readWait))
+			client.touch()
+		}
+		if errors.Is(err, errMessageDropped) {
+			continue
+		}
+		if err == nil && !client.allowMessage(buffer.Len()) {
+			releaseBuffer(buffer)
+			continue
+		}
 		if err != nil {
-			for i, c := range client.Server.Clients {
-				if c == client {
-					client.Server.Clients = append(client.Server.Clients[:i], client.Server.Clients[i+1:]...)
-					break
-				}
+			client.handleReadLimit(err)
+			// the close code of a kicked client is the one the server chose
+			kicked := client.DisconnectReason() == ReasonKicked
+			if !kicked && ws.IsUnexpectedCloseError(err, ws.CloseNormalClosure, ws.CloseGoingAway, ws.CloseNoStatusReceived) {
+				client.Server.emitError(client, nil, ErrClientClosed, err)
 			}
 
-			client.socket.Close()
+			client.setDisconnectReason(classifyReadError(err))
+			if client.Server.detachSession(client, err) {
+				client.Server.logger.Debug("client detached", client.logFields("reason", client.DisconnectReason().String())...)
+				break
+			}
 
-			if client.Server.disconnectedHandler != nil {
-				client.Server.disconnectedHandler(client)
+			if client.session != nil {
+				client.Server.deleteSession(client.session)
 			}
+			client.Server.clients.remove(client)
+			client.Namespace.clients.remove(client)
+
+			client.socket.Close()
+			client.markClosed()
+			client.Server.logger.Debug("client disconnected", client.logFields("reason", client.DisconnectReason().String())...)
+			client.Server.notifyDisconnected(client)
 			break
 		}
 
-		result := make(map[string]interface{})
+		client.handleMessage(messageType, buffer.Bytes(), false)
+		releaseBuffer(buffer)
+	}
+}
+
+// handleMessage decodes a message and dispatches it. The data gets reused once
+// it returns, so nothing handed on may refer to it. A reassembled message,
+// put together from chunks, goes to the events only, chunks and stream frames
+// in it are rejected so messages do not nest.
+func (c *Client) handleMessage(messageType int, data []byte, reassembled bool) {
+	codec := c.Server.codec
 
-		err = json.Unmarshal(data, &result)
+	// whatever a client sends, a codec choking on it must not take the reader
+	// down
+	defer func() {
+		if value := recover(); value != nil {
+			c.rejectFrame(nil, fmt.Errorf("%w: %v", ErrDecodeFailed, value))
+		}
+	}()
+
+	if frame := c.attaching; frame != nil {
+		if messageType == ws.BinaryMessage && codec.MessageType() != ws.BinaryMessage {
+			c.addAttachment(data)
+			return
+		}
+		c.attaching = nil
+		if !frame.dropped {
+			c.rejectFrame(frame, fmt.Errorf("got %d of %d attachments", len(frame.attachments), frame.Attachments))
+		}
+		releaseFrame(frame)
+	}
+
+	if messageType == ws.BinaryMessage && codec.MessageType() != ws.BinaryMessage {
+		eventName, payload, err := decodeBinaryFrame(data)
 		if err != nil {
-			if client.Server.errHandler != nil {
-				client.Server.errHandler(err)
-			}
-			continue
+			c.rejectFrame(nil, fmt.Errorf("%w: %w", ErrDecodeFailed, err))
+			return
+		}
+		switch {
+		case (eventName == chunkEvent || eventName == streamEvent) && reassembled:
+			c.rejectFrame(nil, fmt.Errorf("%s frame in a chunked message", eventName))
+			return
+		case eventName == chunkEvent:
+			c.addChunk(payload)
+			return
+		case eventName == streamEvent:
+			c.handleStream(payload)
+			return
 		}
+		c.Server.metrics.EventReceived(c.Namespace.Name, eventName, len(data))
+		c.Server.logger.Debug("binary event received", c.logFields("event", eventName, "bytes", len(data))...)
 
-		handleClientData(client, result)
+		payload = bytes.Clone(payload)
+		c.Server.dispatch(c, func() {
+			dispatchBinary(c, eventName, payload)
+		})
+		return
+	}
+
+	frame, err := decodeFrame(codec, data)
+	if err != nil {
+		c.rejectFrame(nil, fmt.Errorf("%w: %w", ErrDecodeFailed, err))
+		return
+	}
+	if err := frame.validate(); err != nil {
+		c.rejectFrame(frame, err)
+		releaseFrame(frame)
+		return
+	}
+
+	if frame.Binary && (frame.Event == chunkEvent || frame.Event == streamEvent) {
+		eventName, payload := frame.Event, frame.binaryPayload()
+		releaseFrame(frame)
+		if reassembled {
+			c.rejectFrame(nil, fmt.Errorf("%s frame in a chunked message", eventName))
+			return
+		}
+		if eventName == chunkEvent {
+			c.addChunk(payload)
+		} else {
+			c.handleStream(payload)
+		}
+		return
+	}
+
+	frame.size = len(data)
+	if len(frame.attachments) < frame.Attachments {
+		// the binary messages following the frame get collected first
+		c.attaching = frame
+		return
 	}
+	c.handleFrame(frame)
+}
+
+// handleFrame dispatches a frame which got decoded completely.
+func (c *Client) handleFrame(frame *Frame) {
+	c.Server.metrics.EventReceived(c.Namespace.Name, metricEventName(frame.Event), frame.size)
+	c.Server.logger.Debug("event received", c.logFields("event", frame.Event, "bytes", frame.size)...)
+
+	if frame.Binary {
+		eventName, payload := frame.Event, frame.binaryPayload()
+		releaseFrame(frame)
+		c.Server.dispatch(c, func() {
+			dispatchBinary(c, eventName, payload)
+		})
+		return
+	}
+
+	// acks and replies are resolved right away, so listeners waiting for one on
+	// a worker do not wait behind themselves
+	if frame.Event == replyEvent {
+		c.resolveReply(frame)
+		releaseFrame(frame)
+		return
+	}
+	if c.awaitsAck(frame.Event) {
+		if ackData, err := frame.payload(); err == nil && c.resolveAck(frame.Event, ackData) {
+			releaseFrame(frame)
+			return
+		}
+	}
+
+	c.Server.dispatch(c, func() {
+		handleClientData(c, frame)
+		releaseFrame(frame)
+	})
 }
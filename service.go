@@ -0,0 +1,91 @@
+package socketigo
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var (
+	clientType       = reflect.TypeOf((*Client)(nil))
+	eventContextType = reflect.TypeOf((*EventContext)(nil))
+	errorType        = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// RegisterService turns the exported methods of service into listeners of
+// the namespace, named after the service and the method like "chat.Send".
+// Methods take the *Client or the *EventContext of the event, optionally
+// followed by the payload, which gets decoded and validated like the payload
+// of On. They return an error, optionally preceded by the result the event is
+// acknowledged or the call is answered with. Other methods are skipped.
+func (n *Namespace) RegisterService(name string, service interface{}) error {
+	value := reflect.ValueOf(service)
+	serviceType := value.Type()
+
+	registered := 0
+	for i := 0; i < serviceType.NumMethod(); i++ {
+		method := serviceType.Method(i)
+		if !method.IsExported() || !isServiceMethod(method.Type) {
+			continue
+		}
+
+		eventName := name + "." + method.Name
+		n.events.addTyped(eventName, serviceListener(eventName, value.Method(i)))
+		registered++
+	}
+
+	if registered == 0 {
+		return fmt.Errorf("socketigo: service %q has no suitable methods", name)
+	}
+	return nil
+}
+
+// isServiceMethod checks the signature of a method, its receiver included.
+func isServiceMethod(method reflect.Type) bool {
+	if method.NumIn() < 2 || method.NumIn() > 3 {
+		return false
+	}
+	if in := method.In(1); in != clientType && in != eventContextType {
+		return false
+	}
+
+	if method.NumOut() < 1 || method.NumOut() > 2 {
+		return false
+	}
+	return method.Out(method.NumOut()-1) == errorType
+}
+
+func serviceListener(eventName string, method reflect.Value) ContextListener {
+	methodType := method.Type()
+
+	return func(ctx *EventContext, data map[string]interface{}) interface{} {
+		client := ctx.Client
+
+		args := make([]reflect.Value, 1, 2)
+		if methodType.In(0) == clientType {
+			args[0] = reflect.ValueOf(client)
+		} else {
+			args[0] = reflect.ValueOf(ctx)
+		}
+
+		if methodType.NumIn() == 2 {
+			payload := reflect.New(methodType.In(1))
+			if err := ctx.frame.decode(payload.Interface()); err != nil {
+				client.Server.ReportError(client, fmt.Errorf("event %q: %w", eventName, err))
+				return err
+			}
+			if err := validateStruct(payload.Interface()); err != nil {
+				return err
+			}
+			args = append(args, payload.Elem())
+		}
+
+		results := method.Call(args)
+		if err, _ := results[len(results)-1].Interface().(error); err != nil {
+			return err
+		}
+		if len(results) == 2 {
+			return results[0].Interface()
+		}
+		return nil
+	}
+}
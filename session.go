@@ -0,0 +1,281 @@
+package socketigo
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+const sessionParam = "session"
+
+// session outlives the connection of a client for ResumeWindow, so a client
+// reconnecting with its token picks up where it left off.
+type session struct {
+	mu       sync.Mutex
+	token    string
+	client   *Client
+	detached bool
+	closed   bool
+	buffer   []outboundMessage
+	timer    *time.Timer
+}
+
+func newSessionToken() string {
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// OnReconnected gets called instead of the connected handler when a client
+// resumed its session.
+func (n *Namespace) OnReconnected(listener func(client *Client)) {
+	n.reconnectedHandler = listener
+}
+
+func (s *IgoServer) createSession(client *Client) {
+	sess := &session{
+		token:  newSessionToken(),
+		client: client,
+	}
+	client.session = sess
+
+	s.sessionsMu.Lock()
+	s.sessions[sess.token] = sess
+	s.sessionsMu.Unlock()
+}
+
+func (s *IgoServer) getSession(token string) *session {
+	if token == "" {
+		return nil
+	}
+
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	return s.sessions[token]
+}
+
+func (s *IgoServer) deleteSession(sess *session) {
+	s.sessionsMu.Lock()
+	delete(s.sessions, sess.token)
+	s.sessionsMu.Unlock()
+}
+
+// intercept buffers the messages of a detached client and forwards the ones
+// emitted to a client whose session got resumed by a newer connection.
+func (sess *session) intercept(client *Client, message outboundMessage) (bool, error) {
+	sess.mu.Lock()
+
+	if sess.closed {
+		sess.mu.Unlock()
+		return false, nil
+	}
+
+	if sess.client != client {
+		current := sess.client
+		sess.mu.Unlock()
+		return true, current.enqueueMessage(message)
+	}
+
+	if !sess.detached {
+		sess.mu.Unlock()
+		return false, nil
+	}
+
+	sess.push(message)
+	sess.mu.Unlock()
+	return true, nil
+}
+
+func (sess *session) isDetached(client *Client) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	return sess.detached || sess.client != client
+}
+
+// push appends to the buffer, dropping the oldest message once it holds as
+// many messages as the send queue of a client.
+func (sess *session) push(message outboundMessage) {
+	if len(sess.buffer) >= cap(sess.client.send) {
+		sess.buffer = sess.buffer[1:]
+	}
+	sess.buffer = append(sess.buffer, message)
+}
+
+// end rules out resuming, e.g. once the server closed the client on purpose.
+func (sess *session) end() {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if !sess.detached {
+		sess.closed = true
+	}
+}
+
+func resumable(client *Client, err error) bool {
+	switch client.DisconnectReason() {
+	case ReasonSlowConsumer, ReasonMessageTooLarge, ReasonIdle, ReasonRateLimited:
+		return false
+	}
+
+	var closeErr *ws.CloseError
+	if errors.As(err, &closeErr) {
+		return closeErr.Code != ws.CloseNormalClosure && closeErr.Code != ws.CloseGoingAway
+	}
+	return true
+}
+
+// detachSession keeps a client which lost its connection in its namespace and
+// rooms for ResumeWindow, buffering everything emitted to it meanwhile.
+func (s *IgoServer) detachSession(client *Client, err error) bool {
+	sess := client.session
+	if sess == nil || !resumable(client, err) {
+		return false
+	}
+
+	client.socket.Close()
+	client.markClosed()
+	// streams do not survive the connection, the client loses its side too
+	client.closeStreams(ErrClientClosed)
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.closed || sess.client != client {
+		return false
+	}
+
+	sess.detached = true
+	sess.buffer = nil
+
+	// messages the write pump did not get to anymore
+	for drained := false; !drained; {
+		select {
+		case message := <-client.send:
+			sess.push(message)
+		default:
+			drained = true
+		}
+	}
+
+	sess.timer = time.AfterFunc(s.resumeWindow, func() {
+		s.expireSession(sess)
+	})
+	return true
+}
+
+func (s *IgoServer) expireSession(sess *session) {
+	sess.mu.Lock()
+	if !sess.detached {
+		sess.mu.Unlock()
+		return
+	}
+	sess.detached = false
+	sess.closed = true
+	sess.buffer = nil
+	client := sess.client
+	sess.mu.Unlock()
+
+	s.deleteSession(sess)
+	s.clients.remove(client)
+	client.Namespace.clients.remove(client)
+	s.logger.Debug("session expired", client.logFields("reason", client.DisconnectReason().String())...)
+	s.notifyDisconnected(client)
+}
+
+// resume hands the identity, listeners and rooms of the detached client over
+// to the new one and replays the buffered messages right after the handshake.
+func (s *IgoServer) resume(sess *session, client *Client) bool {
+	sess.mu.Lock()
+
+	// the token alone does not hand the identity over, whoever resumes has to
+	// have authenticated as the detached client did
+	old := sess.client
+	if !sess.detached || old.Namespace != client.Namespace || !sameIdentity(old, client) {
+		sess.mu.Unlock()
+		return false
+	}
+	sess.timer.Stop()
+
+	client.Id = old.Id
+	client.seq = old.Seq()
+	client.events = old.events
+	client.binaryEvents = old.binaryEvents
+	client.anyListener = old.anyHandler()
+	old.limiterMu.RLock()
+	client.limiter = old.limiter
+	old.limiterMu.RUnlock()
+	old.reasonMu.Lock()
+	client.disconnectHandler = old.disconnectHandler
+	old.reasonMu.Unlock()
+	old.progressMu.RLock()
+	client.progressHandler = old.progressHandler
+	old.progressMu.RUnlock()
+	for key, value := range old.Metadata() {
+		if _, ok := client.Get(key); !ok {
+			client.Set(key, value)
+		}
+	}
+
+	// the session is not attached yet, so this does not get intercepted
+	client.Emit(handshakeEvent, s.handshakeData(client, sess, true))
+	client.session = sess
+
+	for _, message := range sess.buffer {
+		client.send <- message
+	}
+	sess.buffer = nil
+	sess.detached = false
+	sess.client = client
+
+	s.clients.replace(old, client)
+	client.Namespace.clients.replace(old, client)
+	s.retag(old, client)
+	s.rebindUser(old, client)
+	client.Namespace.resubscribe(old, client)
+	client.setState(StateConnected)
+
+	for _, room := range old.Rooms() {
+		if s.adapter.RemoveFromRoom(room, old) {
+			s.adapter.AddToRoom(room, client)
+			room.presenceReplaced(old, client)
+		}
+	}
+	sess.mu.Unlock()
+	s.logger.Debug("session resumed", client.logFields()...)
+
+	if client.Namespace.reconnectedHandler != nil {
+		client.Namespace.reconnectedHandler(client)
+	}
+	return true
+}
+
+// sameIdentity reports whether the clients authenticated as the same user with
+// the same claims. The time claims differ between the tokens a client got
+// issued over time, so they are left out.
+func sameIdentity(old *Client, client *Client) bool {
+	if old.UserId() != client.UserId() {
+		return false
+	}
+	return reflect.DeepEqual(identityClaims(old.claims), identityClaims(client.claims))
+}
+
+func identityClaims(claims JWTClaims) JWTClaims {
+	identity := make(JWTClaims, len(claims))
+	for key, value := range claims {
+		switch key {
+		case "exp", "iat", "nbf", "jti":
+		default:
+			identity[key] = value
+		}
+	}
+	return identity
+}
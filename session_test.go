@@ -0,0 +1,132 @@
+package socketigo_test
+
+import (
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+// dropClient cuts the connection of the client and waits for the server to
+// notice.
+func dropClient(t *testing.T, client *testclient.Client, serverClient *socketigo.Client) {
+	t.Helper()
+
+	if err := client.Drop(); err != nil {
+		t.Fatalf("dropping the connection failed: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for serverClient.State() != socketigo.StateClosed {
+		if time.Now().After(deadline) {
+			t.Fatal("server did not notice the dropped connection")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSessionResume(t *testing.T) {
+	server := socketigo.CreateIgoServer(&socketigo.IgoServerOptions{ResumeWindow: 5 * time.Second})
+	server.On("join", func(client *socketigo.Client, data map[string]interface{}) interface{} {
+		return client.Join(server.CreateRoom("lobby"))
+	})
+	connected := make(chan *socketigo.Client, 1)
+	server.OnConnected(func(client *socketigo.Client) {
+		connected <- client
+	})
+	reconnected := make(chan *socketigo.Client, 1)
+	server.OnReconnected(func(client *socketigo.Client) {
+		reconnected <- client
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	serverClient := <-connected
+	client.ExpectAck(t, "join", nil)
+	id := client.Id()
+
+	dropClient(t, client, serverClient)
+	server.GetRoom("lobby").Emit("missed", map[string]interface{}{"n": 1})
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("reconnecting failed: %v", err)
+	}
+	if !client.Resumed() {
+		t.Fatal("reconnected client did not resume its session")
+	}
+	if client.Id() != id {
+		t.Fatalf("resumed client has id %q, want %q", client.Id(), id)
+	}
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second):
+		t.Fatal("reconnected handler did not get called")
+	}
+
+	if data := client.ExpectEvent(t, "missed"); data["n"] != 1.0 {
+		t.Fatalf("missed event is %v, want n 1", data)
+	}
+	// the client is still in its rooms
+	server.GetRoom("lobby").Emit("live", nil)
+	client.ExpectEvent(t, "live")
+}
+
+func TestSessionExpires(t *testing.T) {
+	server := socketigo.CreateIgoServer(&socketigo.IgoServerOptions{ResumeWindow: 20 * time.Millisecond})
+	connected := make(chan *socketigo.Client, 2)
+	server.OnConnected(func(client *socketigo.Client) {
+		connected <- client
+	})
+	disconnected := make(chan *socketigo.Client, 1)
+	server.OnDisconnected(func(client *socketigo.Client) {
+		disconnected <- client
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	serverClient := <-connected
+	id := client.Id()
+
+	dropClient(t, client, serverClient)
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("disconnected handler did not get called once the session expired")
+	}
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("reconnecting failed: %v", err)
+	}
+	if client.Resumed() || client.Id() == id {
+		t.Fatal("client resumed an expired session")
+	}
+	<-connected
+}
+
+func TestSessionResumeRequiresSameUser(t *testing.T) {
+	server := socketigo.CreateIgoServer(&socketigo.IgoServerOptions{ResumeWindow: 5 * time.Second})
+	users := make(chan string, 2)
+	users <- "alice"
+	users <- "mallory"
+	server.SetAuthenticator(func(ctx *socketigo.HandshakeContext, payload map[string]interface{}) error {
+		ctx.Client.SetUserId(<-users)
+		return nil
+	})
+	connected := make(chan *socketigo.Client, 2)
+	server.OnConnected(func(client *socketigo.Client) {
+		connected <- client
+	})
+
+	client := testclient.MustConnect(t, server, &testclient.Options{Auth: map[string]interface{}{}})
+	serverClient := <-connected
+	id := client.Id()
+
+	dropClient(t, client, serverClient)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("reconnecting failed: %v", err)
+	}
+	if client.Resumed() || client.Id() == id {
+		t.Fatal("client of another user resumed the session")
+	}
+	if user := (<-connected).UserId(); user != "mallory" {
+		t.Fatalf("new client is user %q, want mallory", user)
+	}
+}
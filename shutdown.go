@@ -0,0 +1,147 @@
+package socketigo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+const (
+	shuttingDownCode     = "shutting_down"
+	shutdownPollInterval = 10 * time.Millisecond
+)
+
+var ErrServerShutdown = errors.New("socketigo: server shutting down")
+
+// OnDisconnect sets a handler of this client only, called with the reason once
+// the client is gone, right before the disconnected handler of its namespace.
+// It carries over to a newer connection resuming the session.
+func (c *Client) OnDisconnect(listener func(reason DisconnectReason)) {
+	c.reasonMu.Lock()
+	defer c.reasonMu.Unlock()
+
+	c.disconnectHandler = listener
+}
+
+// notifyDisconnected reports a client which is gone for good. It leaves its
+// rooms after the disconnect handlers ran, so they still see them.
+func (s *IgoServer) notifyDisconnected(client *Client) {
+	defer client.LeaveAll()
+
+	client.closeStreams(ErrClientClosed)
+	s.untagAll(client)
+	s.unbindUser(client, client.UserId())
+	client.Namespace.unsubscribeAll(client)
+
+	reason := client.DisconnectReason()
+	s.metrics.ClientDisconnected(client.Namespace.Name, reason)
+
+	client.reasonMu.Lock()
+	handler := client.disconnectHandler
+	client.reasonMu.Unlock()
+
+	if handler != nil {
+		handler(reason)
+	}
+	if client.Namespace.disconnectedHandler != nil {
+		client.Namespace.disconnectedHandler(client)
+	}
+}
+
+func (s *IgoServer) isShuttingDown() bool {
+	return atomic.LoadInt32(&s.shuttingDown) == 1
+}
+
+// rejectShutdown turns away upgrades arriving during the shutdown.
+func (s *IgoServer) rejectShutdown(w http.ResponseWriter) bool {
+	if !s.isShuttingDown() {
+		return false
+	}
+	http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+	return true
+}
+
+/*
+Shutdown stops accepting connections, closing the listeners of ServeRaw and
+ListenAndServe, and disconnects every client with 1001 going away and
+ReasonShutdown. Detached sessions expire right away and pending scheduled emits
+get canceled. Once all clients are gone, the workers run the listeners left
+and exit, the http.Servers of ListenAndServe get shut down and the adapter gets
+closed.
+
+If ctx ends first, the remaining sockets and http.Servers get closed without
+waiting for their close frame and ctx.Err() is returned.
+*/
+func (s *IgoServer) Shutdown(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&s.shuttingDown, 0, 1) {
+		return ErrServerShutdown
+	}
+	s.logger.Info("shutting down", "clients", s.clients.len())
+	s.closeListeners()
+	s.cancelScheduled()
+
+	disconnected := make(map[*Client]bool)
+	s.disconnectAll(disconnected)
+
+	s.sessionsMu.Lock()
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.sessionsMu.Unlock()
+
+	for _, sess := range sessions {
+		s.expireSession(sess)
+	}
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for s.clients.len() > 0 {
+		select {
+		case <-ticker.C:
+			// clients which got registered while the flag was being set
+			s.disconnectAll(disconnected)
+		case <-ctx.Done():
+			for _, client := range s.clients.snapshot() {
+				client.socket.Close()
+			}
+			s.closeHTTPServers()
+			s.stopWorkers(ctx)
+			return ctx.Err()
+		}
+	}
+
+	if err := s.stopWorkers(ctx); err != nil {
+		s.closeHTTPServers()
+		return err
+	}
+	if err := s.shutdownHTTPServers(ctx); err != nil {
+		s.closeHTTPServers()
+		return err
+	}
+	return s.adapter.Close()
+}
+
+func (s *IgoServer) disconnectAll(disconnected map[*Client]bool) {
+	for _, client := range s.clients.snapshot() {
+		if disconnected[client] {
+			continue
+		}
+		disconnected[client] = true
+		client.setDisconnectReason(ReasonShutdown)
+		client.Disconnect(ws.CloseGoingAway, "server shutting down")
+	}
+}
+
+// stopWorkers waits for the listeners left on the workers, if enabled.
+func (s *IgoServer) stopWorkers(ctx context.Context) error {
+	if s.workers == nil {
+		return nil
+	}
+	return s.workers.stop(ctx)
+}
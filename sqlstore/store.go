@@ -0,0 +1,153 @@
+// Package sqlstore stores the messages of offline socket.igo users in a SQL
+// database through database/sql.
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	socketigo "github.com/nauri-io/socket.igo"
+)
+
+const defaultTable = "socketigo_messages"
+
+type Options struct {
+	// Table is the name of the message table. Defaults to "socketigo_messages".
+	Table string
+	// Postgres switches the query placeholders from ? to $1, $2, ...
+	Postgres bool
+}
+
+// Store implements socketigo.Store on top of a database/sql handle.
+type Store struct {
+	db       *sql.DB
+	table    string
+	postgres bool
+}
+
+func New(db *sql.DB, options *Options) *Store {
+	store := &Store{
+		db:    db,
+		table: defaultTable,
+	}
+
+	if options != nil {
+		if options.Table != "" {
+			store.table = options.Table
+		}
+		store.postgres = options.Postgres
+	}
+	return store
+}
+
+// query fills in the table name and numbers the ? placeholders for Postgres.
+func (s *Store) query(query string) string {
+	query = strings.ReplaceAll(query, "{table}", s.table)
+	if !s.postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// CreateTable creates the message table unless it exists already.
+func (s *Store) CreateTable() error {
+	_, err := s.db.Exec(s.query(`CREATE TABLE IF NOT EXISTS {table} (
+		user_id VARCHAR(255) NOT NULL,
+		created_at BIGINT NOT NULL,
+		expires_at BIGINT NOT NULL,
+		payload TEXT NOT NULL
+	)`))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(s.query(`CREATE INDEX IF NOT EXISTS {table}_user_idx ON {table} (user_id, created_at)`))
+	return err
+}
+
+func (s *Store) Push(userId string, message *socketigo.StoredMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(
+		s.query(`INSERT INTO {table} (user_id, created_at, expires_at, payload) VALUES (?, ?, ?, ?)`),
+		userId, time.Now().UnixNano(), message.ExpiresAt.UnixNano(), string(payload),
+	)
+	return err
+}
+
+func (s *Store) Drain(userId string) ([]*socketigo.StoredMessage, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixNano()
+	rows, err := tx.Query(
+		s.query(`SELECT created_at, expires_at, payload FROM {table} WHERE user_id = ? ORDER BY created_at`),
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*socketigo.StoredMessage
+	var last int64
+	for rows.Next() {
+		var createdAt, expiresAt int64
+		var payload string
+		if err := rows.Scan(&createdAt, &expiresAt, &payload); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		last = createdAt
+
+		if expiresAt <= now {
+			continue
+		}
+
+		message := &socketigo.StoredMessage{}
+		if err := json.Unmarshal([]byte(payload), message); err != nil {
+			continue
+		}
+		messages = append(messages, message)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// messages pushed while draining stay queued for the next connect
+	_, err = tx.Exec(
+		s.query(`DELETE FROM {table} WHERE user_id = ? AND created_at <= ?`),
+		userId, last,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return messages, tx.Commit()
+}
+
+// DeleteExpired removes the expired messages of users which never came back.
+// Run it periodically to keep the table small.
+func (s *Store) DeleteExpired() error {
+	_, err := s.db.Exec(s.query(`DELETE FROM {table} WHERE expires_at <= ?`), time.Now().UnixNano())
+	return err
+}
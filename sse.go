@@ -0,0 +1,321 @@
+package socketigo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	ws "github.com/gorilla/websocket"
+)
+
+// The SSE transport delivers the messages of a client as a text/event-stream
+// for consumers unable to open a websocket. The stream opens with an "open"
+// event carrying the id the client posts its own messages with, each a POST
+// request to the same url with the "sid" query parameter. Binary messages
+// arrive as base64 encoded "binary" events or, posted, as
+// application/octet-stream bodies.
+
+const (
+	sseTransport       = "sse"
+	sseParam           = "sid"
+	sseInboundSize     = 16
+	binaryBodyType     = "application/octet-stream"
+	defaultSSEPostSize = 1 << 20
+)
+
+func isSSERequest(r *http.Request) bool {
+	return r.URL.Query().Get(transportParam) == sseTransport
+}
+
+// sseUpgrader opens the event stream of the SSE transport.
+type sseUpgrader struct {
+	server *IgoServer
+}
+
+func (u sseUpgrader) Upgrade(w http.ResponseWriter, r *http.Request) (Conn, error) {
+	conn, err := u.server.openSSE(w, r)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// sseConn emulates the messages of a websocket on an event stream and the
+// POST requests of its client.
+type sseConn struct {
+	token      string
+	server     *IgoServer
+	writer     http.ResponseWriter
+	controller *http.ResponseController
+	request    <-chan struct{}
+	inbound    chan []byte
+	binary     chan []byte
+	writeMu    sync.Mutex
+	mu         sync.Mutex
+	deadline   time.Time
+	pong       func(appData string) error
+	closeErr   *ws.CloseError
+	closed     chan struct{}
+	closeOnce  sync.Once
+}
+
+func (s *IgoServer) openSSE(w http.ResponseWriter, r *http.Request) (*sseConn, error) {
+	if !s.checkOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, errors.New("sse: origin not allowed")
+	}
+
+	conn := &sseConn{
+		token:      newSessionToken(),
+		server:     s,
+		writer:     w,
+		controller: http.NewResponseController(w),
+		request:    r.Context().Done(),
+		inbound:    make(chan []byte, sseInboundSize),
+		binary:     make(chan []byte, sseInboundSize),
+		closed:     make(chan struct{}),
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := conn.writeEvent("open", []byte(conn.token)); err != nil {
+		return nil, err
+	}
+
+	s.sseMu.Lock()
+	s.sseConns[conn.token] = conn
+	s.sseMu.Unlock()
+	return conn, nil
+}
+
+// checkOrigin applies the CheckOrigin of the options, by default accepting
+// every origin.
+func (s *IgoServer) checkOrigin(r *http.Request) bool {
+	return s.upgrader.CheckOrigin(r)
+}
+
+// receiveSSE hands a posted message to the event stream it belongs to.
+func (s *IgoServer) receiveSSE(w http.ResponseWriter, r *http.Request) {
+	s.sseMu.Lock()
+	conn := s.sseConns[r.URL.Query().Get(sseParam)]
+	s.sseMu.Unlock()
+
+	if conn == nil {
+		http.Error(w, "unknown event stream", http.StatusNotFound)
+		return
+	}
+
+	limit := s.maxMessageSize
+	if limit <= 0 {
+		limit = defaultSSEPostSize
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		http.Error(w, "message too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	inbound := conn.inbound
+	if r.Header.Get("Content-Type") == binaryBodyType {
+		inbound = conn.binary
+	}
+
+	select {
+	case inbound <- data:
+		w.WriteHeader(http.StatusNoContent)
+	case <-conn.closed:
+		http.Error(w, "event stream closed", http.StatusGone)
+	case <-r.Context().Done():
+	}
+}
+
+func (c *sseConn) NextReader() (int, io.Reader, error) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for {
+		c.mu.Lock()
+		deadline := c.deadline
+		c.mu.Unlock()
+
+		var expired <-chan time.Time
+		if !deadline.IsZero() {
+			timer.Reset(time.Until(deadline))
+			expired = timer.C
+		}
+
+		select {
+		case data := <-c.inbound:
+			return ws.TextMessage, bytes.NewReader(data), nil
+		case data := <-c.binary:
+			return ws.BinaryMessage, bytes.NewReader(data), nil
+		case <-c.closed:
+			return 0, nil, c.closeError()
+		case <-c.request:
+			c.Close()
+			return 0, nil, c.closeError()
+		case <-expired:
+			// the deadline may have been extended meanwhile
+			c.mu.Lock()
+			extended := c.deadline.After(deadline)
+			c.mu.Unlock()
+			if !extended {
+				return 0, nil, os.ErrDeadlineExceeded
+			}
+		}
+	}
+}
+
+func (c *sseConn) closeError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closeErr != nil {
+		return c.closeErr
+	}
+	return &ws.CloseError{Code: ws.CloseGoingAway, Text: "event stream closed"}
+}
+
+func (c *sseConn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case ws.TextMessage:
+		return c.writeEvent("", data)
+	case ws.BinaryMessage:
+		return c.writeEvent("binary", []byte(base64.StdEncoding.EncodeToString(data)))
+	case ws.CloseMessage:
+		return c.writeClose(data)
+	}
+	return c.WriteControl(messageType, data, time.Time{})
+}
+
+// WriteControl writes pings as comments. The stream has no pongs, a ping
+// making it to the client's connection counts as one.
+func (c *sseConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	switch messageType {
+	case ws.CloseMessage:
+		return c.writeClose(data)
+	case ws.PingMessage:
+		if err := c.write([]byte(": ping\n\n")); err != nil {
+			return err
+		}
+		c.mu.Lock()
+		pong := c.pong
+		c.mu.Unlock()
+		if pong != nil {
+			return pong("")
+		}
+	}
+	return nil
+}
+
+// writeClose ends the stream with a "close" event carrying the close code
+// and reason as JSON.
+func (c *sseConn) writeClose(data []byte) error {
+	closeErr := &ws.CloseError{Code: ws.CloseNoStatusReceived}
+	if len(data) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(data))
+		closeErr.Text = string(data[2:])
+	}
+	c.mu.Lock()
+	if c.closeErr == nil {
+		c.closeErr = closeErr
+	}
+	c.mu.Unlock()
+
+	event, err := json.Marshal(map[string]interface{}{
+		"code":   closeErr.Code,
+		"reason": closeErr.Text,
+	})
+	if err == nil {
+		err = c.writeEvent("close", event)
+	}
+	c.Close()
+	return err
+}
+
+func (c *sseConn) writeEvent(event string, data []byte) error {
+	var buffer bytes.Buffer
+	if event != "" {
+		buffer.WriteString("event: " + event + "\n")
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buffer.WriteString("data: ")
+		buffer.Write(line)
+		buffer.WriteByte('\n')
+	}
+	buffer.WriteByte('\n')
+	return c.write(buffer.Bytes())
+}
+
+func (c *sseConn) write(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	select {
+	case <-c.closed:
+		return net.ErrClosed
+	default:
+	}
+
+	if _, err := c.writer.Write(data); err != nil {
+		return err
+	}
+	return c.controller.Flush()
+}
+
+func (c *sseConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deadline = t
+	return nil
+}
+
+func (c *sseConn) SetWriteDeadline(t time.Time) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	// not every response writer supports deadlines
+	c.controller.SetWriteDeadline(t)
+	return nil
+}
+
+func (c *sseConn) SetPongHandler(h func(appData string) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pong = h
+}
+
+func (c *sseConn) EnableWriteCompression(enable bool) {}
+
+func (c *sseConn) Subprotocol() string {
+	return ""
+}
+
+// Close ends the stream. It waits for a write in progress, so nothing gets
+// written once the handler of the stream returned.
+func (c *sseConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.writeMu.Lock()
+		close(c.closed)
+		c.writeMu.Unlock()
+
+		c.server.sseMu.Lock()
+		delete(c.server.sseConns, c.token)
+		c.server.sseMu.Unlock()
+	})
+	return nil
+}
@@ -0,0 +1,187 @@
+package socketigo_test
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+)
+
+type sseEvent struct {
+	name string
+	data string
+}
+
+// openEventStream opens the event stream of the SSE transport and returns the
+// url to post messages to along with the events arriving on it. The stream
+// gets closed once the test finished, ahead of an http server closed in a
+// cleanup registered before, which would wait for it.
+func openEventStream(t *testing.T, url string) (string, chan sseEvent) {
+	t.Helper()
+
+	response, err := http.Get(url + "/?transport=sse")
+	if err != nil {
+		t.Fatalf("opening the event stream failed: %v", err)
+	}
+	t.Cleanup(func() {
+		response.Body.Close()
+	})
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("stream has content type %q, want text/event-stream", contentType)
+	}
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer close(events)
+
+		scanner := bufio.NewScanner(response.Body)
+		var event sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if event.data != "" || event.name != "" {
+					events <- event
+				}
+				event = sseEvent{}
+			case strings.HasPrefix(line, "event: "):
+				event.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data += strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+
+	open := expectSSEEvent(t, events, "open")
+	return url + "/?transport=sse&sid=" + open.data, events
+}
+
+func expectSSEEvent(t *testing.T, events chan sseEvent, name string) sseEvent {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("event stream ended before a %q event", name)
+			}
+			if event.name == name {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("no %q event on the stream", name)
+		}
+	}
+}
+
+// expectSSEMessage skips the stream up to the message of the event.
+func expectSSEMessage(t *testing.T, events chan sseEvent, eventName string) map[string]interface{} {
+	t.Helper()
+
+	for {
+		event := expectSSEEvent(t, events, "")
+		var message map[string]interface{}
+		if err := json.Unmarshal([]byte(event.data), &message); err != nil {
+			t.Fatalf("message %q is no JSON: %v", event.data, err)
+		}
+		if message["event"] == eventName {
+			data, _ := message["data"].(map[string]interface{})
+			return data
+		}
+	}
+}
+
+func post(t *testing.T, url string, contentType string, body string) int {
+	t.Helper()
+
+	response, err := http.Post(url, contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("posting failed: %v", err)
+	}
+	response.Body.Close()
+	return response.StatusCode
+}
+
+func TestSSE(t *testing.T) {
+	server := echoServer(nil)
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	postUrl, events := openEventStream(t, httpServer.URL)
+	if status := post(t, postUrl, "application/json", `{"event":"echo","data":{"text":"streamed"}}`); status != http.StatusNoContent {
+		t.Fatalf("post answered %d, want 204", status)
+	}
+	if data := expectSSEMessage(t, events, "echo"); data["text"] != "streamed" {
+		t.Fatalf("echo is %v, want the posted message", data)
+	}
+}
+
+func TestSSEUnknownStream(t *testing.T) {
+	httpServer := httptest.NewServer(echoServer(nil))
+	defer httpServer.Close()
+
+	if status := post(t, httpServer.URL+"/?transport=sse&sid=unknown", "application/json", `{"event":"echo"}`); status != http.StatusNotFound {
+		t.Fatalf("post to an unknown stream answered %d, want 404", status)
+	}
+}
+
+func TestSSEPostTooLarge(t *testing.T) {
+	httpServer := httptest.NewServer(echoServer(&socketigo.IgoServerOptions{MaxMessageSize: 1 << 10}))
+	t.Cleanup(httpServer.Close)
+
+	postUrl, _ := openEventStream(t, httpServer.URL)
+	body := `{"event":"echo","data":{"text":"` + strings.Repeat("a", 2<<10) + `"}}`
+	if status := post(t, postUrl, "application/json", body); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized post answered %d, want 413", status)
+	}
+}
+
+func TestSSEDisconnect(t *testing.T) {
+	server := echoServer(nil)
+	server.OnConnected(func(client *socketigo.Client) {
+		client.Disconnect(4000, "bye")
+	})
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	_, events := openEventStream(t, httpServer.URL)
+	var closed struct {
+		Code   int    `json:"code"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(expectSSEEvent(t, events, "close").data), &closed); err != nil {
+		t.Fatal(err)
+	}
+	if closed.Code != 4000 || closed.Reason != "bye" {
+		t.Fatalf("close event is %+v, want 4000 bye", closed)
+	}
+}
+
+func TestSSEBinary(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	server.Of("/").OnBinary("blob", func(client *socketigo.Client, data []byte) {
+		client.EmitBinary("blob", data)
+	})
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	postUrl, events := openEventStream(t, httpServer.URL)
+	// the binary frame: the length of the event name, the name and the data
+	if status := post(t, postUrl, "application/octet-stream", "\x00\x04blob\x01\x02\x03"); status != http.StatusNoContent {
+		t.Fatalf("binary post answered %d, want 204", status)
+	}
+	frame, err := base64.StdEncoding.DecodeString(expectSSEEvent(t, events, "binary").data)
+	if err != nil {
+		t.Fatalf("binary event is no base64: %v", err)
+	}
+	if string(frame) != "\x00\x04blob\x01\x02\x03" {
+		t.Fatalf("binary event is %q, want the posted frame", frame)
+	}
+}
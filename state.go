@@ -0,0 +1,58 @@
+package socketigo
+
+import "sync/atomic"
+
+type ClientState int32
+
+const (
+	// StateConnecting lasts from the upgrade until the client is registered
+	// with its namespace, i.e. during authentication and the pre-connect checks.
+	StateConnecting ClientState = iota
+	StateConnected
+	// StateClosing means the server is closing the client, emits fail already.
+	StateClosing
+	StateClosed
+)
+
+func (s ClientState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateClosing:
+		return "closing"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+func (c *Client) State() ClientState {
+	return ClientState(atomic.LoadInt32(&c.state))
+}
+
+// IsConnected reports whether the client is connected and has not been closed
+// yet. Emits to a client which is not connected anymore return
+// ErrClientClosed, unless its session buffers them until it resumes.
+func (c *Client) IsConnected() bool {
+	return c.State() == StateConnected
+}
+
+// setState moves the client forward to the state, it never goes back.
+func (c *Client) setState(state ClientState) {
+	for {
+		current := atomic.LoadInt32(&c.state)
+		if current >= int32(state) {
+			return
+		}
+		if atomic.CompareAndSwapInt32(&c.state, current, int32(state)) {
+			return
+		}
+	}
+}
+
+func (c *Client) closing() bool {
+	return c.State() >= StateClosing
+}
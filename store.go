@@ -0,0 +1,139 @@
+package socketigo
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultOfflineTTL = 24 * time.Hour
+
+// StoredMessage is an event emitted to a user while none of its clients was
+// connected.
+type StoredMessage struct {
+	Event     string      `json:"event"`
+	Data      interface{} `json:"data,omitempty"`
+	ExpiresAt time.Time   `json:"expiresAt"`
+}
+
+func (m *StoredMessage) Expired(now time.Time) bool {
+	return !m.ExpiresAt.After(now)
+}
+
+// Store queues the messages of offline users until they reconnect.
+type Store interface {
+	Push(userId string, message *StoredMessage) error
+	// Drain removes the queued messages of the user and returns the ones that
+	// did not expire yet, oldest first.
+	Drain(userId string) ([]*StoredMessage, error)
+}
+
+// SetStore enables queueing the messages emitted with EmitToUser while the
+// user is offline.
+func (s *IgoServer) SetStore(store Store) {
+	s.store = store
+}
+
+// SetUserId binds the client to a user. Set it from a middleware or the
+// connected handler, so the messages queued for the user get delivered right
+// after the handshake.
+func (c *Client) SetUserId(userId string) {
+	c.userMu.Lock()
+	previous := c.userId
+	c.userId = userId
+	c.userMu.Unlock()
+
+	// clients still connecting get bound once they are registered
+	if previous != userId && c.IsConnected() {
+		c.Server.unbindUser(c, previous)
+		c.Server.bindUser(c, userId)
+	}
+}
+
+func (c *Client) UserId() string {
+	c.userMu.RLock()
+	defer c.userMu.RUnlock()
+
+	return c.userId
+}
+
+// EmitToUser emits to every client of the user connected to this instance. If
+// there is none and a store is set, the message is queued for OfflineTTL.
+func (s *IgoServer) EmitToUser(userId string, eventName string, data interface{}) error {
+	delivered := false
+	if user := s.GetUser(userId); user != nil {
+		for _, client := range user.Clients() {
+			client.Emit(eventName, data)
+			delivered = true
+		}
+	}
+
+	if delivered || s.store == nil {
+		return nil
+	}
+
+	return s.store.Push(userId, &StoredMessage{
+		Event:     eventName,
+		Data:      data,
+		ExpiresAt: time.Now().Add(s.offlineTTL),
+	})
+}
+
+func (s *IgoServer) deliverStored(client *Client) {
+	userId := client.UserId()
+	if s.store == nil || userId == "" {
+		return
+	}
+
+	messages, err := s.store.Drain(userId)
+	if err != nil {
+		s.emitError(client, nil, nil, err)
+		return
+	}
+
+	for _, message := range messages {
+		client.Emit(message.Event, message.Data)
+	}
+}
+
+// MemoryStore keeps the queued messages in the process.
+type MemoryStore struct {
+	mu       sync.Mutex
+	messages map[string][]*StoredMessage
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		messages: make(map[string][]*StoredMessage),
+	}
+}
+
+func (m *MemoryStore) Push(userId string, message *StoredMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	queued := m.messages[userId][:0]
+	for _, queuedMessage := range m.messages[userId] {
+		if !queuedMessage.Expired(now) {
+			queued = append(queued, queuedMessage)
+		}
+	}
+	m.messages[userId] = append(queued, message)
+	return nil
+}
+
+func (m *MemoryStore) Drain(userId string) ([]*StoredMessage, error) {
+	m.mu.Lock()
+	queued := m.messages[userId]
+	delete(m.messages, userId)
+	m.mu.Unlock()
+
+	now := time.Now()
+	messages := make([]*StoredMessage, 0, len(queued))
+	for _, message := range queued {
+		if !message.Expired(now) {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
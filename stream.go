@@ -0,0 +1,342 @@
+package socketigo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Streams are multiplexed over binary events named "#stream" whose payload
+// starts with the big endian uint32 id of the stream and a byte telling what
+// follows: the name of the stream being opened, data, a uint32 window update
+// granting the other side more bytes, or nothing for a close. The server opens
+// streams with odd ids, clients with even ones.
+
+const (
+	streamEvent      = "#stream"
+	streamHeaderSize = 5
+	// streamWindow is the number of bytes a side may send before the other
+	// side read them.
+	streamWindow    = 256 << 10
+	streamFrameSize = 16 << 10
+	maxStreams      = 64
+)
+
+const (
+	streamOpen byte = iota
+	streamData
+	streamWindowUpdate
+	streamClose
+)
+
+var (
+	ErrStreamClosed   = errors.New("socketigo: stream closed")
+	ErrTooManyStreams = errors.New("socketigo: too many streams")
+)
+
+// StreamListener gets called on its own goroutine for every stream a client
+// opens, so it may block reading it.
+type StreamListener func(client *Client, stream *Stream)
+
+// Stream is a byte stream multiplexed over the connection of a client. Writes
+// block once the other side has a window worth of bytes left to read, reads
+// return io.EOF after the other side closed the stream. A stream must not be
+// written from several goroutines at a time.
+type Stream struct {
+	Name         string
+	id           uint32
+	client       *Client
+	mu           sync.Mutex
+	cond         *sync.Cond
+	buffer       bytes.Buffer
+	credit       int
+	consumed     int
+	closed       bool
+	remoteClosed bool
+	err          error
+}
+
+func newStream(client *Client, id uint32, name string) *Stream {
+	stream := &Stream{
+		Name:   name,
+		id:     id,
+		client: client,
+		credit: streamWindow,
+	}
+	stream.cond = sync.NewCond(&stream.mu)
+	return stream
+}
+
+// OpenStream opens a stream to the client, which accepts it with OnStream.
+func (s *IgoServer) OpenStream(client *Client, name string) (*Stream, error) {
+	client.streamsMu.Lock()
+	if len(client.streams) >= maxStreams {
+		client.streamsMu.Unlock()
+		return nil, ErrTooManyStreams
+	}
+	client.streamSeq++
+	stream := newStream(client, client.streamSeq*2-1, name)
+	client.addStream(stream)
+	client.streamsMu.Unlock()
+
+	if err := client.sendStream(stream.id, streamOpen, []byte(name)); err != nil {
+		client.removeStream(stream)
+		return nil, err
+	}
+	return stream, nil
+}
+
+// OnStream accepts the streams named name the clients of the namespace open.
+// Streams without a listener get closed right away.
+func (n *Namespace) OnStream(name string, listener StreamListener) {
+	n.eventsMu.Lock()
+	defer n.eventsMu.Unlock()
+
+	n.streams[name] = listener
+}
+
+func (s *Stream) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	for s.buffer.Len() == 0 && !s.closed && !s.remoteClosed {
+		s.cond.Wait()
+	}
+
+	switch {
+	case s.closed:
+		s.mu.Unlock()
+		return 0, ErrStreamClosed
+	case s.buffer.Len() == 0:
+		err := s.err
+		s.mu.Unlock()
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, err
+	}
+
+	n, _ := s.buffer.Read(p)
+	// the window is granted back in halves, rather than per read
+	s.consumed += n
+	grant := 0
+	if s.consumed >= streamWindow/2 && !s.remoteClosed {
+		grant, s.consumed = s.consumed, 0
+	}
+	s.mu.Unlock()
+
+	if grant > 0 {
+		update := make([]byte, 4)
+		binary.BigEndian.PutUint32(update, uint32(grant))
+		s.client.sendStream(s.id, streamWindowUpdate, update)
+	}
+	return n, nil
+}
+
+func (s *Stream) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		s.mu.Lock()
+		for s.credit == 0 && !s.closed && !s.remoteClosed {
+			s.cond.Wait()
+		}
+		if s.closed || s.remoteClosed {
+			err := s.err
+			s.mu.Unlock()
+			if err == nil {
+				err = ErrStreamClosed
+			}
+			return written, err
+		}
+		n := min(len(p), s.credit, streamFrameSize)
+		s.credit -= n
+		s.mu.Unlock()
+
+		if err := s.client.sendStream(s.id, streamData, p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Close closes the stream in both directions, discarding what was not read
+// yet.
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	remoteClosed := s.remoteClosed
+	s.buffer.Reset()
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	s.client.removeStream(s)
+	if remoteClosed {
+		return nil
+	}
+	return s.client.sendStream(s.id, streamClose, nil)
+}
+
+func (s *Stream) receive(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	if s.buffer.Len()+len(data) > streamWindow {
+		return fmt.Errorf("stream %d exceeded its window", s.id)
+	}
+	s.buffer.Write(data)
+	s.cond.Broadcast()
+	return nil
+}
+
+func (s *Stream) grant(credit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.credit = min(s.credit+credit, streamWindow)
+	s.cond.Broadcast()
+}
+
+// closeRemote ends the stream once the other side closed it, or the
+// connection got lost with err.
+func (s *Stream) closeRemote(err error) {
+	s.mu.Lock()
+	if s.remoteClosed {
+		s.mu.Unlock()
+		return
+	}
+	s.remoteClosed = true
+	s.err = err
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	s.client.removeStream(s)
+}
+
+// addStream registers the stream, the caller holds streamsMu.
+func (c *Client) addStream(stream *Stream) {
+	if c.streams == nil {
+		c.streams = make(map[uint32]*Stream)
+	}
+	c.streams[stream.id] = stream
+}
+
+func (c *Client) removeStream(stream *Stream) {
+	c.streamsMu.Lock()
+	defer c.streamsMu.Unlock()
+
+	if c.streams[stream.id] == stream {
+		delete(c.streams, stream.id)
+	}
+}
+
+func (c *Client) closeStreams(err error) {
+	c.streamsMu.Lock()
+	streams := make([]*Stream, 0, len(c.streams))
+	for _, stream := range c.streams {
+		streams = append(streams, stream)
+	}
+	c.streamsMu.Unlock()
+
+	for _, stream := range streams {
+		stream.closeRemote(err)
+	}
+}
+
+func (c *Client) sendStream(id uint32, kind byte, data []byte) error {
+	payload := make([]byte, streamHeaderSize+len(data))
+	binary.BigEndian.PutUint32(payload, id)
+	payload[4] = kind
+	copy(payload[streamHeaderSize:], data)
+	return c.EmitBinary(streamEvent, payload)
+}
+
+// handleStream handles a stream message of the client on its reader, so the
+// data of a stream stays in order whatever the dispatch order.
+func (c *Client) handleStream(payload []byte) {
+	if len(payload) < streamHeaderSize {
+		c.rejectFrame(nil, errors.New("stream header too short"))
+		return
+	}
+	id := binary.BigEndian.Uint32(payload)
+	kind := payload[4]
+	data := payload[streamHeaderSize:]
+
+	if kind == streamOpen {
+		c.acceptStream(id, string(data))
+		return
+	}
+
+	c.streamsMu.Lock()
+	stream := c.streams[id]
+	c.streamsMu.Unlock()
+
+	// messages in flight may still arrive for a stream closed meanwhile
+	if stream == nil {
+		return
+	}
+
+	switch kind {
+	case streamData:
+		if err := stream.receive(data); err != nil {
+			c.rejectFrame(nil, err)
+			stream.Close()
+		}
+	case streamWindowUpdate:
+		if len(data) < 4 {
+			c.rejectFrame(nil, fmt.Errorf("window update of stream %d too short", id))
+			return
+		}
+		stream.grant(int(binary.BigEndian.Uint32(data)))
+	case streamClose:
+		stream.closeRemote(nil)
+	default:
+		c.rejectFrame(nil, fmt.Errorf("unknown message %d on stream %d", kind, id))
+	}
+}
+
+func (c *Client) acceptStream(id uint32, name string) {
+	if id%2 == 1 {
+		c.rejectFrame(nil, fmt.Errorf("stream id %d is odd", id))
+		return
+	}
+
+	c.Namespace.eventsMu.RLock()
+	listener := c.Namespace.streams[name]
+	c.Namespace.eventsMu.RUnlock()
+
+	c.streamsMu.Lock()
+	if _, exists := c.streams[id]; exists {
+		c.streamsMu.Unlock()
+		c.rejectFrame(nil, fmt.Errorf("stream %d opened twice", id))
+		return
+	}
+	if listener == nil || len(c.streams) >= maxStreams {
+		c.streamsMu.Unlock()
+		c.sendStream(id, streamClose, nil)
+		return
+	}
+	stream := newStream(c, id, name)
+	c.addStream(stream)
+	c.streamsMu.Unlock()
+
+	go func() {
+		defer func() {
+			if value := recover(); value != nil {
+				c.handlePanic(streamEvent, nil, value)
+				stream.Close()
+			}
+		}()
+
+		listener(c, stream)
+	}()
+}
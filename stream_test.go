@@ -0,0 +1,107 @@
+package socketigo_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/igoclient"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+func TestStreamEcho(t *testing.T) {
+	streamEcho(t, nil, nil)
+}
+
+// Stream frames are larger than the chunks, but must not be chunked since
+// chunked messages cannot carry them.
+func TestStreamEchoWithChunking(t *testing.T) {
+	streamEcho(t, &socketigo.IgoServerOptions{ChunkSize: 1 << 10}, &testclient.Options{ChunkSize: 1 << 10})
+}
+
+func streamEcho(t *testing.T, serverOptions *socketigo.IgoServerOptions, clientOptions *testclient.Options) {
+	server := socketigo.CreateIgoServer(serverOptions)
+	server.OnStream("echo", func(client *socketigo.Client, stream *socketigo.Stream) {
+		defer stream.Close()
+		io.Copy(stream, stream)
+	})
+
+	client := testclient.MustConnect(t, server, clientOptions)
+	stream, err := client.OpenStream("echo")
+	if err != nil {
+		t.Fatalf("opening the stream failed: %v", err)
+	}
+	defer stream.Close()
+
+	// more than a window, so both sides have to wait for the other to read
+	data := make([]byte, 1<<20)
+	rand.Read(data)
+	written := make(chan error, 1)
+	go func() {
+		_, err := stream.Write(data)
+		written <- err
+	}()
+
+	echoed := make([]byte, len(data))
+	if _, err := io.ReadFull(stream, echoed); err != nil {
+		t.Fatalf("reading the echo failed: %v", err)
+	}
+	if err := <-written; err != nil {
+		t.Fatalf("writing to the stream failed: %v", err)
+	}
+	if !bytes.Equal(echoed, data) {
+		t.Fatal("echo differs from what got written")
+	}
+}
+
+func TestServerOpenStream(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	connected := make(chan *socketigo.Client, 1)
+	server.OnConnected(func(client *socketigo.Client) {
+		connected <- client
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	received := make(chan []byte, 1)
+	client.OnStream("greeting", func(client *igoclient.Client, stream *igoclient.Stream) {
+		defer stream.Close()
+		data, _ := io.ReadAll(stream)
+		received <- data
+	})
+
+	stream, err := server.OpenStream(<-connected, "greeting")
+	if err != nil {
+		t.Fatalf("opening the stream failed: %v", err)
+	}
+	if _, err := stream.Write([]byte("hello")); err != nil {
+		t.Fatalf("writing to the stream failed: %v", err)
+	}
+	stream.Close()
+
+	select {
+	case data := <-received:
+		if string(data) != "hello" {
+			t.Fatalf("client read %q, want %q", data, "hello")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("client did not read the stream to its end")
+	}
+}
+
+func TestStreamWithoutListener(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+
+	client := testclient.MustConnect(t, server, nil)
+	stream, err := client.OpenStream("missing")
+	if err != nil {
+		t.Fatalf("opening the stream failed: %v", err)
+	}
+	defer stream.Close()
+
+	if _, err := io.ReadAll(stream); err != nil {
+		t.Fatalf("stream without a listener ended with %v, want it closed", err)
+	}
+}
@@ -0,0 +1,187 @@
+package socketigo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+const (
+	// framedHeaderSize is the type byte and the length in front of a message
+	framedHeaderSize  = 5
+	maxControlPayload = 125
+)
+
+// streamConn frames websocket messages on a reliable byte stream, for
+// transports which have no messages of their own. Every message is its
+// websocket message type as a byte, its length as a big endian uint32 and the
+// payload.
+type streamConn struct {
+	stream    io.ReadWriteCloser
+	reader    *bufio.Reader
+	unread    *io.LimitedReader
+	readLimit int64
+	writeMu   sync.Mutex
+	writer    *bufio.Writer
+	pong      func(appData string) error
+	closeOnce sync.Once
+	closeSent bool
+}
+
+// NewStreamConn returns a connection framing messages on the stream, so a
+// transport handing out a byte stream, like a QUIC or TCP stream, only needs
+// to upgrade to it. Deadlines are passed on if the stream supports them.
+func NewStreamConn(stream io.ReadWriteCloser) Conn {
+	return &streamConn{
+		stream: stream,
+		reader: bufio.NewReader(stream),
+		writer: bufio.NewWriter(stream),
+	}
+}
+
+func (c *streamConn) NextReader() (int, io.Reader, error) {
+	// whatever the last reader left of its message
+	if c.unread != nil {
+		if _, err := io.Copy(io.Discard, c.unread); err != nil {
+			return 0, nil, err
+		}
+		c.unread = nil
+	}
+
+	for {
+		var header [framedHeaderSize]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			return 0, nil, err
+		}
+		messageType := int(header[0])
+		length := int64(binary.BigEndian.Uint32(header[1:]))
+
+		switch messageType {
+		case ws.TextMessage, ws.BinaryMessage:
+			if c.readLimit > 0 && length > c.readLimit {
+				c.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(ws.CloseMessageTooBig, ""), time.Now().Add(time.Second))
+				return 0, nil, ws.ErrReadLimit
+			}
+			c.unread = &io.LimitedReader{R: c.reader, N: length}
+			return messageType, c.unread, nil
+		case ws.PingMessage, ws.PongMessage, ws.CloseMessage:
+		default:
+			return 0, nil, fmt.Errorf("socketigo: unknown message type %d", messageType)
+		}
+
+		if length > maxControlPayload {
+			return 0, nil, fmt.Errorf("socketigo: control message of %d bytes", length)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return 0, nil, err
+		}
+
+		switch messageType {
+		case ws.PingMessage:
+			c.WriteControl(ws.PongMessage, payload, time.Now().Add(time.Second))
+		case ws.PongMessage:
+			if c.pong != nil {
+				if err := c.pong(string(payload)); err != nil {
+					return 0, nil, err
+				}
+			}
+		case ws.CloseMessage:
+			closeErr := &ws.CloseError{Code: ws.CloseNoStatusReceived}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Text = string(payload[2:])
+			}
+			c.WriteControl(ws.CloseMessage, payload, time.Now().Add(time.Second))
+			return 0, nil, closeErr
+		}
+	}
+}
+
+func (c *streamConn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case ws.TextMessage, ws.BinaryMessage:
+		return c.write(messageType, data)
+	case ws.PingMessage, ws.PongMessage, ws.CloseMessage:
+		return c.writeControl(messageType, data)
+	default:
+		return fmt.Errorf("socketigo: unknown message type %d", messageType)
+	}
+}
+
+func (c *streamConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != ws.PingMessage && messageType != ws.PongMessage && messageType != ws.CloseMessage {
+		return fmt.Errorf("socketigo: unknown control message type %d", messageType)
+	}
+
+	c.SetWriteDeadline(deadline)
+	return c.writeControl(messageType, data)
+}
+
+func (c *streamConn) writeControl(messageType int, data []byte) error {
+	if len(data) > maxControlPayload {
+		return fmt.Errorf("socketigo: control message of %d bytes", len(data))
+	}
+	return c.write(messageType, data)
+}
+
+func (c *streamConn) write(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	// nothing goes out behind a close
+	if c.closeSent {
+		return ws.ErrCloseSent
+	}
+	if messageType == ws.CloseMessage {
+		c.closeSent = true
+	}
+
+	var header [framedHeaderSize]byte
+	header[0] = byte(messageType)
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+	c.writer.Write(header[:])
+	c.writer.Write(data)
+	return c.writer.Flush()
+}
+
+func (c *streamConn) SetReadDeadline(t time.Time) error {
+	if stream, ok := c.stream.(interface{ SetReadDeadline(time.Time) error }); ok {
+		return stream.SetReadDeadline(t)
+	}
+	return nil
+}
+
+func (c *streamConn) SetWriteDeadline(t time.Time) error {
+	if stream, ok := c.stream.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		return stream.SetWriteDeadline(t)
+	}
+	return nil
+}
+
+func (c *streamConn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+func (c *streamConn) SetPongHandler(h func(appData string) error) {
+	c.pong = h
+}
+
+func (c *streamConn) EnableWriteCompression(enable bool) {}
+
+func (c *streamConn) Subprotocol() string {
+	return ""
+}
+
+func (c *streamConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.stream.Close()
+	})
+	return err
+}
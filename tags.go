@@ -0,0 +1,110 @@
+package socketigo
+
+// Tags are lightweight cohorts of clients, cheaper than rooms: they are only
+// known to this node and have no handlers. The server indexes the clients of
+// every tag, so emitting to a tag does not scan all clients.
+
+func (c *Client) AddTag(tags ...string) {
+	s := c.Server
+	s.tagsMu.Lock()
+	defer s.tagsMu.Unlock()
+
+	if c.tags == nil {
+		c.tags = make(map[string]struct{})
+	}
+	for _, tag := range tags {
+		c.tags[tag] = struct{}{}
+
+		index, ok := s.tags[tag]
+		if !ok {
+			index = make(map[*Client]struct{})
+			s.tags[tag] = index
+		}
+		index[c] = struct{}{}
+	}
+}
+
+func (c *Client) RemoveTag(tags ...string) {
+	s := c.Server
+	s.tagsMu.Lock()
+	defer s.tagsMu.Unlock()
+
+	for _, tag := range tags {
+		delete(c.tags, tag)
+		s.unindexTag(tag, c)
+	}
+}
+
+func (c *Client) HasTag(tag string) bool {
+	c.Server.tagsMu.RLock()
+	defer c.Server.tagsMu.RUnlock()
+
+	_, ok := c.tags[tag]
+	return ok
+}
+
+func (c *Client) Tags() []string {
+	c.Server.tagsMu.RLock()
+	defer c.Server.tagsMu.RUnlock()
+
+	tags := make([]string, 0, len(c.tags))
+	for tag := range c.tags {
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func (s *IgoServer) unindexTag(tag string, client *Client) {
+	index := s.tags[tag]
+	delete(index, client)
+	if len(index) == 0 {
+		delete(s.tags, tag)
+	}
+}
+
+// untagAll drops a client which is gone from the index.
+func (s *IgoServer) untagAll(client *Client) {
+	s.tagsMu.Lock()
+	defer s.tagsMu.Unlock()
+
+	for tag := range client.tags {
+		s.unindexTag(tag, client)
+	}
+	client.tags = nil
+}
+
+// retag hands the tags of a detached client over to the one resuming its
+// session.
+func (s *IgoServer) retag(old *Client, client *Client) {
+	s.tagsMu.Lock()
+	defer s.tagsMu.Unlock()
+
+	if client.tags == nil && len(old.tags) > 0 {
+		client.tags = make(map[string]struct{}, len(old.tags))
+	}
+	for tag := range old.tags {
+		client.tags[tag] = struct{}{}
+		delete(s.tags[tag], old)
+		s.tags[tag][client] = struct{}{}
+	}
+	old.tags = nil
+}
+
+// ClientsWithTag returns the clients of this node carrying the tag.
+func (s *IgoServer) ClientsWithTag(tag string) []*Client {
+	s.tagsMu.RLock()
+	defer s.tagsMu.RUnlock()
+
+	clients := make([]*Client, 0, len(s.tags[tag]))
+	for client := range s.tags[tag] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+func (s *IgoServer) EmitToTag(tag string, eventName string, data interface{}) {
+	s.emitToClients(s.ClientsWithTag(tag), map[string]interface{}{
+		"event": eventName,
+		"data":  data,
+	}, nil, false)
+}
@@ -0,0 +1,168 @@
+package socketigo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// The raw transport carries the messages of a client over a plain stream
+// connection, framed as by NewStreamConn, without HTTP or websocket in front.
+// The first message of a client is a text message carrying the query string
+// it would connect to the websocket with, e.g. "namespace=/chat". Everything
+// after it works the same as on a websocket.
+
+const rawRequestTimeout = 10 * time.Second
+
+// ListenAndServeTCP accepts raw connections on the TCP address until the
+// server shuts down.
+func (s *IgoServer) ListenAndServeTCP(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.ServeRaw(listener)
+}
+
+// ServeRaw accepts raw connections on the listener and serves each on its own
+// goroutine. It returns ErrServerShutdown once Shutdown closed the listener,
+// or the error accepting failed with otherwise.
+func (s *IgoServer) ServeRaw(listener net.Listener) error {
+	listener = s.proxyListener(listener)
+	s.trackListener(listener, true)
+	defer s.trackListener(listener, false)
+
+	if s.isShuttingDown() {
+		listener.Close()
+		return ErrServerShutdown
+	}
+
+	for {
+		netConn, err := listener.Accept()
+		if err != nil {
+			if s.isShuttingDown() {
+				return ErrServerShutdown
+			}
+			return err
+		}
+		go s.serveRaw(netConn)
+	}
+}
+
+func (s *IgoServer) trackListener(listener net.Listener, add bool) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+
+	if add {
+		s.listeners[listener] = struct{}{}
+	} else {
+		delete(s.listeners, listener)
+	}
+}
+
+// closeListeners stops accepting raw connections.
+func (s *IgoServer) closeListeners() {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+
+	for listener := range s.listeners {
+		listener.Close()
+	}
+}
+
+func (s *IgoServer) serveRaw(netConn net.Conn) {
+	conn := NewStreamConn(netConn)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r, err := readRawRequest(ctx, conn, netConn)
+	if err != nil {
+		s.emitError(nil, nil, ErrUpgradeFailed, err)
+		rejectHandshake(conn, s.codec, ws.CloseProtocolError, malformedFrameCode, fmt.Errorf("malformed connection request: %w", err))
+		return
+	}
+
+	s.ServeUpgraded(conn, r)
+}
+
+// readRawRequest turns the first message of a raw connection into the request
+// the rest of the server works with.
+func readRawRequest(ctx context.Context, conn Conn, netConn net.Conn) (*http.Request, error) {
+	// first, it reads the PROXY protocol header of a proxied connection
+	remoteAddr := netConn.RemoteAddr().String()
+
+	conn.SetReadDeadline(time.Now().Add(rawRequestTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	messageType, reader, err := conn.NextReader()
+	if err != nil {
+		return nil, err
+	}
+	if messageType != ws.TextMessage {
+		return nil, errors.New("expected a text message")
+	}
+	query, err := io.ReadAll(io.LimitReader(reader, 4096))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := url.ParseQuery(string(query)); err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, "/?"+string(query), nil)
+	if err != nil {
+		return nil, err
+	}
+	r.RemoteAddr = remoteAddr
+	r.Host = netConn.LocalAddr().String()
+	return r, nil
+}
+
+// rawResponse records the HTTP error a request got rejected with before the
+// upgrade, so it can be passed on to a raw client as a close frame.
+type rawResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *rawResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *rawResponse) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(data)
+}
+
+func (r *rawResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *rawResponse) reject(conn Conn, codec Codec) {
+	closeCode := ws.ClosePolicyViolation
+	if r.status == http.StatusServiceUnavailable || r.status == http.StatusTooManyRequests {
+		closeCode = ws.CloseTryAgainLater
+	}
+
+	message := strings.TrimSpace(r.body.String())
+	if message == "" {
+		message = http.StatusText(r.status)
+	}
+	rejectHandshake(conn, codec, closeCode, connectionRejectedCode, errors.New(message))
+}
@@ -0,0 +1,228 @@
+// Package testclient connects clients to a socket.igo server in memory, so
+// event handlers, rooms and broadcasts can be unit tested without a network.
+//
+//	server := socketigo.CreateIgoServer(nil)
+//	server.On("ping", func(client *socketigo.Client, data map[string]interface{}) interface{} {
+//		client.Emit("pong", data)
+//		return nil
+//	})
+//
+//	client := testclient.MustConnect(t, server, nil)
+//	client.Emit("ping", map[string]interface{}{"n": 1})
+//	data := client.ExpectEvent(t, "pong")
+package testclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/igoclient"
+)
+
+const defaultTimeout = time.Second
+
+var ErrTimeout = errors.New("testclient: timed out waiting for event")
+
+type Options struct {
+	// Namespace is the server namespace to connect to. Defaults to "/".
+	Namespace string
+	// Auth is sent as the first frame of the connection when set.
+	Auth   map[string]interface{}
+	Header http.Header
+	// Codec has to match the codec of the server. Defaults to JSON.
+	Codec igoclient.Codec
+	// Timeout is the time the expectations wait for an event. Defaults to one
+	// second.
+	Timeout time.Duration
+	// ChunkSize splits messages larger than it into chunks, see
+	// igoclient.Options.
+	ChunkSize int
+}
+
+// Event is an event the client received.
+type Event struct {
+	Name string
+	Data map[string]interface{}
+}
+
+// Client is an igoclient connected over an in-memory pipe. It records every
+// event it receives until an expectation consumes it, so the any listener of
+// the underlying client must not be replaced.
+type Client struct {
+	*igoclient.Client
+	timeout time.Duration
+	mu      sync.Mutex
+	events  []Event
+	changed chan struct{}
+	conn    net.Conn
+}
+
+// Connect connects a client to the server and blocks until the handshake
+// completed. The client does not reconnect on its own, calling Connect of the
+// underlying client reconnects it.
+func Connect(server *socketigo.IgoServer, options *Options) (*Client, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	c := &Client{
+		timeout: timeout,
+		changed: make(chan struct{}),
+	}
+	c.Client = igoclient.NewClient("ws://socketigo.test/", &igoclient.Options{
+		Namespace:        options.Namespace,
+		Auth:             options.Auth,
+		Header:           options.Header,
+		Codec:            options.Codec,
+		Dialer:           pipeDialer(server, c.setConn),
+		DisableReconnect: true,
+		ChunkSize:        options.ChunkSize,
+	})
+	c.Client.OnAny(func(client *igoclient.Client, eventName string, data map[string]interface{}) {
+		c.record(Event{Name: eventName, Data: data})
+	})
+
+	if err := c.Client.Connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// MustConnect is Connect, failing the test if the client cannot connect. The
+// client gets closed once the test finished.
+func MustConnect(t testing.TB, server *socketigo.IgoServer, options *Options) *Client {
+	t.Helper()
+
+	c, err := Connect(server, options)
+	if err != nil {
+		t.Fatalf("testclient: connecting failed: %v", err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+	})
+	return c
+}
+
+func (c *Client) setConn(conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conn = conn
+}
+
+// Drop cuts the connection without a close frame, like a network failure
+// would, so a server with a ResumeWindow keeps the session for the client to
+// resume by connecting again.
+func (c *Client) Drop() error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+func (c *Client) record(event Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.events = append(c.events, event)
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// take removes the first recorded event with the given name.
+func (c *Client) take(eventName string) (Event, bool, chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, event := range c.events {
+		if event.Name == eventName {
+			c.events = append(c.events[:i], c.events[i+1:]...)
+			return event, true, nil
+		}
+	}
+	return Event{}, false, c.changed
+}
+
+// Events returns the received events no expectation consumed yet.
+func (c *Client) Events() []Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Event(nil), c.events...)
+}
+
+// WaitEvent waits for the event and returns its data. Events received before
+// with other names stay recorded.
+func (c *Client) WaitEvent(eventName string, timeout time.Duration) (map[string]interface{}, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		event, ok, changed := c.take(eventName)
+		if ok {
+			return event.Data, nil
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return nil, fmt.Errorf("%w %q", ErrTimeout, eventName)
+		}
+	}
+}
+
+// ExpectEvent waits for the event and fails the test if it does not arrive
+// within the timeout of the client.
+func (c *Client) ExpectEvent(t testing.TB, eventName string) map[string]interface{} {
+	t.Helper()
+
+	data, err := c.WaitEvent(eventName, c.timeout)
+	if err != nil {
+		t.Fatalf("testclient: %v, received %v", err, eventNames(c.Events()))
+	}
+	return data
+}
+
+// ExpectNoEvent fails the test if the event arrives within wait.
+func (c *Client) ExpectNoEvent(t testing.TB, eventName string, wait time.Duration) {
+	t.Helper()
+
+	if data, err := c.WaitEvent(eventName, wait); err == nil {
+		t.Fatalf("testclient: unexpected event %q: %v", eventName, data)
+	}
+}
+
+// ExpectAck emits the event and fails the test if it does not get acked within
+// the timeout of the client.
+func (c *Client) ExpectAck(t testing.TB, eventName string, data interface{}) interface{} {
+	t.Helper()
+
+	result, err := c.EmitWithAck(eventName, data, c.timeout)
+	if err != nil {
+		t.Fatalf("testclient: ack of %q: %v", eventName, err)
+	}
+	return result
+}
+
+func eventNames(events []Event) []string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.Name
+	}
+	return names
+}
@@ -0,0 +1,95 @@
+package testclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+
+	ws "github.com/gorilla/websocket"
+	socketigo "github.com/nauri-io/socket.igo"
+)
+
+// pipeDialer connects every dial to the server over a net.Pipe, serving the
+// upgrade request right on the other end. dialed gets the client end of every
+// pipe.
+func pipeDialer(server *socketigo.IgoServer, dialed func(conn net.Conn)) *ws.Dialer {
+	handler := server.Handle()
+
+	return &ws.Dialer{
+		NetDialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			clientConn, serverConn := net.Pipe()
+			go serve(handler, serverConn)
+			dialed(clientConn)
+			return clientConn, nil
+		},
+		HandshakeTimeout: defaultTimeout,
+	}
+}
+
+func serve(handler socketigo.IgoServerHandle, conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	r, err := http.ReadRequest(reader)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	r.RemoteAddr = conn.RemoteAddr().String()
+
+	w := &pipeResponseWriter{
+		conn:   conn,
+		rw:     bufio.NewReadWriter(reader, bufio.NewWriter(conn)),
+		header: make(http.Header),
+		status: http.StatusOK,
+	}
+	handler(w, r)
+
+	if !w.hijacked {
+		w.finish()
+	}
+}
+
+// pipeResponseWriter hands the pipe over to the upgrader and writes plain
+// responses, e.g. for an unknown namespace, once the handler returned.
+type pipeResponseWriter struct {
+	conn     net.Conn
+	rw       *bufio.ReadWriter
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	hijacked bool
+}
+
+func (w *pipeResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *pipeResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *pipeResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *pipeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return w.conn, w.rw, nil
+}
+
+func (w *pipeResponseWriter) finish() {
+	defer w.conn.Close()
+
+	response := &http.Response{
+		StatusCode:    w.status,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header,
+		Body:          io.NopCloser(&w.body),
+		ContentLength: int64(w.body.Len()),
+		Close:         true,
+	}
+	response.Write(w.conn)
+}
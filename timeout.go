@@ -0,0 +1,78 @@
+package socketigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrHandlerTimeout = errors.New("socketigo: handler timed out")
+
+// SetTimeout overrides HandlerTimeout for the event. A timeout of zero lets its
+// listeners run for as long as they take.
+func (n *Namespace) SetTimeout(eventName string, timeout time.Duration) {
+	n.eventsMu.Lock()
+	defer n.eventsMu.Unlock()
+
+	n.timeouts[eventName] = timeout
+}
+
+func (n *Namespace) handlerTimeout(eventName string) time.Duration {
+	n.eventsMu.RLock()
+	defer n.eventsMu.RUnlock()
+
+	if timeout, ok := n.timeouts[eventName]; ok {
+		return timeout
+	}
+	return n.server.handlerTimeout
+}
+
+// runListeners invokes the listeners in order and returns the result of the
+// first one, which acknowledges the event.
+func runListeners(ctx *EventContext, listeners []ContextListener, data map[string]interface{}) interface{} {
+	var result interface{}
+	for i, listener := range listeners {
+		if r := listener(ctx, data); i == 0 {
+			result = r
+		}
+	}
+	return result
+}
+
+// runListenersWithTimeout runs the listeners in their own goroutine and gives
+// up on them once the context of the event expires. Listeners still running by
+// then should watch the context, their result is dropped. When the event must
+// not be acked, the returned result is the error it failed with.
+func runListenersWithTimeout(ctx *EventContext, listeners []ContextListener, data map[string]interface{}) (interface{}, bool) {
+	client := ctx.Client
+	results := make(chan interface{}, 1)
+	panicked := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if value := recover(); value != nil {
+				panicked <- client.handlePanic(ctx.Event, &ctx.frame, value)
+			}
+		}()
+
+		results <- runListeners(ctx, listeners, data)
+	}()
+
+	select {
+	case result := <-results:
+		return result, true
+	case err := <-panicked:
+		return err, false
+	case <-ctx.Done():
+	}
+
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// the client disconnected, there is no one to answer
+		return ctx.Err(), false
+	}
+
+	err := fmt.Errorf("%w: event %q", ErrHandlerTimeout, ctx.Event)
+	client.Server.emitError(client, nil, nil, err)
+	return err, true
+}
@@ -0,0 +1,257 @@
+package socketigo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Topics are a publish/subscribe model next to rooms: clients subscribe to
+// topic filters themselves and published messages are routed to every client
+// with a matching filter. Filters follow MQTT, topic levels are separated by
+// "/", a "+" matches a single level and a trailing "#" any number of levels,
+// including none. So "sensors/+/temp" matches "sensors/7/temp" and "sensors/#"
+// matches "sensors" as well as "sensors/7/temp".
+//
+// Clients subscribe with a "#subscribe" frame carrying {"topic"}, acknowledged
+// with an error if the filter is invalid or refused, and unsubscribe with
+// "#unsubscribe". Messages arrive as "#message" carrying {"topic", "data"},
+// once per client even if several of its filters match.
+
+const (
+	subscribeEvent          = "#subscribe"
+	unsubscribeEvent        = "#unsubscribe"
+	messageEvent            = "#message"
+	defaultMaxSubscriptions = 100
+)
+
+var (
+	ErrInvalidTopic         = errors.New("socketigo: invalid topic")
+	ErrTooManySubscriptions = errors.New("socketigo: too many subscriptions")
+)
+
+func validTopicFilter(filter string) bool {
+	if filter == "" {
+		return false
+	}
+
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		switch {
+		case level == "#":
+			if i != len(levels)-1 {
+				return false
+			}
+		case level == "+":
+		case strings.ContainsAny(level, "+#"):
+			return false
+		}
+	}
+	return true
+}
+
+func validTopic(topic string) bool {
+	return topic != "" && !strings.ContainsAny(topic, "+#")
+}
+
+func matchTopic(filter string, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+// OnSubscribe gates the subscriptions clients ask for with "#subscribe", e.g.
+// by their claims. Without it every valid filter is accepted. Subscribe called
+// by the server is not gated.
+func (n *Namespace) OnSubscribe(guard func(client *Client, filter string) error) {
+	n.subscribeGuard = guard
+}
+
+// SetMaxSubscriptions limits the filters a client may subscribe to with
+// "#subscribe", defaulting to 100. Zero disables the limit. Subscribe called
+// by the server is not limited.
+func (n *Namespace) SetMaxSubscriptions(limit int) {
+	n.topicsMu.Lock()
+	defer n.topicsMu.Unlock()
+
+	n.maxSubscriptions = limit
+}
+
+// Subscribe subscribes the client to the topics matching the filter.
+func (c *Client) Subscribe(filter string) error {
+	return c.subscribe(filter, false)
+}
+
+// subscribe adds the filter, failing with ErrTooManySubscriptions beyond the
+// limit of the namespace if limited.
+func (c *Client) subscribe(filter string, limited bool) error {
+	if !validTopicFilter(filter) {
+		return fmt.Errorf("%w: %q", ErrInvalidTopic, filter)
+	}
+
+	n := c.Namespace
+	n.topicsMu.Lock()
+	defer n.topicsMu.Unlock()
+
+	if _, ok := c.topics[filter]; !ok && limited && n.maxSubscriptions > 0 && len(c.topics) >= n.maxSubscriptions {
+		return fmt.Errorf("%w: limit is %d", ErrTooManySubscriptions, n.maxSubscriptions)
+	}
+	if c.topics == nil {
+		c.topics = make(map[string]struct{})
+	}
+	c.topics[filter] = struct{}{}
+
+	subscribers, ok := n.topics[filter]
+	if !ok {
+		subscribers = make(map[*Client]struct{})
+		n.topics[filter] = subscribers
+	}
+	subscribers[c] = struct{}{}
+	return nil
+}
+
+func (c *Client) Unsubscribe(filter string) {
+	n := c.Namespace
+	n.topicsMu.Lock()
+	defer n.topicsMu.Unlock()
+
+	delete(c.topics, filter)
+	n.dropSubscriber(filter, c)
+}
+
+// Subscriptions returns the topic filters the client is subscribed to.
+func (c *Client) Subscriptions() []string {
+	c.Namespace.topicsMu.RLock()
+	defer c.Namespace.topicsMu.RUnlock()
+
+	filters := make([]string, 0, len(c.topics))
+	for filter := range c.topics {
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+func (n *Namespace) dropSubscriber(filter string, client *Client) {
+	subscribers := n.topics[filter]
+	delete(subscribers, client)
+	if len(subscribers) == 0 {
+		delete(n.topics, filter)
+	}
+}
+
+func (n *Namespace) unsubscribeAll(client *Client) {
+	n.topicsMu.Lock()
+	defer n.topicsMu.Unlock()
+
+	for filter := range client.topics {
+		n.dropSubscriber(filter, client)
+	}
+	client.topics = nil
+}
+
+// resubscribe hands the subscriptions of a detached client over to the one
+// resuming its session.
+func (n *Namespace) resubscribe(old *Client, client *Client) {
+	n.topicsMu.Lock()
+	defer n.topicsMu.Unlock()
+
+	if client.topics == nil && len(old.topics) > 0 {
+		client.topics = make(map[string]struct{}, len(old.topics))
+	}
+	for filter := range old.topics {
+		client.topics[filter] = struct{}{}
+		delete(n.topics[filter], old)
+		n.topics[filter][client] = struct{}{}
+	}
+	old.topics = nil
+}
+
+// Subscribers returns the clients of this node subscribed to a filter matching
+// the topic.
+func (n *Namespace) Subscribers(topic string) []*Client {
+	n.topicsMu.RLock()
+	defer n.topicsMu.RUnlock()
+
+	seen := make(map[*Client]struct{})
+	var clients []*Client
+	for filter, subscribers := range n.topics {
+		if !matchTopic(filter, topic) {
+			continue
+		}
+		for client := range subscribers {
+			if _, ok := seen[client]; !ok {
+				seen[client] = struct{}{}
+				clients = append(clients, client)
+			}
+		}
+	}
+	return clients
+}
+
+// Publish sends the message to every subscriber of a filter matching the
+// topic, on every node of the cluster.
+func (n *Namespace) Publish(topic string, data interface{}) error {
+	if !validTopic(topic) {
+		return fmt.Errorf("%w: %q", ErrInvalidTopic, topic)
+	}
+
+	n.emitPacket(&BroadcastPacket{
+		Topic: topic,
+		Event: messageEvent,
+		Data:  data,
+	})
+	return nil
+}
+
+func (n *Namespace) deliverTopic(packet *BroadcastPacket) {
+	message := map[string]interface{}{
+		"topic": packet.Topic,
+		"data":  packet.Data,
+	}
+	n.server.emitToClients(n.Subscribers(packet.Topic), map[string]interface{}{
+		"event": messageEvent,
+		"data":  message,
+	}, nil, false)
+}
+
+func isTopicEvent(eventName string) bool {
+	return eventName == subscribeEvent || eventName == unsubscribeEvent
+}
+
+// handleTopicEvent handles "#subscribe" and "#unsubscribe".
+func (c *Client) handleTopicEvent(frame *Frame, data map[string]interface{}) {
+	filter, _ := data["topic"].(string)
+
+	var result interface{} = filter
+	if frame.Event == unsubscribeEvent {
+		c.Unsubscribe(filter)
+	} else if err := c.guardSubscription(filter); err != nil {
+		result = err
+	} else if err := c.subscribe(filter, true); err != nil {
+		result = err
+	}
+
+	c.acknowledge(frame, result)
+}
+
+func (c *Client) guardSubscription(filter string) error {
+	if !validTopicFilter(filter) {
+		return fmt.Errorf("%w: %q", ErrInvalidTopic, filter)
+	}
+	if guard := c.Namespace.subscribeGuard; guard != nil {
+		return guard(c, filter)
+	}
+	return nil
+}
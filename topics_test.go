@@ -0,0 +1,126 @@
+package socketigo_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/testclient"
+)
+
+func TestTopicFilters(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	client := testclient.MustConnect(t, server, nil)
+	for _, filter := range []string{"sensors/+/temp", "sensors/7/#", "alerts/#"} {
+		if err := client.Subscribe(filter, time.Second); err != nil {
+			t.Fatalf("subscribing to %q failed: %v", filter, err)
+		}
+	}
+
+	ns := server.Of("/")
+	tests := []struct {
+		topic   string
+		matches bool
+	}{
+		{"sensors/3/temp", true},
+		// matches two filters, still arrives once
+		{"sensors/7/temp", true},
+		{"sensors/7", true},
+		{"alerts", true},
+		{"alerts/fire/kitchen", true},
+		{"sensors/3/humidity", false},
+		{"sensors/3/temp/raw", false},
+		{"sensors", false},
+	}
+	for _, test := range tests {
+		if err := ns.Publish(test.topic, map[string]interface{}{"topic": test.topic}); err != nil {
+			t.Fatalf("publishing to %q failed: %v", test.topic, err)
+		}
+		if !test.matches {
+			client.ExpectNoEvent(t, "#message", 20*time.Millisecond)
+			continue
+		}
+		if data := client.ExpectEvent(t, "#message"); data["topic"] != test.topic {
+			t.Fatalf("message is %v, want one of %q", data, test.topic)
+		}
+		client.ExpectNoEvent(t, "#message", 20*time.Millisecond)
+	}
+
+	if err := client.Unsubscribe("alerts/#", time.Second); err != nil {
+		t.Fatalf("unsubscribing failed: %v", err)
+	}
+	ns.Publish("alerts/fire", nil)
+	client.ExpectNoEvent(t, "#message", 20*time.Millisecond)
+}
+
+func TestInvalidTopics(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	client := testclient.MustConnect(t, server, nil)
+
+	for _, filter := range []string{"", "sensors/#/temp", "sensors/a+", "sensors#"} {
+		if err := client.Subscribe(filter, time.Second); err == nil {
+			t.Errorf("invalid filter %q got accepted", filter)
+		}
+	}
+	for _, topic := range []string{"", "sensors/+", "sensors/#"} {
+		if err := server.Of("/").Publish(topic, nil); !errors.Is(err, socketigo.ErrInvalidTopic) {
+			t.Errorf("publishing to %q: error is %v, want ErrInvalidTopic", topic, err)
+		}
+	}
+}
+
+func TestMaxSubscriptions(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	server.Of("/").SetMaxSubscriptions(2)
+	connected := make(chan *socketigo.Client, 1)
+	server.OnConnected(func(client *socketigo.Client) {
+		connected <- client
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	serverClient := <-connected
+	for _, filter := range []string{"a", "b"} {
+		if err := client.Subscribe(filter, time.Second); err != nil {
+			t.Fatalf("subscribing to %q failed: %v", filter, err)
+		}
+	}
+	if err := client.Subscribe("c", time.Second); err == nil || !strings.Contains(err.Error(), "too many subscriptions") {
+		t.Fatalf("error is %v, want too many subscriptions", err)
+	}
+	// subscribing to a filter again takes no further one
+	if err := client.Subscribe("a", time.Second); err != nil {
+		t.Fatalf("subscribing again failed: %v", err)
+	}
+
+	// the server is not limited
+	if err := serverClient.Subscribe("c"); err != nil {
+		t.Fatalf("server subscribing the client failed: %v", err)
+	}
+	if subscriptions := serverClient.Subscriptions(); len(subscriptions) != 3 {
+		t.Fatalf("client has subscriptions %v, want 3", subscriptions)
+	}
+}
+
+func TestOnSubscribe(t *testing.T) {
+	server := socketigo.CreateIgoServer(nil)
+	errPrivate := errors.New("private topics are off limits")
+	server.Of("/").OnSubscribe(func(client *socketigo.Client, filter string) error {
+		if strings.HasPrefix(filter, "private/") {
+			return errPrivate
+		}
+		return nil
+	})
+
+	client := testclient.MustConnect(t, server, nil)
+	if err := client.Subscribe("private/#", time.Second); err == nil || !strings.Contains(err.Error(), errPrivate.Error()) {
+		t.Fatalf("error is %v, want the one of the guard", err)
+	}
+	if err := client.Subscribe("public/#", time.Second); err != nil {
+		t.Fatalf("subscribing to a public topic failed: %v", err)
+	}
+
+	server.Of("/").Publish("private/secret", nil)
+	client.ExpectNoEvent(t, "#message", 20*time.Millisecond)
+}
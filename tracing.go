@@ -0,0 +1,44 @@
+package socketigo
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer traces the events of the server, see the oteltracing package for an
+// OpenTelemetry implementation. The returned end functions get the error the
+// event or ack failed with, if any.
+type Tracer interface {
+	// StartConnection derives the context of a client from its upgrade request,
+	// e.g. to continue a trace passed in its headers.
+	StartConnection(ctx context.Context, r *http.Request) context.Context
+	// StartEvent starts the span of an incoming event. Trace holds the trace
+	// headers the client sent along with it.
+	StartEvent(ctx context.Context, client *Client, eventName string, trace map[string]string) (context.Context, func(err error))
+	// StartAck starts the span of an ack round trip. The returned trace headers
+	// are sent along with the event.
+	StartAck(ctx context.Context, client *Client, eventName string) (map[string]string, func(err error))
+}
+
+type noopTracer struct{}
+
+func (noopTracer) StartConnection(ctx context.Context, r *http.Request) context.Context {
+	return ctx
+}
+
+func (noopTracer) StartEvent(ctx context.Context, client *Client, eventName string, trace map[string]string) (context.Context, func(err error)) {
+	return ctx, func(error) {}
+}
+
+func (noopTracer) StartAck(ctx context.Context, client *Client, eventName string) (map[string]string, func(err error)) {
+	return nil, func(error) {}
+}
+
+// SetTracer installs the tracer. It has to be called before the first client
+// connects.
+func (s *IgoServer) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = noopTracer{}
+	}
+	s.tracer = tracer
+}
@@ -0,0 +1,79 @@
+package socketigo
+
+import (
+	"fmt"
+
+	"github.com/goccy/go-json"
+)
+
+// decodePayload converts the decoded payload of an event into out by
+// round-tripping it through JSON, so out can use the usual json struct tags.
+func decodePayload(data interface{}, out interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodeFailed, err)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodeFailed, err)
+	}
+	return nil
+}
+
+func typedListener[T any, R any](eventName string, handler func(client *Client, payload T) (R, error)) ContextListener {
+	return func(ctx *EventContext, data map[string]interface{}) interface{} {
+		client := ctx.Client
+		var payload T
+		if err := ctx.frame.decode(&payload); err != nil {
+			client.Server.ReportError(client, fmt.Errorf("event %q: %w", eventName, err))
+			return err
+		}
+		if err := validateStruct(payload); err != nil {
+			return err
+		}
+
+		result, err := handler(client, payload)
+		if err != nil {
+			return err
+		}
+		return result
+	}
+}
+
+// RawListener receives the payload of an event as it was sent, whatever JSON
+// value it is. Listeners taking a map only get events carrying an object.
+type RawListener func(client *Client, data json.RawMessage) interface{}
+
+func (l RawListener) contextListener() ContextListener {
+	return func(ctx *EventContext, data map[string]interface{}) interface{} {
+		raw, err := ctx.frame.raw()
+		if err != nil {
+			ctx.Client.Server.ReportError(ctx.Client, fmt.Errorf("event %q: %w", ctx.Event, err))
+			return err
+		}
+		return l(ctx.Client, raw)
+	}
+}
+
+// OnRaw adds a listener getting the payload of the event undecoded, e.g. an
+// array or a string. Use On with interface{} as T to get it decoded.
+func (c *Client) OnRaw(eventName string, listener RawListener) *Subscription {
+	return c.events.addTyped(eventName, listener.contextListener())
+}
+
+// OnRaw adds a raw listener for every client of the namespace.
+func (n *Namespace) OnRaw(eventName string, listener RawListener) *Subscription {
+	return n.events.addTyped(eventName, listener.contextListener())
+}
+
+// On registers a listener on the client whose payload gets decoded into T and,
+// for structs, validated against their `validate` tags. The result R
+// acknowledges the event; an error, including a payload failing to decode or
+// validate, is acknowledged as such.
+func On[T any, R any](client *Client, eventName string, handler func(client *Client, payload T) (R, error)) *Subscription {
+	return client.events.addTyped(eventName, typedListener(eventName, handler))
+}
+
+// OnNamespace registers a typed listener for every client of the namespace.
+func OnNamespace[T any, R any](ns *Namespace, eventName string, handler func(client *Client, payload T) (R, error)) *Subscription {
+	return ns.events.addTyped(eventName, typedListener(eventName, handler))
+}
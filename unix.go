@@ -0,0 +1,68 @@
+package socketigo
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ListenAndServeUnix accepts raw connections on a Unix domain socket at the
+// path until the server shuts down, for clients on the same host. A socket
+// left behind at the path gets replaced, and the new one gets the file mode,
+// so access can be granted through file permissions. The socket file is
+// removed once the listener got closed.
+func (s *IgoServer) ListenAndServeUnix(path string, mode os.FileMode) error {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("socketigo: %s exists and is no socket", path)
+	}
+
+	// the socket is created in a directory only the server can enter and moved
+	// to the path once it got the mode, so no one can connect in between
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".socketigo-")
+	if err != nil {
+		return err
+	}
+	socket := filepath.Join(dir, "socket")
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+	if err != nil {
+		os.Remove(dir)
+		return err
+	}
+	listener.SetUnlinkOnClose(false)
+
+	if err := os.Chmod(socket, mode); err != nil {
+		listener.Close()
+		os.RemoveAll(dir)
+		return err
+	}
+	if err := os.Rename(socket, path); err != nil {
+		listener.Close()
+		os.RemoveAll(dir)
+		return err
+	}
+	os.Remove(dir)
+	return s.ServeRaw(&unixListener{UnixListener: listener, path: path})
+}
+
+// unixListener is a listener whose socket got moved to the path, removing it
+// from there once closed.
+type unixListener struct {
+	*net.UnixListener
+	path      string
+	closeOnce sync.Once
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.closeOnce.Do(func() {
+		os.Remove(l.path)
+	})
+	return err
+}
@@ -0,0 +1,66 @@
+package socketigo_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	"github.com/nauri-io/socket.igo/igoclient"
+)
+
+func TestListenAndServeUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "igo.sock")
+	server := echoServer(nil)
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ListenAndServeUnix(path, 0600)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		info, err := os.Stat(path)
+		if err == nil {
+			if info.Mode().Perm() != 0600 {
+				t.Fatalf("socket has mode %v, want 0600", info.Mode().Perm())
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("socket did not get created")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// only the socket is left in the directory
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Fatalf("directory holds %d entries, want the socket only", len(entries))
+	}
+
+	echoes := make(chan map[string]interface{}, 1)
+	client, err := igoclient.DialUnix(path, &igoclient.Options{DisableReconnect: true})
+	if err != nil {
+		t.Fatalf("connecting failed: %v", err)
+	}
+	client.On("echo", func(client *igoclient.Client, data map[string]interface{}) interface{} {
+		echoes <- data
+		return nil
+	})
+	client.Emit("echo", map[string]interface{}{"text": "local"})
+	select {
+	case <-echoes:
+	case <-time.After(time.Second):
+		t.Fatal("client on the socket did not get an echo")
+	}
+	client.Close()
+
+	server.Shutdown(context.Background())
+	if err := <-served; !errors.Is(err, socketigo.ErrServerShutdown) {
+		t.Fatalf("serving ended with %v, want ErrServerShutdown", err)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Fatal("socket did not get removed")
+	}
+}
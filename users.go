@@ -0,0 +1,128 @@
+package socketigo
+
+// User groups the clients bound to the same user Id with SetUserId, e.g. the
+// tabs and devices of a person. Only the clients of this node are tracked.
+type User struct {
+	Id      string
+	server  *IgoServer
+	clients map[*Client]struct{}
+}
+
+// Clients returns the connected clients of the user.
+func (u *User) Clients() []*Client {
+	u.server.usersMu.RLock()
+	defer u.server.usersMu.RUnlock()
+
+	clients := make([]*Client, 0, len(u.clients))
+	for client := range u.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+func (u *User) Emit(eventName string, data interface{}) {
+	for _, client := range u.Clients() {
+		client.Emit(eventName, data)
+	}
+}
+
+// OnUserConnected gets called when the first client of a user connected.
+func (s *IgoServer) OnUserConnected(listener func(user *User)) {
+	s.userConnectedHandler = listener
+}
+
+// OnUserDisconnected gets called once the last client of a user is gone. A
+// client resuming its session does not count as gone.
+func (s *IgoServer) OnUserDisconnected(listener func(user *User)) {
+	s.userDisconnectedHandler = listener
+}
+
+func (s *IgoServer) GetUser(userId string) *User {
+	s.usersMu.RLock()
+	defer s.usersMu.RUnlock()
+
+	return s.users[userId]
+}
+
+func (s *IgoServer) Users() []*User {
+	s.usersMu.RLock()
+	defer s.usersMu.RUnlock()
+
+	users := make([]*User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	return users
+}
+
+// bindUser adds a connected client to its user.
+func (s *IgoServer) bindUser(client *Client, userId string) {
+	if userId == "" {
+		return
+	}
+
+	s.usersMu.Lock()
+	user, ok := s.users[userId]
+	if !ok {
+		user = &User{
+			Id:      userId,
+			server:  s,
+			clients: make(map[*Client]struct{}),
+		}
+		s.users[userId] = user
+	}
+	user.clients[client] = struct{}{}
+	s.usersMu.Unlock()
+
+	if !ok && s.userConnectedHandler != nil {
+		s.userConnectedHandler(user)
+	}
+}
+
+func (s *IgoServer) unbindUser(client *Client, userId string) {
+	if userId == "" {
+		return
+	}
+
+	s.usersMu.Lock()
+	user, ok := s.users[userId]
+	if !ok {
+		s.usersMu.Unlock()
+		return
+	}
+	if _, member := user.clients[client]; !member {
+		s.usersMu.Unlock()
+		return
+	}
+	delete(user.clients, client)
+	gone := len(user.clients) == 0
+	if gone {
+		delete(s.users, userId)
+	}
+	s.usersMu.Unlock()
+
+	if gone && s.userDisconnectedHandler != nil {
+		s.userDisconnectedHandler(user)
+	}
+}
+
+// rebindUser hands the place of a detached client over to the one resuming its
+// session.
+func (s *IgoServer) rebindUser(old *Client, client *Client) {
+	userId := client.UserId()
+	if userId == "" {
+		return
+	}
+
+	s.usersMu.Lock()
+	user, ok := s.users[userId]
+	if ok {
+		delete(user.clients, old)
+		user.clients[client] = struct{}{}
+	}
+	s.usersMu.Unlock()
+
+	if !ok {
+		s.bindUser(client, userId)
+	}
+}
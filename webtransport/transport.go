@@ -0,0 +1,100 @@
+// Package webtransport is an experimental socket.igo transport on top of
+// WebTransport over HTTP/3, for browsers able to reach the server over QUIC.
+//
+// A client opens a WebTransport session to the url of the server with the
+// "transport=webtransport" query parameter and then a single bidirectional
+// stream, which carries its messages framed as by socketigo.NewStreamConn:
+// the websocket message type as a byte, the length as a big endian uint32
+// and the payload.
+//
+//	h3 := &http3.Server{Addr: ":443", Handler: mux}
+//	wt.ConfigureHTTP3Server(h3)
+//	server.RegisterTransport(webtransport.Name, webtransport.New(&wt.Server{H3: h3}, nil))
+package webtransport
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	socketigo "github.com/nauri-io/socket.igo"
+	wt "github.com/quic-go/webtransport-go"
+)
+
+// Name is the value of the "transport" query parameter the transport is
+// usually registered under.
+const Name = "webtransport"
+
+const (
+	defaultStreamTimeout = 10 * time.Second
+	// closeGrace is how long a closed session waits for the client to close
+	// it, so what was written last is not reset on the way
+	closeGrace = time.Second
+)
+
+type Options struct {
+	// StreamTimeout bounds the wait for the client to open its stream once
+	// the session got established. Defaults to 10 seconds.
+	StreamTimeout time.Duration
+}
+
+// Transport implements socketigo.Transport on top of a WebTransport server.
+type Transport struct {
+	server        *wt.Server
+	streamTimeout time.Duration
+}
+
+func New(server *wt.Server, options *Options) *Transport {
+	streamTimeout := defaultStreamTimeout
+	if options != nil && options.StreamTimeout > 0 {
+		streamTimeout = options.StreamTimeout
+	}
+
+	return &Transport{
+		server:        server,
+		streamTimeout: streamTimeout,
+	}
+}
+
+func (t *Transport) Upgrade(w http.ResponseWriter, r *http.Request) (socketigo.Conn, error) {
+	session, err := t.server.Upgrade(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(session.Context(), t.streamTimeout)
+	defer cancel()
+
+	stream, err := session.AcceptStream(ctx)
+	if err != nil {
+		session.CloseWithError(0, "no stream opened")
+		return nil, err
+	}
+
+	return &conn{
+		Conn:    socketigo.NewStreamConn(stream),
+		session: session,
+	}, nil
+}
+
+// conn tears down the whole session with the stream, the client has no use
+// for it anymore.
+type conn struct {
+	socketigo.Conn
+	session *wt.Session
+}
+
+func (c *conn) Close() error {
+	err := c.Conn.Close()
+	go func() {
+		timer := time.NewTimer(closeGrace)
+		defer timer.Stop()
+
+		select {
+		case <-c.session.Context().Done():
+		case <-timer.C:
+			c.session.CloseWithError(0, "")
+		}
+	}()
+	return err
+}
@@ -0,0 +1,293 @@
+package socketigo
+
+import (
+	"errors"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+const (
+	defaultSendQueueSize        = 256
+	defaultBlockTimeout         = time.Second
+	defaultCompressionThreshold = 512
+)
+
+var ErrQueueFull = errors.New("socketigo: outbound queue full")
+
+// BackpressurePolicy decides what happens to an emit when the client's outbound
+// queue is full.
+type BackpressurePolicy int
+
+const (
+	// DropNewest rejects the new message with ErrQueueFull.
+	DropNewest BackpressurePolicy = iota
+	// DropOldest discards the oldest queued message to make room for the new one.
+	DropOldest
+	// Block waits up to BlockTimeout for room before failing with ErrQueueFull.
+	Block
+	// Disconnect closes the connection of the slow client.
+	Disconnect
+)
+
+func (p BackpressurePolicy) String() string {
+	switch p {
+	case DropNewest:
+		return "drop newest"
+	case DropOldest:
+		return "drop oldest"
+	case Block:
+		return "block"
+	case Disconnect:
+		return "disconnect"
+	default:
+		return "unknown"
+	}
+}
+
+// OnSlowConsumer gets called whenever a client's outbound queue overflows,
+// right before the backpressure policy is applied.
+func (s *IgoServer) OnSlowConsumer(listener func(client *Client, policy BackpressurePolicy)) {
+	s.slowConsumerHandler = listener
+}
+
+type outboundMessage struct {
+	messageType int
+	data        []byte
+	// prepared is shared by every client a broadcast goes to, so the frame
+	// gets compressed once per compression setting rather than per client
+	prepared *ws.PreparedMessage
+	// attachments are written as binary messages right behind the frame, so
+	// no other message gets in between
+	attachments [][]byte
+	// written gets called once the message is on the wire
+	written func()
+}
+
+// enqueue hands an encoded message to the client's writer without blocking.
+func (c *Client) enqueue(messageType int, data []byte) error {
+	return c.enqueueMessage(outboundMessage{messageType: messageType, data: data})
+}
+
+func (c *Client) enqueueMessage(message outboundMessage) error {
+	if c.session != nil {
+		if handled, err := c.session.intercept(c, message); handled {
+			return err
+		}
+	}
+
+	if c.closing() {
+		return ErrClientClosed
+	}
+
+	select {
+	case c.send <- message:
+		return nil
+	default:
+	}
+
+	policy := c.Server.backpressure
+	c.Server.logger.Warn("send queue full", c.logFields("policy", policy.String())...)
+	if c.Server.slowConsumerHandler != nil {
+		c.Server.slowConsumerHandler(c, policy)
+	}
+
+	switch policy {
+	case DropOldest:
+		for {
+			select {
+			case c.send <- message:
+				return nil
+			default:
+			}
+
+			select {
+			case <-c.send:
+			default:
+			}
+		}
+
+	case Block:
+		timer := time.NewTimer(c.Server.blockTimeout)
+		defer timer.Stop()
+
+		select {
+		case c.send <- message:
+			return nil
+		case <-timer.C:
+			return ErrQueueFull
+		case <-c.done:
+			return ErrClientClosed
+		}
+
+	case Disconnect:
+		c.closeWithReason(ReasonSlowConsumer)
+		return ErrQueueFull
+
+	default:
+		return ErrQueueFull
+	}
+}
+
+// congested reports whether the outbound queue is at least half full, or the
+// client is detached and its messages would only get buffered.
+func (c *Client) congested() bool {
+	if c.session != nil && c.session.isDetached(c) {
+		return true
+	}
+	return len(c.send)*2 >= cap(c.send)
+}
+
+// enqueueVolatile queues the message unless the client is congested, without
+// applying the backpressure policy. It reports whether it got queued.
+func (c *Client) enqueueVolatile(message outboundMessage) bool {
+	if c.closing() || c.congested() {
+		return false
+	}
+
+	select {
+	case c.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// emitToClients sends the frame to every client. Unless sequence numbers make
+// every copy differ, the frame is encoded once and shared as a prepared
+// message.
+func (s *IgoServer) emitToClients(clients []*Client, frame map[string]interface{}, attachments [][]byte, volatile bool) {
+	if s.sequenceNumbers || len(clients) < 2 {
+		for _, client := range clients {
+			copied := make(map[string]interface{}, len(frame)+1)
+			for key, value := range frame {
+				copied[key] = value
+			}
+			client.sendFrame(copied, attachments, volatile)
+		}
+		return
+	}
+
+	data, err := s.codec.Marshal(frame)
+	if err != nil {
+		s.emitError(nil, nil, nil, err)
+		return
+	}
+	prepared, err := ws.NewPreparedMessage(s.codec.MessageType(), data)
+	if err != nil {
+		s.emitError(nil, nil, nil, err)
+		return
+	}
+
+	message := outboundMessage{
+		messageType: s.codec.MessageType(),
+		data:        data,
+		prepared:    prepared,
+		attachments: attachments,
+	}
+	eventName := metricEventName(frame["event"].(string))
+
+	for _, client := range clients {
+		if client.chunks(frame["event"].(string), len(data)) {
+			chunked := message
+			chunked.prepared = nil
+			if err := client.enqueueChunked(chunked, volatile); err != nil {
+				continue
+			}
+		} else if volatile {
+			if !client.enqueueVolatile(message) {
+				continue
+			}
+		} else if err := client.enqueueMessage(message); err != nil {
+			continue
+		}
+		s.metrics.EventSent(client.Namespace.Name, eventName, len(data))
+	}
+}
+
+func (c *Client) enqueueFrame(frame map[string]interface{}) error {
+	return c.sendFrame(frame, nil, false)
+}
+
+func (c *Client) sendFrame(frame map[string]interface{}, attachments [][]byte, volatile bool) error {
+	eventName, _ := frame["event"].(string)
+
+	if c.Server.sequenceNumbers && eventName != handshakeEvent {
+		// held until the frame is queued, so the numbers go out in order
+		c.seqMu.Lock()
+		defer c.seqMu.Unlock()
+
+		c.seq++
+		frame["seq"] = c.seq
+	}
+
+	data, err := c.Server.codec.Marshal(frame)
+	if err != nil {
+		return err
+	}
+
+	message := outboundMessage{
+		messageType: c.Server.codec.MessageType(),
+		data:        data,
+		attachments: attachments,
+	}
+	if c.chunks(eventName, len(data)) {
+		if err := c.enqueueChunked(message, volatile); err != nil {
+			return err
+		}
+	} else if volatile {
+		if !c.enqueueVolatile(message) {
+			return nil
+		}
+	} else if err := c.enqueueMessage(message); err != nil {
+		return err
+	}
+
+	c.Server.metrics.EventSent(c.Namespace.Name, metricEventName(eventName), len(data))
+	return nil
+}
+
+// writePump is the only goroutine writing messages to the client's socket, so
+// emits from broadcasts and handlers never interleave. It sends the pings too.
+func (s *IgoServer) writePump(client *Client) {
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message := <-client.send:
+			if s.upgrader.EnableCompression {
+				client.socket.EnableWriteCompression(len(message.data) >= s.compressionMin)
+			}
+			client.socket.SetWriteDeadline(time.Now().Add(s.writeWait))
+			var err error
+			if writer, ok := client.socket.(preparedWriter); ok && message.prepared != nil {
+				err = writer.WritePreparedMessage(message.prepared)
+			} else {
+				err = client.socket.WriteMessage(message.messageType, message.data)
+			}
+			for _, attachment := range message.attachments {
+				if err != nil {
+					break
+				}
+				err = client.socket.WriteMessage(ws.BinaryMessage, attachment)
+			}
+			if err == nil && message.written != nil {
+				message.written()
+			}
+			if err != nil {
+				// closing the socket makes the reader notice and run the disconnect
+				client.socket.Close()
+				return
+			}
+		case <-ticker.C:
+			err := client.socket.WriteControl(ws.PingMessage, nil, time.Now().Add(s.writeWait))
+			if err != nil {
+				client.socket.Close()
+				return
+			}
+		case <-client.done:
+			return
+		}
+	}
+}